@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/scheduler"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// purgeTrashJob permanently deletes the products and users soft deleted for longer than the retention.
+// Each row is purged in its own transaction, rows still referenced by orders are kept.
+func purgeTrashJob(dataManager *data.Manager, services *InternalServices, retention time.Duration) *scheduler.Job {
+	purge := func(ctx context.Context, name string, id int, purgeFunc func(ctx context.Context, id int) *types.Error) {
+		var err *types.Error
+		errTransaction := dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+			err = purgeFunc(ctx, id)
+			if err != nil {
+				return err.Error
+			}
+			return nil
+		})
+		if errTransaction != nil && errTransaction != data.ErrStillReferenced {
+			log.Printf("purge-trash: failed to purge %s %d: %s\n", name, id, errTransaction)
+		}
+	}
+
+	return &scheduler.Job{
+		Name:     "purge-trash",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			deletedBefore := time.Now().Add(-retention)
+
+			products, _, err := services.productService.ListDeletedProducts(ctx, &product.FindAllProductsParams{
+				DeletedBefore: &deletedBefore,
+			})
+			if err != nil {
+				return err.Error
+			}
+			for _, p := range products {
+				purge(ctx, "product", p.ID, services.productService.PurgeProduct)
+			}
+
+			users, _, err := services.userService.ListDeletedUsers(ctx, &user.FindAllUsersParams{
+				DeletedBefore: &deletedBefore,
+			})
+			if err != nil {
+				return err.Error
+			}
+			for _, u := range users {
+				purge(ctx, "user", u.ID, services.userService.PurgeUser)
+			}
+
+			return nil
+		},
+	}
+}
+
+// applyScheduledPricesJob applies the scheduled product prices which became effective.
+// Each price is applied in its own transaction, the latest first so it wins over an older price of the same product.
+func applyScheduledPricesJob(dataManager *data.Manager, services *InternalServices) *scheduler.Job {
+	return &scheduler.Job{
+		Name:     "apply-scheduled-prices",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			now := time.Now()
+			prices, err := services.productService.ListDuePrices(ctx, now)
+			if err != nil {
+				return err.Error
+			}
+
+			applied := 0
+			for _, price := range prices {
+				var ok bool
+				errTransaction := dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+					ok, err = services.productService.ApplyScheduledPrice(ctx, price.ID, now)
+					if err != nil {
+						return err.Error
+					}
+					return nil
+				})
+				if errTransaction != nil {
+					log.Printf("apply-scheduled-prices: failed to apply price %d: %s\n", price.ID, errTransaction)
+					continue
+				}
+				if ok {
+					applied++
+				}
+			}
+			if applied > 0 {
+				log.Printf("apply-scheduled-prices: applied %d prices\n", applied)
+			}
+			return nil
+		},
+	}
+}
+
+// deleteExpiredTokensJob deletes the expired sessions, single-use tokens and failed logins,
+// they are refused once expired already so the job only keeps the tables small
+func deleteExpiredTokensJob(services *InternalServices) *scheduler.Job {
+	return &scheduler.Job{
+		Name:     "delete-expired-tokens",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			deleted, err := services.userService.DeleteExpiredTokens(ctx, time.Now())
+			if err != nil {
+				return err.Error
+			}
+			if deleted > 0 {
+				log.Printf("delete-expired-tokens: deleted %d tokens\n", deleted)
+			}
+			return nil
+		},
+	}
+}
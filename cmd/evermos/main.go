@@ -4,14 +4,31 @@ import (
 	"fmt"
 	"log"
 	testUser "os/user"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/riskiramdan/evermos/config"
 	"github.com/riskiramdan/evermos/databases"
+	"github.com/riskiramdan/evermos/internal/apikey"
+	apiKeyPg "github.com/riskiramdan/evermos/internal/apikey/postgres"
+	"github.com/riskiramdan/evermos/internal/audit"
+	auditPg "github.com/riskiramdan/evermos/internal/audit/postgres"
+	"github.com/riskiramdan/evermos/internal/blob"
+	blobLocal "github.com/riskiramdan/evermos/internal/blob/local"
+	blobS3 "github.com/riskiramdan/evermos/internal/blob/s3"
 	"github.com/riskiramdan/evermos/internal/data"
 	internalhttp "github.com/riskiramdan/evermos/internal/http"
+	"github.com/riskiramdan/evermos/internal/loginguard"
+	loginGuardMemory "github.com/riskiramdan/evermos/internal/loginguard/memory"
+	loginGuardPg "github.com/riskiramdan/evermos/internal/loginguard/postgres"
+	"github.com/riskiramdan/evermos/internal/mailer"
+	mailerFile "github.com/riskiramdan/evermos/internal/mailer/file"
+	mailerSMTP "github.com/riskiramdan/evermos/internal/mailer/smtp"
+	"github.com/riskiramdan/evermos/internal/oidc"
 	"github.com/riskiramdan/evermos/internal/product"
 	productPg "github.com/riskiramdan/evermos/internal/product/postgres"
+	"github.com/riskiramdan/evermos/internal/scheduler"
+	"github.com/riskiramdan/evermos/internal/token"
 	"github.com/riskiramdan/evermos/internal/user"
 	userPg "github.com/riskiramdan/evermos/internal/user/postgres"
 )
@@ -21,24 +38,165 @@ import (
 type InternalServices struct {
 	userService    user.ServiceInterface
 	productService product.ServiceInterface
+	auditService   audit.ServiceInterface
+	apiKeyService  apikey.ServiceInterface
+	accessTokens   *token.Manager
+}
+
+func buildBlobStore(config *config.Config) blob.Store {
+	if config.BlobDriver == "s3" {
+		return blobS3.NewStorage(
+			config.BlobEndpoint,
+			config.BlobRegion,
+			config.CloudName,
+			config.AccountKey,
+			config.SecretKey,
+		)
+	}
+	return blobLocal.NewStorage(config.ImagePath, "/images")
+}
+
+func buildMailer(config *config.Config) mailer.Mailer {
+	if config.MailDriver == "smtp" {
+		return mailerSMTP.NewMailer(
+			config.SMTPAddr,
+			config.SMTPUsername,
+			config.SMTPPassword,
+			config.MailFrom,
+		)
+	}
+	return mailerFile.NewMailer(config.MailPath)
+}
+
+func buildLoginGuard(db *sqlx.DB, config *config.Config) *loginguard.Guard {
+	policy := loginguard.DefaultPolicy
+	policy.AccountMaxFailures = config.LoginMaxFailures
+	policy.IPMaxFailures = config.LoginIPMaxFailures
+	policy.LockDuration = time.Duration(config.LoginLockMinutes) * time.Minute
+
+	if config.LoginGuardDriver == "memory" {
+		return loginguard.NewGuard(loginGuardMemory.NewStore(), policy)
+	}
+	return loginguard.NewGuard(loginGuardPg.NewStore(db), policy)
+}
+
+// buildAccessTokens loads the signing keys when the signed access tokens are enabled
+func buildAccessTokens(config *config.Config) *token.Manager {
+	if config.AuthMode != "jwt" {
+		return nil
+	}
+
+	keys, err := token.LoadKeySet(config.JWTKeyDir, config.JWTActiveKeyID)
+	if err != nil {
+		log.Fatalln("failed to load the access token keys: ", err)
+	}
+	return token.NewManager(keys, config.JWTIssuer, time.Duration(config.JWTAccessTTLMinutes)*time.Minute)
+}
+
+// buildOIDCProvider returns nil when no OpenID Connect provider is configured
+func buildOIDCProvider(config *config.Config) *oidc.Provider {
+	if config.OIDCIssuer == "" {
+		return nil
+	}
+
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       config.OIDCIssuer,
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURL:  config.OIDCRedirectURL,
+		Scopes:       config.OIDCScopes,
+	}, nil)
 }
 
 func buildInternalServices(db *sqlx.DB, config *config.Config) *InternalServices {
 	userPostgresStorage := userPg.NewPostgresStorage(
-		data.NewPostgresStorage(db, "user", user.User{}),
+		data.NewPostgresStorage(db, "user", user.User{}).WithAudit(),
+	)
+	sessionPostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "session", user.Session{}),
+	)
+	refreshTokenHistoryPostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "refresh_token_history", user.RefreshTokenHistory{}),
+	)
+	userTokenPostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "user_token", user.UserToken{}),
+	)
+	totpPostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "user_totp", user.UserTOTP{}),
+	)
+	recoveryCodePostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "recovery_code", user.RecoveryCode{}),
+	)
+	userService := user.NewService(
+		userPostgresStorage,
+		sessionPostgresStorage,
+		refreshTokenHistoryPostgresStorage,
+		userTokenPostgresStorage,
+		totpPostgresStorage,
+		recoveryCodePostgresStorage,
+		buildMailer(config),
+		config.AppURL,
+	)
+	userService.WithLoginGuard(buildLoginGuard(db, config))
+	userService.WithResetPasswordURL(config.ResetPasswordURL)
+	userService.WithTOTPPolicy(config.TOTPIssuer, config.TOTPRequiredRoles)
+	userService.WithSessionExpiry(
+		time.Duration(config.SessionAbsoluteTTLHours)*time.Hour,
+		time.Duration(config.SessionIdleTTLMinutes)*time.Minute,
+	)
+	identityPostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "user_identity", user.Identity{}),
+	)
+	oidcStatePostgresStorage := userPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "oidc_state", user.OIDCState{}),
 	)
-	userService := user.NewService(userPostgresStorage)
+	userService.WithOIDC(buildOIDCProvider(config), identityPostgresStorage, oidcStatePostgresStorage)
+	accessTokens := buildAccessTokens(config)
+	if accessTokens != nil {
+		userService.WithAccessTokens(accessTokens, time.Duration(config.JWTRefreshTTLHours)*time.Hour)
+	}
 
 	productPostgresStorage := productPg.NewPostgresStorage(
-		data.NewPostgresStorage(db, "product", product.Product{}),
+		data.NewPostgresStorage(db, "product", product.Product{}).WithAudit(),
 	)
 	orderHistoryPostgresStorage := productPg.NewPostgresStorage(
 		data.NewPostgresStorage(db, "order_history", product.OrderHistory{}),
 	)
-	productService := product.NewService(productPostgresStorage, orderHistoryPostgresStorage)
+	productImagePostgresStorage := productPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "product_image", product.ProductImage{}),
+	)
+	productImportJobPostgresStorage := productPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "product_import_job", product.ImportJob{}),
+	)
+	productSlugHistoryPostgresStorage := productPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "product_slug_history", product.SlugHistory{}),
+	)
+	productPricePostgresStorage := productPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "product_price", product.ProductPrice{}),
+	)
+	productService := product.NewService(
+		productPostgresStorage,
+		orderHistoryPostgresStorage,
+		productImagePostgresStorage,
+		productImportJobPostgresStorage,
+		productSlugHistoryPostgresStorage,
+		productPricePostgresStorage,
+		buildBlobStore(config),
+	)
+	auditPostgresStorage := auditPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "audit_log", audit.Log{}),
+	)
+	auditService := audit.NewService(auditPostgresStorage)
+	apiKeyPostgresStorage := apiKeyPg.NewPostgresStorage(
+		data.NewPostgresStorage(db, "api_key", apikey.APIKey{}),
+	)
+	apiKeyService := apikey.NewService(apiKeyPostgresStorage)
 	return &InternalServices{
 		userService:    userService,
 		productService: productService,
+		auditService:   auditService,
+		apiKeyService:  apiKeyService,
+		accessTokens:   accessTokens,
 	}
 }
 
@@ -64,11 +222,21 @@ func main() {
 	// Migrate the db
 	databases.MigrateUp()
 
+	jobScheduler := scheduler.NewScheduler()
+	jobScheduler.Add(purgeTrashJob(dataManager, internalServices, time.Duration(config.TrashRetentionDays)*24*time.Hour))
+	jobScheduler.Add(applyScheduledPricesJob(dataManager, internalServices))
+	jobScheduler.Add(deleteExpiredTokensJob(internalServices))
+	jobScheduler.Start()
+	defer jobScheduler.Stop()
+
 	s := internalhttp.NewServer(
 		internalServices.userService,
 		internalServices.productService,
+		internalServices.auditService,
+		internalServices.apiKeyService,
 		dataManager,
 		config,
+		internalServices.accessTokens,
 	)
 	s.Serve()
 }
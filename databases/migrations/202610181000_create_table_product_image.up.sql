@@ -0,0 +1,20 @@
+CREATE TABLE "product_image" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "product_id" int NOT NULL,
+  "key" varchar NOT NULL,
+  "thumbnail_key" varchar NOT NULL,
+  "content_type" varchar(40) NOT NULL,
+  "size" int NOT NULL,
+  "position" int NOT NULL DEFAULT 0,
+  "is_primary" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "product_image" ADD FOREIGN KEY ("product_id") REFERENCES "product" ("id");
+
+CREATE INDEX "product_image_product_id_idx" ON "product_image" ("product_id", "position");
@@ -0,0 +1 @@
+drop table if exists "product_import_job";
@@ -0,0 +1,17 @@
+CREATE TABLE "product_import_job" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "filename" varchar NOT NULL,
+  "status" varchar(20) NOT NULL,
+  "total_rows" int NOT NULL DEFAULT 0,
+  "created_rows" int NOT NULL DEFAULT 0,
+  "updated_rows" int NOT NULL DEFAULT 0,
+  "errors" jsonb NOT NULL DEFAULT '[]',
+  "message" varchar NULL,
+  "finished_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
@@ -0,0 +1,2 @@
+drop table if exists "product_slug_history";
+ALTER TABLE "product" DROP COLUMN IF EXISTS "slug";
@@ -0,0 +1,26 @@
+ALTER TABLE "product" ADD COLUMN "slug" varchar(100) NULL;
+
+-- Backfill the existing products, duplicated slugs are suffixed with the product id
+UPDATE "product" SET "slug" = trim(both '-' from lower(regexp_replace("name", '[^a-zA-Z0-9]+', '-', 'g')));
+UPDATE "product" SET "slug" = 'product-' || "id" WHERE "slug" = '' OR "slug" ~ '^[0-9]+$';
+UPDATE "product" p SET "slug" = p."slug" || '-' || p."id"
+FROM (SELECT "id", row_number() OVER (PARTITION BY "slug" ORDER BY "id") AS "rank" FROM "product") d
+WHERE d."id" = p."id" AND d."rank" > 1;
+
+ALTER TABLE "product" ALTER COLUMN "slug" SET NOT NULL;
+CREATE UNIQUE INDEX "product_slug_idx" ON "product" ("slug");
+
+CREATE TABLE "product_slug_history" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "product_id" int NOT NULL,
+  "slug" varchar(100) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "product_slug_history" ADD FOREIGN KEY ("product_id") REFERENCES "product" ("id");
+CREATE UNIQUE INDEX "product_slug_history_slug_idx" ON "product_slug_history" ("slug");
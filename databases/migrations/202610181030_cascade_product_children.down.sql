@@ -0,0 +1,5 @@
+ALTER TABLE "product_image" DROP CONSTRAINT "product_image_product_id_fkey";
+ALTER TABLE "product_image" ADD FOREIGN KEY ("product_id") REFERENCES "product" ("id");
+
+ALTER TABLE "product_slug_history" DROP CONSTRAINT "product_slug_history_product_id_fkey";
+ALTER TABLE "product_slug_history" ADD FOREIGN KEY ("product_id") REFERENCES "product" ("id");
@@ -0,0 +1,19 @@
+CREATE TABLE "audit_log" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "entity" varchar(40) NOT NULL,
+  "entity_id" int NOT NULL,
+  "action" varchar(20) NOT NULL,
+  "actor" varchar(20) NOT NULL,
+  "request_id" varchar(100) NULL,
+  "changes" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+CREATE INDEX "audit_log_entity_idx" ON "audit_log" ("entity", "entity_id", "created_at");
+CREATE INDEX "audit_log_actor_idx" ON "audit_log" ("actor", "created_at");
+CREATE INDEX "audit_log_request_id_idx" ON "audit_log" ("request_id");
@@ -0,0 +1,22 @@
+CREATE TABLE "product_price" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "product_id" int NOT NULL,
+  "price" int NOT NULL,
+  "effective_from" timestamptz NOT NULL,
+  "applied_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "product_price" ADD FOREIGN KEY ("product_id") REFERENCES "product" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "product_price_product_id_idx" ON "product_price" ("product_id", "effective_from");
+CREATE INDEX "product_price_pending_idx" ON "product_price" ("effective_from") WHERE "applied_at" IS NULL;
+
+-- The current prices start the history
+INSERT INTO "product_price" ("product_id", "price", "effective_from", "applied_at")
+SELECT "id", "price", "created_at", "created_at" FROM "product";
@@ -0,0 +1,4 @@
+ALTER TABLE "user" ADD COLUMN "role" varchar(20) NOT NULL DEFAULT 'customer';
+
+-- The seeded administrator keeps managing the application
+UPDATE "user" SET "role" = 'admin' WHERE "email" = 'admin';
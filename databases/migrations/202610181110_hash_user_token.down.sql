@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "user_email_idx";
+DROP INDEX IF EXISTS "user_token_idx";
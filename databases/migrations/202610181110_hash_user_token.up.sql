@@ -0,0 +1,5 @@
+-- Tokens are stored as SHA-256 hashes from now on, the raw tokens stored so far are revoked
+UPDATE "user" SET "token" = NULL, "tokenExpiredAt" = NULL;
+
+CREATE UNIQUE INDEX "user_token_idx" ON "user" ("token") WHERE "token" IS NOT NULL;
+CREATE INDEX "user_email_idx" ON "user" (lower("email"));
@@ -0,0 +1,4 @@
+ALTER TABLE "user" ADD COLUMN "token" varchar NULL, ADD COLUMN "tokenExpiredAt" timestamptz NULL;
+CREATE UNIQUE INDEX "user_token_idx" ON "user" ("token") WHERE "token" IS NOT NULL;
+
+drop table if exists "session";
@@ -0,0 +1,27 @@
+CREATE TABLE "session" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" int NOT NULL,
+  "token_hash" varchar(64) NOT NULL,
+  "user_agent" varchar(255) NOT NULL DEFAULT '',
+  "ip" varchar(64) NOT NULL DEFAULT '',
+  "last_seen_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "session" ADD FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "session_token_hash_idx" ON "session" ("token_hash");
+CREATE INDEX "session_user_id_idx" ON "session" ("user_id");
+
+-- The live tokens of the user table become the first sessions
+INSERT INTO "session" ("user_id", "token_hash", "expired_at")
+SELECT "id", "token", "tokenExpiredAt" FROM "user" WHERE "token" IS NOT NULL AND "tokenExpiredAt" > now();
+
+DROP INDEX IF EXISTS "user_token_idx";
+ALTER TABLE "user" DROP COLUMN "token", DROP COLUMN "tokenExpiredAt";
@@ -0,0 +1,15 @@
+CREATE TABLE "refresh_token_history" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "session_id" int NOT NULL,
+  "token_hash" varchar(64) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "refresh_token_history" ADD FOREIGN KEY ("session_id") REFERENCES "session" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "refresh_token_history_token_hash_idx" ON "refresh_token_history" ("token_hash");
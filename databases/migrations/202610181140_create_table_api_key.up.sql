@@ -0,0 +1,18 @@
+CREATE TABLE "api_key" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "name" varchar(80) NOT NULL,
+  "prefix" varchar(8) NOT NULL,
+  "key_hash" varchar(64) NOT NULL,
+  "permissions" text[] NOT NULL DEFAULT '{}',
+  "expired_at" timestamptz NULL,
+  "last_used_at" timestamptz NULL,
+  "revoked_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+CREATE UNIQUE INDEX "api_key_key_hash_idx" ON "api_key" ("key_hash");
@@ -0,0 +1,2 @@
+drop table if exists "user_token";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "verified_at";
@@ -0,0 +1,25 @@
+ALTER TABLE "user" ADD COLUMN "verified_at" timestamptz NULL;
+
+-- The existing users keep logging in
+UPDATE "user" SET "verified_at" = "created_at";
+
+CREATE TABLE "user_token" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" int NOT NULL,
+  "purpose" varchar(20) NOT NULL,
+  "token_hash" varchar(64) NOT NULL,
+  "email" varchar(80) NOT NULL,
+  "expired_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "user_token" ADD FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "user_token_token_hash_idx" ON "user_token" ("token_hash");
+CREATE INDEX "user_token_user_id_idx" ON "user_token" ("user_id", "purpose", "created_at");
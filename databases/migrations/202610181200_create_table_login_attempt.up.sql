@@ -0,0 +1,11 @@
+CREATE TABLE "login_attempt" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "key" varchar(100) NOT NULL,
+  "failures" int NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NOT NULL,
+  "locked_until" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX "login_attempt_key_idx" ON "login_attempt" ("key");
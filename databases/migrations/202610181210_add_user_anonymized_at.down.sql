@@ -0,0 +1,2 @@
+-- The redacted audit log changes can not be recovered
+ALTER TABLE "user" DROP COLUMN IF EXISTS "anonymized_at";
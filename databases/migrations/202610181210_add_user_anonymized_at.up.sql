@@ -0,0 +1,10 @@
+ALTER TABLE "user" ADD COLUMN "anonymized_at" timestamptz NULL;
+
+-- Names and emails are no longer copied into the audit log, redact the ones recorded already
+UPDATE "audit_log"
+SET "changes" = "changes" || jsonb_build_object('name', jsonb_build_object('from', '[redacted]', 'to', '[redacted]'))
+WHERE "entity" = 'user' AND "changes" ? 'name';
+
+UPDATE "audit_log"
+SET "changes" = "changes" || jsonb_build_object('email', jsonb_build_object('from', '[redacted]', 'to', '[redacted]'))
+WHERE "entity" = 'user' AND "changes" ? 'email';
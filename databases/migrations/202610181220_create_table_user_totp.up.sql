@@ -0,0 +1,34 @@
+CREATE TABLE "user_totp" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" int NOT NULL,
+  "secret" varchar(64) NOT NULL,
+  "enabled_at" timestamptz NULL,
+  "last_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "user_totp" ADD FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "user_totp_user_id_idx" ON "user_totp" ("user_id");
+
+CREATE TABLE "recovery_code" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" int NOT NULL,
+  "code_hash" varchar(64) NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "recovery_code" ADD FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "recovery_code_user_id_idx" ON "recovery_code" ("user_id");
@@ -0,0 +1,2 @@
+drop table if exists "oidc_state";
+drop table if exists "user_identity";
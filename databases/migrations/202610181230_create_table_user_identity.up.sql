@@ -0,0 +1,34 @@
+CREATE TABLE "user_identity" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" int NOT NULL,
+  "issuer" varchar(255) NOT NULL,
+  "subject" varchar(255) NOT NULL,
+  "email" varchar(255) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+ALTER TABLE "user_identity" ADD FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "user_identity_issuer_subject_idx" ON "user_identity" ("issuer", "subject");
+CREATE INDEX "user_identity_user_id_idx" ON "user_identity" ("user_id");
+
+CREATE TABLE "oidc_state" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "state_hash" varchar(64) NOT NULL,
+  "nonce" varchar(64) NOT NULL,
+  "code_verifier" varchar(128) NOT NULL,
+  "expired_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_by" varchar(20) DEFAULT 'admin',
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_by" varchar(20) DEFAULT 'admin',
+  "deleted_at" timestamptz NULL,
+  "deleted_by" varchar(20) NULL
+);
+
+CREATE UNIQUE INDEX "oidc_state_state_hash_idx" ON "oidc_state" ("state_hash");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "oidc_state_expired_at_idx";
+DROP INDEX IF EXISTS "user_token_expired_at_idx";
+DROP INDEX IF EXISTS "session_expired_at_idx";
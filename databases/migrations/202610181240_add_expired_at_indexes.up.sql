@@ -0,0 +1,3 @@
+CREATE INDEX "session_expired_at_idx" ON "session" ("expired_at");
+CREATE INDEX "user_token_expired_at_idx" ON "user_token" ("expired_at");
+CREATE INDEX "oidc_state_expired_at_idx" ON "oidc_state" ("expired_at");
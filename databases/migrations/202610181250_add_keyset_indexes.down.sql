@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "order_history_created_at_id_idx";
+DROP INDEX IF EXISTS "user_created_at_id_idx";
+DROP INDEX IF EXISTS "product_created_at_id_idx";
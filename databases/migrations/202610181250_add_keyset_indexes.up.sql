@@ -0,0 +1,3 @@
+CREATE INDEX "product_created_at_id_idx" ON "product" ("created_at" DESC, "id" DESC) WHERE "deleted_at" IS NULL;
+CREATE INDEX "user_created_at_id_idx" ON "user" ("created_at" DESC, "id" DESC) WHERE "deleted_at" IS NULL;
+CREATE INDEX "order_history_created_at_id_idx" ON "order_history" ("created_at" DESC, "id" DESC) WHERE "deleted_at" IS NULL;
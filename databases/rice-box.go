@@ -22,14 +22,266 @@ func init() {
 
 		Content: string("-- Table Definition ----------------------------------------------\n\nCREATE TABLE \"user\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"name\" varchar(80) NOT NULL,\n  \"email\" varchar(80) NOT NULL,\n  \"password\" varchar NOT NULL,\n  \"token\" varchar null,\n  \"tokenExpiredAt\" timestamptz null,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nCREATE TABLE \"product\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"name\" varchar(80) NOT NULL,\n  \"qty\" int NOT NULL,\n  \"price\" int NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nCREATE TABLE \"order_history\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"user_id\" int,\n  \"product_id\" int NOT NULL,\n  \"qty\" int NOT NULL,\n  \"price\" int NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"order_history\" ADD FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\");"),
 	}
+	file4 := &embedded.EmbeddedFile{
+		Filename:    "202610181000_create_table_product_image.down.sql",
+		FileModTime: time.Unix(1792354656, 0),
+
+		Content: string("drop table if exists \"product_image\";\n"),
+	}
+	file5 := &embedded.EmbeddedFile{
+		Filename:    "202610181000_create_table_product_image.up.sql",
+		FileModTime: time.Unix(1792354656, 0),
+
+		Content: string("CREATE TABLE \"product_image\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"product_id\" int NOT NULL,\n  \"key\" varchar NOT NULL,\n  \"thumbnail_key\" varchar NOT NULL,\n  \"content_type\" varchar(40) NOT NULL,\n  \"size\" int NOT NULL,\n  \"position\" int NOT NULL DEFAULT 0,\n  \"is_primary\" boolean NOT NULL DEFAULT false,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"product_image\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\");\n\nCREATE INDEX \"product_image_product_id_idx\" ON \"product_image\" (\"product_id\", \"position\");\n"),
+	}
+	file6 := &embedded.EmbeddedFile{
+		Filename:    "202610181010_create_table_product_import_job.down.sql",
+		FileModTime: time.Unix(1792354836, 0),
+
+		Content: string("drop table if exists \"product_import_job\";\n"),
+	}
+	file7 := &embedded.EmbeddedFile{
+		Filename:    "202610181010_create_table_product_import_job.up.sql",
+		FileModTime: time.Unix(1792354836, 0),
+
+		Content: string("CREATE TABLE \"product_import_job\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"filename\" varchar NOT NULL,\n  \"status\" varchar(20) NOT NULL,\n  \"total_rows\" int NOT NULL DEFAULT 0,\n  \"created_rows\" int NOT NULL DEFAULT 0,\n  \"updated_rows\" int NOT NULL DEFAULT 0,\n  \"errors\" jsonb NOT NULL DEFAULT '[]',\n  \"message\" varchar NULL,\n  \"finished_at\" timestamptz NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n"),
+	}
+	file8 := &embedded.EmbeddedFile{
+		Filename:    "202610181020_add_product_slug.down.sql",
+		FileModTime: time.Unix(1792355036, 0),
+
+		Content: string("drop table if exists \"product_slug_history\";\nALTER TABLE \"product\" DROP COLUMN IF EXISTS \"slug\";\n"),
+	}
+	file9 := &embedded.EmbeddedFile{
+		Filename:    "202610181020_add_product_slug.up.sql",
+		FileModTime: time.Unix(1792355036, 0),
+
+		Content: string("ALTER TABLE \"product\" ADD COLUMN \"slug\" varchar(100) NULL;\n\n-- Backfill the existing products, duplicated slugs are suffixed with the product id\nUPDATE \"product\" SET \"slug\" = trim(both '-' from lower(regexp_replace(\"name\", '[^a-zA-Z0-9]+', '-', 'g')));\nUPDATE \"product\" SET \"slug\" = 'product-' || \"id\" WHERE \"slug\" = '' OR \"slug\" ~ '^[0-9]+$';\nUPDATE \"product\" p SET \"slug\" = p.\"slug\" || '-' || p.\"id\"\nFROM (SELECT \"id\", row_number() OVER (PARTITION BY \"slug\" ORDER BY \"id\") AS \"rank\" FROM \"product\") d\nWHERE d.\"id\" = p.\"id\" AND d.\"rank\" > 1;\n\nALTER TABLE \"product\" ALTER COLUMN \"slug\" SET NOT NULL;\nCREATE UNIQUE INDEX \"product_slug_idx\" ON \"product\" (\"slug\");\n\nCREATE TABLE \"product_slug_history\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"product_id\" int NOT NULL,\n  \"slug\" varchar(100) NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"product_slug_history\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\");\nCREATE UNIQUE INDEX \"product_slug_history_slug_idx\" ON \"product_slug_history\" (\"slug\");\n"),
+	}
+	filea := &embedded.EmbeddedFile{
+		Filename:    "202610181030_cascade_product_children.down.sql",
+		FileModTime: time.Unix(1792355183, 0),
+
+		Content: string("ALTER TABLE \"product_image\" DROP CONSTRAINT \"product_image_product_id_fkey\";\nALTER TABLE \"product_image\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\");\n\nALTER TABLE \"product_slug_history\" DROP CONSTRAINT \"product_slug_history_product_id_fkey\";\nALTER TABLE \"product_slug_history\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\");\n"),
+	}
+	fileb := &embedded.EmbeddedFile{
+		Filename:    "202610181030_cascade_product_children.up.sql",
+		FileModTime: time.Unix(1792355183, 0),
+
+		Content: string("-- Images and previous slugs belong to the product, they go away when the product is purged\nALTER TABLE \"product_image\" DROP CONSTRAINT \"product_image_product_id_fkey\";\nALTER TABLE \"product_image\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\") ON DELETE CASCADE;\n\nALTER TABLE \"product_slug_history\" DROP CONSTRAINT \"product_slug_history_product_id_fkey\";\nALTER TABLE \"product_slug_history\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\") ON DELETE CASCADE;\n"),
+	}
+	filec := &embedded.EmbeddedFile{
+		Filename:    "202610181040_create_table_audit_log.down.sql",
+		FileModTime: time.Unix(1792355378, 0),
+
+		Content: string("drop table if exists \"audit_log\";\n"),
+	}
+	filed := &embedded.EmbeddedFile{
+		Filename:    "202610181040_create_table_audit_log.up.sql",
+		FileModTime: time.Unix(1792355378, 0),
+
+		Content: string("CREATE TABLE \"audit_log\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"entity\" varchar(40) NOT NULL,\n  \"entity_id\" int NOT NULL,\n  \"action\" varchar(20) NOT NULL,\n  \"actor\" varchar(20) NOT NULL,\n  \"request_id\" varchar(100) NULL,\n  \"changes\" jsonb NOT NULL DEFAULT '{}',\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nCREATE INDEX \"audit_log_entity_idx\" ON \"audit_log\" (\"entity\", \"entity_id\", \"created_at\");\nCREATE INDEX \"audit_log_actor_idx\" ON \"audit_log\" (\"actor\", \"created_at\");\nCREATE INDEX \"audit_log_request_id_idx\" ON \"audit_log\" (\"request_id\");\n"),
+	}
+	filee := &embedded.EmbeddedFile{
+		Filename:    "202610181050_create_table_product_price.down.sql",
+		FileModTime: time.Unix(1792355446, 0),
+
+		Content: string("drop table if exists \"product_price\";\n"),
+	}
+	filef := &embedded.EmbeddedFile{
+		Filename:    "202610181050_create_table_product_price.up.sql",
+		FileModTime: time.Unix(1792355446, 0),
+
+		Content: string("CREATE TABLE \"product_price\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"product_id\" int NOT NULL,\n  \"price\" int NOT NULL,\n  \"effective_from\" timestamptz NOT NULL,\n  \"applied_at\" timestamptz NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"product_price\" ADD FOREIGN KEY (\"product_id\") REFERENCES \"product\" (\"id\") ON DELETE CASCADE;\n\nCREATE INDEX \"product_price_product_id_idx\" ON \"product_price\" (\"product_id\", \"effective_from\");\nCREATE INDEX \"product_price_pending_idx\" ON \"product_price\" (\"effective_from\") WHERE \"applied_at\" IS NULL;\n\n-- The current prices start the history\nINSERT INTO \"product_price\" (\"product_id\", \"price\", \"effective_from\", \"applied_at\")\nSELECT \"id\", \"price\", \"created_at\", \"created_at\" FROM \"product\";\n"),
+	}
+	fileg := &embedded.EmbeddedFile{
+		Filename:    "202610181100_add_user_role.down.sql",
+		FileModTime: time.Unix(1792355704, 0),
+
+		Content: string("ALTER TABLE \"user\" DROP COLUMN IF EXISTS \"role\";\n"),
+	}
+	fileh := &embedded.EmbeddedFile{
+		Filename:    "202610181100_add_user_role.up.sql",
+		FileModTime: time.Unix(1792355704, 0),
+
+		Content: string("ALTER TABLE \"user\" ADD COLUMN \"role\" varchar(20) NOT NULL DEFAULT 'customer';\n\n-- The seeded administrator keeps managing the application\nUPDATE \"user\" SET \"role\" = 'admin' WHERE \"email\" = 'admin';\n"),
+	}
+	filei := &embedded.EmbeddedFile{
+		Filename:    "202610181110_hash_user_token.down.sql",
+		FileModTime: time.Unix(1792355736, 0),
+
+		Content: string("DROP INDEX IF EXISTS \"user_email_idx\";\nDROP INDEX IF EXISTS \"user_token_idx\";\n"),
+	}
+	filej := &embedded.EmbeddedFile{
+		Filename:    "202610181110_hash_user_token.up.sql",
+		FileModTime: time.Unix(1792355736, 0),
+
+		Content: string("-- Tokens are stored as SHA-256 hashes from now on, the raw tokens stored so far are revoked\nUPDATE \"user\" SET \"token\" = NULL, \"tokenExpiredAt\" = NULL;\n\nCREATE UNIQUE INDEX \"user_token_idx\" ON \"user\" (\"token\") WHERE \"token\" IS NOT NULL;\nCREATE INDEX \"user_email_idx\" ON \"user\" (lower(\"email\"));\n"),
+	}
+	filek := &embedded.EmbeddedFile{
+		Filename:    "202610181120_create_table_session.down.sql",
+		FileModTime: time.Unix(1792355823, 0),
+
+		Content: string("ALTER TABLE \"user\" ADD COLUMN \"token\" varchar NULL, ADD COLUMN \"tokenExpiredAt\" timestamptz NULL;\nCREATE UNIQUE INDEX \"user_token_idx\" ON \"user\" (\"token\") WHERE \"token\" IS NOT NULL;\n\ndrop table if exists \"session\";\n"),
+	}
+	filel := &embedded.EmbeddedFile{
+		Filename:    "202610181120_create_table_session.up.sql",
+		FileModTime: time.Unix(1792355823, 0),
+
+		Content: string("CREATE TABLE \"session\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"user_id\" int NOT NULL,\n  \"token_hash\" varchar(64) NOT NULL,\n  \"user_agent\" varchar(255) NOT NULL DEFAULT '',\n  \"ip\" varchar(64) NOT NULL DEFAULT '',\n  \"last_seen_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"expired_at\" timestamptz NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"session\" ADD FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\") ON DELETE CASCADE;\n\nCREATE UNIQUE INDEX \"session_token_hash_idx\" ON \"session\" (\"token_hash\");\nCREATE INDEX \"session_user_id_idx\" ON \"session\" (\"user_id\");\n\n-- The live tokens of the user table become the first sessions\nINSERT INTO \"session\" (\"user_id\", \"token_hash\", \"expired_at\")\nSELECT \"id\", \"token\", \"tokenExpiredAt\" FROM \"user\" WHERE \"token\" IS NOT NULL AND \"tokenExpiredAt\" > now();\n\nDROP INDEX IF EXISTS \"user_token_idx\";\nALTER TABLE \"user\" DROP COLUMN \"token\", DROP COLUMN \"tokenExpiredAt\";\n"),
+	}
+	filem := &embedded.EmbeddedFile{
+		Filename:    "202610181130_create_table_refresh_token_history.down.sql",
+		FileModTime: time.Unix(1792355964, 0),
+
+		Content: string("drop table if exists \"refresh_token_history\";\n"),
+	}
+	filen := &embedded.EmbeddedFile{
+		Filename:    "202610181130_create_table_refresh_token_history.up.sql",
+		FileModTime: time.Unix(1792355964, 0),
+
+		Content: string("CREATE TABLE \"refresh_token_history\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"session_id\" int NOT NULL,\n  \"token_hash\" varchar(64) NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"refresh_token_history\" ADD FOREIGN KEY (\"session_id\") REFERENCES \"session\" (\"id\") ON DELETE CASCADE;\n\nCREATE UNIQUE INDEX \"refresh_token_history_token_hash_idx\" ON \"refresh_token_history\" (\"token_hash\");\n"),
+	}
+	fileo := &embedded.EmbeddedFile{
+		Filename:    "202610181140_create_table_api_key.down.sql",
+		FileModTime: time.Unix(1792356066, 0),
+
+		Content: string("drop table if exists \"api_key\";\n"),
+	}
+	filep := &embedded.EmbeddedFile{
+		Filename:    "202610181140_create_table_api_key.up.sql",
+		FileModTime: time.Unix(1792356066, 0),
+
+		Content: string("CREATE TABLE \"api_key\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"name\" varchar(80) NOT NULL,\n  \"prefix\" varchar(8) NOT NULL,\n  \"key_hash\" varchar(64) NOT NULL,\n  \"permissions\" text[] NOT NULL DEFAULT '{}',\n  \"expired_at\" timestamptz NULL,\n  \"last_used_at\" timestamptz NULL,\n  \"revoked_at\" timestamptz NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nCREATE UNIQUE INDEX \"api_key_key_hash_idx\" ON \"api_key\" (\"key_hash\");\n"),
+	}
+	fileq := &embedded.EmbeddedFile{
+		Filename:    "202610181150_add_user_verification.down.sql",
+		FileModTime: time.Unix(1792356186, 0),
+
+		Content: string("drop table if exists \"user_token\";\nALTER TABLE \"user\" DROP COLUMN IF EXISTS \"verified_at\";\n"),
+	}
+	filer := &embedded.EmbeddedFile{
+		Filename:    "202610181150_add_user_verification.up.sql",
+		FileModTime: time.Unix(1792356186, 0),
+
+		Content: string("ALTER TABLE \"user\" ADD COLUMN \"verified_at\" timestamptz NULL;\n\n-- The existing users keep logging in\nUPDATE \"user\" SET \"verified_at\" = \"created_at\";\n\nCREATE TABLE \"user_token\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"user_id\" int NOT NULL,\n  \"purpose\" varchar(20) NOT NULL,\n  \"token_hash\" varchar(64) NOT NULL,\n  \"email\" varchar(80) NOT NULL,\n  \"expired_at\" timestamptz NOT NULL,\n  \"used_at\" timestamptz NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"user_token\" ADD FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\") ON DELETE CASCADE;\n\nCREATE UNIQUE INDEX \"user_token_token_hash_idx\" ON \"user_token\" (\"token_hash\");\nCREATE INDEX \"user_token_user_id_idx\" ON \"user_token\" (\"user_id\", \"purpose\", \"created_at\");\n"),
+	}
+	files := &embedded.EmbeddedFile{
+		Filename:    "202610181200_create_table_login_attempt.down.sql",
+		FileModTime: time.Unix(1792357040, 0),
+
+		Content: string("drop table if exists \"login_attempt\";\n"),
+	}
+	filet := &embedded.EmbeddedFile{
+		Filename:    "202610181200_create_table_login_attempt.up.sql",
+		FileModTime: time.Unix(1792359786, 0),
+
+		Content: string("CREATE TABLE \"login_attempt\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"key\" varchar(100) NOT NULL,\n  \"failures\" int NOT NULL DEFAULT 0,\n  \"last_failed_at\" timestamptz NOT NULL,\n  \"locked_until\" timestamptz NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now())\n);\n\nCREATE UNIQUE INDEX \"login_attempt_key_idx\" ON \"login_attempt\" (\"key\");\n"),
+	}
+	fileu := &embedded.EmbeddedFile{
+		Filename:    "202610181210_add_user_anonymized_at.down.sql",
+		FileModTime: time.Unix(1792357411, 0),
+
+		Content: string("-- The redacted audit log changes can not be recovered\nALTER TABLE \"user\" DROP COLUMN IF EXISTS \"anonymized_at\";\n"),
+	}
+	filev := &embedded.EmbeddedFile{
+		Filename:    "202610181210_add_user_anonymized_at.up.sql",
+		FileModTime: time.Unix(1792357411, 0),
+
+		Content: string("ALTER TABLE \"user\" ADD COLUMN \"anonymized_at\" timestamptz NULL;\n\n-- Names and emails are no longer copied into the audit log, redact the ones recorded already\nUPDATE \"audit_log\"\nSET \"changes\" = \"changes\" || jsonb_build_object('name', jsonb_build_object('from', '[redacted]', 'to', '[redacted]'))\nWHERE \"entity\" = 'user' AND \"changes\" ? 'name';\n\nUPDATE \"audit_log\"\nSET \"changes\" = \"changes\" || jsonb_build_object('email', jsonb_build_object('from', '[redacted]', 'to', '[redacted]'))\nWHERE \"entity\" = 'user' AND \"changes\" ? 'email';\n"),
+	}
+	filew := &embedded.EmbeddedFile{
+		Filename:    "202610181220_create_table_user_totp.down.sql",
+		FileModTime: time.Unix(1792357723, 0),
+
+		Content: string("drop table if exists \"recovery_code\";\ndrop table if exists \"user_totp\";\n"),
+	}
+	filex := &embedded.EmbeddedFile{
+		Filename:    "202610181220_create_table_user_totp.up.sql",
+		FileModTime: time.Unix(1792357723, 0),
+
+		Content: string("CREATE TABLE \"user_totp\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"user_id\" int NOT NULL,\n  \"secret\" varchar(64) NOT NULL,\n  \"enabled_at\" timestamptz NULL,\n  \"last_step\" bigint NOT NULL DEFAULT 0,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"user_totp\" ADD FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\") ON DELETE CASCADE;\n\nCREATE UNIQUE INDEX \"user_totp_user_id_idx\" ON \"user_totp\" (\"user_id\");\n\nCREATE TABLE \"recovery_code\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"user_id\" int NOT NULL,\n  \"code_hash\" varchar(64) NOT NULL,\n  \"used_at\" timestamptz NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"recovery_code\" ADD FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\") ON DELETE CASCADE;\n\nCREATE INDEX \"recovery_code_user_id_idx\" ON \"recovery_code\" (\"user_id\");\n"),
+	}
+	filey := &embedded.EmbeddedFile{
+		Filename:    "202610181230_create_table_user_identity.down.sql",
+		FileModTime: time.Unix(1792357955, 0),
+
+		Content: string("drop table if exists \"oidc_state\";\ndrop table if exists \"user_identity\";\n"),
+	}
+	filez := &embedded.EmbeddedFile{
+		Filename:    "202610181230_create_table_user_identity.up.sql",
+		FileModTime: time.Unix(1792357955, 0),
+
+		Content: string("CREATE TABLE \"user_identity\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"user_id\" int NOT NULL,\n  \"issuer\" varchar(255) NOT NULL,\n  \"subject\" varchar(255) NOT NULL,\n  \"email\" varchar(255) NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nALTER TABLE \"user_identity\" ADD FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\") ON DELETE CASCADE;\n\nCREATE UNIQUE INDEX \"user_identity_issuer_subject_idx\" ON \"user_identity\" (\"issuer\", \"subject\");\nCREATE INDEX \"user_identity_user_id_idx\" ON \"user_identity\" (\"user_id\");\n\nCREATE TABLE \"oidc_state\" (\n  \"id\" SERIAL PRIMARY KEY NOT NULL,\n  \"state_hash\" varchar(64) NOT NULL,\n  \"nonce\" varchar(64) NOT NULL,\n  \"code_verifier\" varchar(128) NOT NULL,\n  \"expired_at\" timestamptz NOT NULL,\n  \"created_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"created_by\" varchar(20) DEFAULT 'admin',\n  \"updated_at\" timestamptz NOT NULL DEFAULT (now()),\n  \"updated_by\" varchar(20) DEFAULT 'admin',\n  \"deleted_at\" timestamptz NULL,\n  \"deleted_by\" varchar(20) NULL\n);\n\nCREATE UNIQUE INDEX \"oidc_state_state_hash_idx\" ON \"oidc_state\" (\"state_hash\");\n"),
+	}
+	file10 := &embedded.EmbeddedFile{
+		Filename:    "202610181240_add_expired_at_indexes.down.sql",
+		FileModTime: time.Unix(1792358168, 0),
+
+		Content: string("DROP INDEX IF EXISTS \"oidc_state_expired_at_idx\";\nDROP INDEX IF EXISTS \"user_token_expired_at_idx\";\nDROP INDEX IF EXISTS \"session_expired_at_idx\";\n"),
+	}
+	file11 := &embedded.EmbeddedFile{
+		Filename:    "202610181240_add_expired_at_indexes.up.sql",
+		FileModTime: time.Unix(1792358168, 0),
+
+		Content: string("CREATE INDEX \"session_expired_at_idx\" ON \"session\" (\"expired_at\");\nCREATE INDEX \"user_token_expired_at_idx\" ON \"user_token\" (\"expired_at\");\nCREATE INDEX \"oidc_state_expired_at_idx\" ON \"oidc_state\" (\"expired_at\");\n"),
+	}
+	file12 := &embedded.EmbeddedFile{
+		Filename:    "202610181250_add_keyset_indexes.down.sql",
+		FileModTime: time.Unix(1792358543, 0),
+
+		Content: string("DROP INDEX IF EXISTS \"order_history_created_at_id_idx\";\nDROP INDEX IF EXISTS \"user_created_at_id_idx\";\nDROP INDEX IF EXISTS \"product_created_at_id_idx\";\n"),
+	}
+	file13 := &embedded.EmbeddedFile{
+		Filename:    "202610181250_add_keyset_indexes.up.sql",
+		FileModTime: time.Unix(1792358543, 0),
+
+		Content: string("CREATE INDEX \"product_created_at_id_idx\" ON \"product\" (\"created_at\" DESC, \"id\" DESC) WHERE \"deleted_at\" IS NULL;\nCREATE INDEX \"user_created_at_id_idx\" ON \"user\" (\"created_at\" DESC, \"id\" DESC) WHERE \"deleted_at\" IS NULL;\nCREATE INDEX \"order_history_created_at_id_idx\" ON \"order_history\" (\"created_at\" DESC, \"id\" DESC) WHERE \"deleted_at\" IS NULL;\n"),
+	}
 
 	// define dirs
 	dir1 := &embedded.EmbeddedDir{
 		Filename:   "",
-		DirModTime: time.Unix(1612074654, 0),
+		DirModTime: time.Unix(1792358543, 0),
 		ChildFiles: []*embedded.EmbeddedFile{
-			file2, // "202101312211_create_table_user.down.sql"
-			file3, // "202101312211_create_table_user.up.sql"
+			file2,  // "202101312211_create_table_user.down.sql"
+			file3,  // "202101312211_create_table_user.up.sql"
+			file4,  // "202610181000_create_table_product_image.down.sql"
+			file5,  // "202610181000_create_table_product_image.up.sql"
+			file6,  // "202610181010_create_table_product_import_job.down.sql"
+			file7,  // "202610181010_create_table_product_import_job.up.sql"
+			file8,  // "202610181020_add_product_slug.down.sql"
+			file9,  // "202610181020_add_product_slug.up.sql"
+			filea,  // "202610181030_cascade_product_children.down.sql"
+			fileb,  // "202610181030_cascade_product_children.up.sql"
+			filec,  // "202610181040_create_table_audit_log.down.sql"
+			filed,  // "202610181040_create_table_audit_log.up.sql"
+			filee,  // "202610181050_create_table_product_price.down.sql"
+			filef,  // "202610181050_create_table_product_price.up.sql"
+			fileg,  // "202610181100_add_user_role.down.sql"
+			fileh,  // "202610181100_add_user_role.up.sql"
+			filei,  // "202610181110_hash_user_token.down.sql"
+			filej,  // "202610181110_hash_user_token.up.sql"
+			filek,  // "202610181120_create_table_session.down.sql"
+			filel,  // "202610181120_create_table_session.up.sql"
+			filem,  // "202610181130_create_table_refresh_token_history.down.sql"
+			filen,  // "202610181130_create_table_refresh_token_history.up.sql"
+			fileo,  // "202610181140_create_table_api_key.down.sql"
+			filep,  // "202610181140_create_table_api_key.up.sql"
+			fileq,  // "202610181150_add_user_verification.down.sql"
+			filer,  // "202610181150_add_user_verification.up.sql"
+			files,  // "202610181200_create_table_login_attempt.down.sql"
+			filet,  // "202610181200_create_table_login_attempt.up.sql"
+			fileu,  // "202610181210_add_user_anonymized_at.down.sql"
+			filev,  // "202610181210_add_user_anonymized_at.up.sql"
+			filew,  // "202610181220_create_table_user_totp.down.sql"
+			filex,  // "202610181220_create_table_user_totp.up.sql"
+			filey,  // "202610181230_create_table_user_identity.down.sql"
+			filez,  // "202610181230_create_table_user_identity.up.sql"
+			file10, // "202610181240_add_expired_at_indexes.down.sql"
+			file11, // "202610181240_add_expired_at_indexes.up.sql"
+			file12, // "202610181250_add_keyset_indexes.down.sql"
+			file13, // "202610181250_add_keyset_indexes.up.sql"
 
 		},
 	}
@@ -40,13 +292,49 @@ func init() {
 	// register embeddedBox
 	embedded.RegisterEmbeddedBox(`./migrations`, &embedded.EmbeddedBox{
 		Name: `./migrations`,
-		Time: time.Unix(1612074654, 0),
+		Time: time.Unix(1792358543, 0),
 		Dirs: map[string]*embedded.EmbeddedDir{
 			"": dir1,
 		},
 		Files: map[string]*embedded.EmbeddedFile{
-			"202101312211_create_table_user.down.sql": file2,
-			"202101312211_create_table_user.up.sql":   file3,
+			"202101312211_create_table_user.down.sql":                  file2,
+			"202101312211_create_table_user.up.sql":                    file3,
+			"202610181000_create_table_product_image.down.sql":         file4,
+			"202610181000_create_table_product_image.up.sql":           file5,
+			"202610181010_create_table_product_import_job.down.sql":    file6,
+			"202610181010_create_table_product_import_job.up.sql":      file7,
+			"202610181020_add_product_slug.down.sql":                   file8,
+			"202610181020_add_product_slug.up.sql":                     file9,
+			"202610181030_cascade_product_children.down.sql":           filea,
+			"202610181030_cascade_product_children.up.sql":             fileb,
+			"202610181040_create_table_audit_log.down.sql":             filec,
+			"202610181040_create_table_audit_log.up.sql":               filed,
+			"202610181050_create_table_product_price.down.sql":         filee,
+			"202610181050_create_table_product_price.up.sql":           filef,
+			"202610181100_add_user_role.down.sql":                      fileg,
+			"202610181100_add_user_role.up.sql":                        fileh,
+			"202610181110_hash_user_token.down.sql":                    filei,
+			"202610181110_hash_user_token.up.sql":                      filej,
+			"202610181120_create_table_session.down.sql":               filek,
+			"202610181120_create_table_session.up.sql":                 filel,
+			"202610181130_create_table_refresh_token_history.down.sql": filem,
+			"202610181130_create_table_refresh_token_history.up.sql":   filen,
+			"202610181140_create_table_api_key.down.sql":               fileo,
+			"202610181140_create_table_api_key.up.sql":                 filep,
+			"202610181150_add_user_verification.down.sql":              fileq,
+			"202610181150_add_user_verification.up.sql":                filer,
+			"202610181200_create_table_login_attempt.down.sql":         files,
+			"202610181200_create_table_login_attempt.up.sql":           filet,
+			"202610181210_add_user_anonymized_at.down.sql":             fileu,
+			"202610181210_add_user_anonymized_at.up.sql":               filev,
+			"202610181220_create_table_user_totp.down.sql":             filew,
+			"202610181220_create_table_user_totp.up.sql":               filex,
+			"202610181230_create_table_user_identity.down.sql":         filey,
+			"202610181230_create_table_user_identity.up.sql":           filez,
+			"202610181240_add_expired_at_indexes.down.sql":             file10,
+			"202610181240_add_expired_at_indexes.up.sql":               file11,
+			"202610181250_add_keyset_indexes.down.sql":                 file12,
+			"202610181250_add_keyset_indexes.up.sql":                   file13,
 		},
 	})
 }
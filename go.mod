@@ -12,10 +12,12 @@ require (
 	github.com/docker/go-units v0.4.0 // indirect
 	github.com/go-chi/chi v1.5.1
 	github.com/go-playground/universal-translator v0.17.0 // indirect
+	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/golang-migrate/migrate v3.5.4+incompatible
 	github.com/gosimple/slug v1.9.0
 	github.com/jmoiron/sqlx v1.3.1
 	github.com/leodido/go-urn v1.2.1 // indirect
+	github.com/lib/pq v1.2.0
 	github.com/moby/term v0.0.0-20201216013528-df9cb8a40635 // indirect
 	github.com/morikuni/aec v1.0.0 // indirect
 	github.com/opencontainers/go-digest v1.0.0 // indirect
@@ -0,0 +1,238 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+const (
+	// keyPrefixLength is the length of the key prefix shown to tell the keys apart
+	keyPrefixLength = 8
+
+	// touchInterval limits how often the last used time of a key is written
+	touchInterval = 5 * time.Minute
+)
+
+// Errors
+var (
+	ErrNameRequired      = errors.New("Name is required")
+	ErrPermissionInvalid = errors.New("Permission can not be granted to an api key")
+	ErrExpiredAtInPast   = errors.New("Expired at should be in the future")
+	ErrKeyExpired        = errors.New("api key expired")
+	ErrKeyRevoked        = errors.New("api key revoked")
+)
+
+// APIKey represents a key of a service-to-service client, only the hash of the key is stored
+type APIKey struct {
+	ID          int            `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	Prefix      string         `json:"prefix" db:"prefix"`
+	KeyHash     string         `json:"-" db:"key_hash" audit:"-"`
+	Permissions pq.StringArray `json:"permissions" db:"permissions"`
+	ExpiredAt   *time.Time     `json:"expiredAt" db:"expired_at"`
+	LastUsedAt  *time.Time     `json:"lastUsedAt" db:"last_used_at"`
+	RevokedAt   *time.Time     `json:"revokedAt" db:"revoked_at"`
+	CreatedAt   time.Time      `json:"createdAt" db:"created_at"`
+	CreatedBy   *string        `json:"createdBy" db:"created_by"`
+	UpdatedAt   *time.Time     `json:"updatedAt" db:"updated_at"`
+	UpdatedBy   *string        `json:"updatedBy" db:"updated_by"`
+}
+
+//FindAllAPIKeysParams params for find all
+type FindAllAPIKeysParams struct {
+	ID      int    `json:"id"`
+	KeyHash string `json:"-"`
+}
+
+// CreateAPIKeyParams represent the http request data for create api key
+// swagger:model
+type CreateAPIKeyParams struct {
+	Name        string     `json:"name" validate:"required,max=80"`
+	Permissions []string   `json:"permissions" validate:"required,min=1,dive,required"`
+	ExpiredAt   *time.Time `json:"expiredAt"`
+}
+
+// CreateAPIKeyResponse represents the created api key, the key itself is only returned once
+// swagger:model
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"apiKey"`
+}
+
+// Storage represents the api key storage interface
+type Storage interface {
+	FindAll(ctx context.Context, params *FindAllAPIKeysParams) ([]*APIKey, *types.Error)
+	FindByID(ctx context.Context, apiKeyID int) (*APIKey, *types.Error)
+	FindByKeyHash(ctx context.Context, keyHash string) (*APIKey, *types.Error)
+	Insert(ctx context.Context, apiKey *APIKey) (*APIKey, *types.Error)
+	Update(ctx context.Context, apiKey *APIKey) (*APIKey, *types.Error)
+	TouchAPIKey(ctx context.Context, apiKeyID int, usedAt time.Time) (*APIKey, *types.Error)
+}
+
+// ServiceInterface represents the api key service interface
+type ServiceInterface interface {
+	ListAPIKeys(ctx context.Context) ([]*APIKey, *types.Error)
+	CreateAPIKey(ctx context.Context, params *CreateAPIKeyParams) (*CreateAPIKeyResponse, *types.Error)
+	RevokeAPIKey(ctx context.Context, apiKeyID int) *types.Error
+	Authenticate(ctx context.Context, key string) (*APIKey, *types.Error)
+}
+
+// Service is the domain logic implementation of api key Service interface
+type Service struct {
+	apiKeyStorage Storage
+}
+
+func generateKey() (string, error) {
+	buff := make([]byte, 32)
+	_, err := rand.Read(buff)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", buff), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func validationError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    "validation-error",
+	}
+}
+
+// ListAPIKeys is listing api keys
+func (s *Service) ListAPIKeys(ctx context.Context) ([]*APIKey, *types.Error) {
+	apiKeys, err := s.apiKeyStorage.FindAll(ctx, &FindAllAPIKeysParams{})
+	if err != nil {
+		err.Path = ".APIKeyService->ListAPIKeys()" + err.Path
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// CreateAPIKey create api key granted with the permissions
+func (s *Service) CreateAPIKey(ctx context.Context, params *CreateAPIKeyParams) (*CreateAPIKeyResponse, *types.Error) {
+	if params.Name == "" {
+		return nil, validationError(".APIKeyService->CreateAPIKey()", ErrNameRequired)
+	}
+	permissions := pq.StringArray{}
+	for _, permission := range params.Permissions {
+		if !user.ClientPermission(permission) {
+			return nil, validationError(".APIKeyService->CreateAPIKey()", ErrPermissionInvalid)
+		}
+		permissions = append(permissions, permission)
+	}
+	now := time.Now()
+	if params.ExpiredAt != nil && !params.ExpiredAt.After(now) {
+		return nil, validationError(".APIKeyService->CreateAPIKey()", ErrExpiredAtInPast)
+	}
+
+	key, errKey := generateKey()
+	if errKey != nil {
+		return nil, &types.Error{
+			Path:    ".APIKeyService->CreateAPIKey()",
+			Message: errKey.Error(),
+			Error:   errKey,
+			Type:    "golang-error",
+		}
+	}
+
+	apiKey, err := s.apiKeyStorage.Insert(ctx, &APIKey{
+		Name:        params.Name,
+		Prefix:      key[:keyPrefixLength],
+		KeyHash:     hashKey(key),
+		Permissions: permissions,
+		ExpiredAt:   params.ExpiredAt,
+		CreatedAt:   now,
+		UpdatedAt:   &now,
+	})
+	if err != nil {
+		err.Path = ".APIKeyService->CreateAPIKey()" + err.Path
+		return nil, err
+	}
+
+	return &CreateAPIKeyResponse{
+		Key:    key,
+		APIKey: apiKey,
+	}, nil
+}
+
+// RevokeAPIKey revoke api key, it can not be used anymore
+func (s *Service) RevokeAPIKey(ctx context.Context, apiKeyID int) *types.Error {
+	apiKey, err := s.apiKeyStorage.FindByID(ctx, apiKeyID)
+	if err != nil {
+		err.Path = ".APIKeyService->RevokeAPIKey()" + err.Path
+		return err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	apiKey.UpdatedAt = &now
+	_, err = s.apiKeyStorage.Update(ctx, apiKey)
+	if err != nil {
+		err.Path = ".APIKeyService->RevokeAPIKey()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate get the api key of the key, revoked and expired keys are refused.
+// The last used time is written at most once per touch interval.
+func (s *Service) Authenticate(ctx context.Context, key string) (*APIKey, *types.Error) {
+	apiKey, err := s.apiKeyStorage.FindByKeyHash(ctx, hashKey(key))
+	if err != nil {
+		err.Path = ".APIKeyService->Authenticate()" + err.Path
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return nil, validationError(".APIKeyService->Authenticate()", ErrKeyRevoked)
+	}
+	if apiKey.ExpiredAt != nil && apiKey.ExpiredAt.Before(now) {
+		return nil, validationError(".APIKeyService->Authenticate()", ErrKeyExpired)
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= touchInterval {
+		apiKey, err = s.apiKeyStorage.TouchAPIKey(ctx, apiKey.ID, now)
+		if err != nil {
+			// the key was revoked since it was read
+			if err.Error == data.ErrNotFound {
+				return nil, validationError(".APIKeyService->Authenticate()", ErrKeyRevoked)
+			}
+			err.Path = ".APIKeyService->Authenticate()" + err.Path
+			return nil, err
+		}
+	}
+
+	return apiKey, nil
+}
+
+// NewService creates a new api key AppService
+func NewService(
+	apiKeyStorage Storage,
+) *Service {
+	return &Service{
+		apiKeyStorage: apiKeyStorage,
+	}
+}
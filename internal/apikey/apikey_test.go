@@ -0,0 +1,122 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// memStorage keeps the api keys in memory, the methods Authenticate does not use are left to the nil interface
+type memStorage struct {
+	Storage
+	apiKeys []*APIKey
+	touches int
+}
+
+func (s *memStorage) FindByKeyHash(ctx context.Context, keyHash string) (*APIKey, *types.Error) {
+	for _, apiKey := range s.apiKeys {
+		if apiKey.KeyHash == keyHash {
+			// a copy, as the row read from the database
+			found := *apiKey
+			return &found, nil
+		}
+	}
+	return nil, &types.Error{
+		Path:    ".memStorage->FindByKeyHash()",
+		Message: data.ErrNotFound.Error(),
+		Error:   data.ErrNotFound,
+		Type:    "pq-error",
+	}
+}
+
+func (s *memStorage) TouchAPIKey(ctx context.Context, apiKeyID int, usedAt time.Time) (*APIKey, *types.Error) {
+	for _, apiKey := range s.apiKeys {
+		if apiKey.ID == apiKeyID && apiKey.RevokedAt == nil {
+			s.touches++
+			apiKey.LastUsedAt = &usedAt
+			touched := *apiKey
+			return &touched, nil
+		}
+	}
+	return nil, &types.Error{
+		Path:    ".memStorage->TouchAPIKey()",
+		Message: data.ErrNotFound.Error(),
+		Error:   data.ErrNotFound,
+		Type:    "pq-error",
+	}
+}
+
+// revokeOnFind revokes the key right after it is read, as a revocation running concurrently
+type revokeOnFind struct {
+	*memStorage
+}
+
+func (s *revokeOnFind) FindByKeyHash(ctx context.Context, keyHash string) (*APIKey, *types.Error) {
+	apiKey, err := s.memStorage.FindByKeyHash(ctx, keyHash)
+	if err == nil {
+		now := time.Now()
+		s.apiKeys[0].RevokedAt = &now
+	}
+	return apiKey, err
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	recently := now.Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		apiKey      APIKey
+		key         string
+		wantErr     error
+		wantTouches int
+	}{
+		{"first use", APIKey{}, "key", nil, 1},
+		{"used recently", APIKey{LastUsedAt: &recently}, "key", nil, 0},
+		{"used before the touch interval", APIKey{LastUsedAt: &past}, "key", nil, 1},
+		{"not expired yet", APIKey{ExpiredAt: &future}, "key", nil, 1},
+		{"expired", APIKey{ExpiredAt: &past}, "key", ErrKeyExpired, 0},
+		{"revoked", APIKey{RevokedAt: &past}, "key", ErrKeyRevoked, 0},
+		{"unknown key", APIKey{}, "other", data.ErrNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKey := tt.apiKey
+			apiKey.ID = 1
+			apiKey.KeyHash = hashKey("key")
+			storage := &memStorage{apiKeys: []*APIKey{&apiKey}}
+
+			got, err := NewService(storage).Authenticate(context.Background(), tt.key)
+			if tt.wantErr != nil {
+				if err == nil || err.Error != tt.wantErr {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Authenticate() error = %v", err.Error)
+			} else if got.ID != 1 || got.LastUsedAt == nil {
+				t.Errorf("Authenticate() = %+v, want the key with its last used time", got)
+			}
+			if storage.touches != tt.wantTouches {
+				t.Errorf("touched %d times, want %d", storage.touches, tt.wantTouches)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRevokedWhileReading(t *testing.T) {
+	apiKey := &APIKey{ID: 1, KeyHash: hashKey("key")}
+	storage := &revokeOnFind{&memStorage{apiKeys: []*APIKey{apiKey}}}
+
+	_, err := NewService(storage).Authenticate(context.Background(), "key")
+	if err == nil || err.Error != ErrKeyRevoked {
+		t.Errorf("Authenticate() error = %v, want %v", err, ErrKeyRevoked)
+	}
+	if apiKey.RevokedAt == nil {
+		t.Error("the revocation was overwritten")
+	}
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/apikey"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// PostgresStorage implements the api key storage service interface
+type PostgresStorage struct {
+	Storage data.GenericStorage
+}
+
+// FindAll find all api keys
+func (s *PostgresStorage) FindAll(ctx context.Context, params *apikey.FindAllAPIKeysParams) ([]*apikey.APIKey, *types.Error) {
+	apiKeys := []*apikey.APIKey{}
+
+	where := `"deleted_at" IS NULL`
+	if params.ID != 0 {
+		where += ` AND "id" = :id`
+	}
+	if params.KeyHash != "" {
+		where += ` AND "key_hash" = :keyHash`
+	}
+	where += ` ORDER BY "id" DESC`
+
+	err := s.Storage.Where(ctx, &apiKeys, where, map[string]interface{}{
+		"id":      params.ID,
+		"keyHash": params.KeyHash,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->FindAll()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return apiKeys, nil
+}
+
+// FindByID find api key by its id
+func (s *PostgresStorage) FindByID(ctx context.Context, apiKeyID int) (*apikey.APIKey, *types.Error) {
+	apiKeys, err := s.FindAll(ctx, &apikey.FindAllAPIKeysParams{
+		ID: apiKeyID,
+	})
+	if err != nil {
+		err.Path = ".APIKeyPostgresStorage->FindByID()" + err.Path
+		return nil, err
+	}
+
+	if len(apiKeys) < 1 {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->FindByID()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return apiKeys[0], nil
+}
+
+// FindByKeyHash find api key by the hash of its key
+func (s *PostgresStorage) FindByKeyHash(ctx context.Context, keyHash string) (*apikey.APIKey, *types.Error) {
+	apiKeys, err := s.FindAll(ctx, &apikey.FindAllAPIKeysParams{
+		KeyHash: keyHash,
+	})
+	if err != nil {
+		err.Path = ".APIKeyPostgresStorage->FindByKeyHash()" + err.Path
+		return nil, err
+	}
+
+	if len(apiKeys) < 1 {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->FindByKeyHash()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return apiKeys[0], nil
+}
+
+// Insert insert api key
+func (s *PostgresStorage) Insert(ctx context.Context, apiKey *apikey.APIKey) (*apikey.APIKey, *types.Error) {
+	err := s.Storage.Insert(ctx, apiKey)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->Insert()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return apiKey, nil
+}
+
+// Update update api key
+func (s *PostgresStorage) Update(ctx context.Context, apiKey *apikey.APIKey) (*apikey.APIKey, *types.Error) {
+	err := s.Storage.Update(ctx, apiKey)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->Update()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return apiKey, nil
+}
+
+// TouchAPIKey sets the last used time of the api key unless it was revoked, only that column is written
+// so a revocation made since the key was read is kept
+func (s *PostgresStorage) TouchAPIKey(ctx context.Context, apiKeyID int, usedAt time.Time) (*apikey.APIKey, *types.Error) {
+	apiKeys := []*apikey.APIKey{}
+
+	err := s.Storage.UpdateWhere(ctx, &apiKeys, `"last_used_at" = :usedAt`,
+		`"id" = :id AND "revoked_at" IS NULL AND "deleted_at" IS NULL`,
+		map[string]interface{}{
+			"id":     apiKeyID,
+			"usedAt": usedAt,
+		})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->TouchAPIKey()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	if len(apiKeys) < 1 {
+		return nil, &types.Error{
+			Path:    ".APIKeyPostgresStorage->TouchAPIKey()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return apiKeys[0], nil
+}
+
+// NewPostgresStorage creates new api key repository service
+func NewPostgresStorage(
+	storage data.GenericStorage,
+) *PostgresStorage {
+	return &PostgresStorage{
+		Storage: storage,
+	}
+}
@@ -54,6 +54,15 @@ const (
 
 	// KeyAllLog represents the key Log String in server context
 	KeyAllLog contextKey = "KeyAllLog"
+
+	// KeyRequestID represents the id of the current http request
+	KeyRequestID contextKey = "RequestID"
+
+	// KeyUserRole represents the role of the current logged-in user
+	KeyUserRole contextKey = "UserRole"
+
+	// KeyUserSessionID represents the id of the session of the current logged-in user
+	KeyUserSessionID contextKey = "UserSessionID"
 )
 
 // Owner gets the data owner from the context
@@ -213,3 +222,33 @@ func AllLog(ctx context.Context) *string {
 	}
 	return nil
 }
+
+// RequestID gets the id of the current http request from the context
+func RequestID(ctx context.Context) string {
+	requestID := ctx.Value(KeyRequestID)
+	if requestID != nil {
+		v := requestID.(string)
+		return v
+	}
+	return ""
+}
+
+// UserRole gets the role of the current logged-in user from the context
+func UserRole(ctx context.Context) string {
+	userRole := ctx.Value(KeyUserRole)
+	if userRole != nil {
+		v := userRole.(string)
+		return v
+	}
+	return ""
+}
+
+// UserSessionID gets the id of the session of the current logged-in user from the context
+func UserSessionID(ctx context.Context) int {
+	userSessionID := ctx.Value(KeyUserSessionID)
+	if userSessionID != nil {
+		v := userSessionID.(int)
+		return v
+	}
+	return 0
+}
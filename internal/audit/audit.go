@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// Log represents a recorded change of an entity
+type Log struct {
+	ID        int             `json:"id" db:"id"`
+	Entity    string          `json:"entity" db:"entity"`
+	EntityID  int             `json:"entityId" db:"entity_id"`
+	Action    string          `json:"action" db:"action"`
+	Actor     string          `json:"actor" db:"actor"`
+	RequestID *string         `json:"requestId" db:"request_id"`
+	Changes   json.RawMessage `json:"changes" db:"changes"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+//FindAllLogsParams params for find all
+type FindAllLogsParams struct {
+	Page          int        `json:"page"`
+	Limit         int        `json:"limit"`
+	Entity        string     `json:"entity"`
+	EntityID      int        `json:"entityId"`
+	Action        string     `json:"action"`
+	Actor         string     `json:"actor"`
+	RequestID     string     `json:"requestId"`
+	From          *time.Time `json:"from"`
+	To            *time.Time `json:"to"`
+	Cursor        string     `json:"cursor"`
+	EstimateCount bool       `json:"estimateCount"`
+}
+
+// Storage represents the audit log storage interface
+type Storage interface {
+	FindAll(ctx context.Context, params *FindAllLogsParams) ([]*Log, *types.Error)
+	FindAllByCursor(ctx context.Context, params *FindAllLogsParams) ([]*Log, *data.CursorPage, *types.Error)
+	Count(ctx context.Context, params *FindAllLogsParams) (int, *types.Error)
+}
+
+// ServiceInterface represents the audit log service interface
+type ServiceInterface interface {
+	ListLogs(ctx context.Context, params *FindAllLogsParams) ([]*Log, int, *types.Error)
+	ListLogsByCursor(ctx context.Context, params *FindAllLogsParams) ([]*Log, *data.CursorPage, *types.Error)
+}
+
+// Service is the domain logic implementation of audit log Service interface.
+// The logs are written by the audited data storages, the service only reads them.
+type Service struct {
+	logStorage Storage
+}
+
+// ListLogs is listing audit logs
+func (s *Service) ListLogs(ctx context.Context, params *FindAllLogsParams) ([]*Log, int, *types.Error) {
+	logs, err := s.logStorage.FindAll(ctx, params)
+	if err != nil {
+		err.Path = ".AuditService->ListLogs()" + err.Path
+		return nil, 0, err
+	}
+	count, err := s.logStorage.Count(ctx, params)
+	if err != nil {
+		err.Path = ".AuditService->ListLogs()" + err.Path
+		return nil, 0, err
+	}
+
+	return logs, count, nil
+}
+
+// ListLogsByCursor is listing audit logs paginated by cursor
+func (s *Service) ListLogsByCursor(ctx context.Context, params *FindAllLogsParams) ([]*Log, *data.CursorPage, *types.Error) {
+	logs, cursors, err := s.logStorage.FindAllByCursor(ctx, params)
+	if err != nil {
+		err.Path = ".AuditService->ListLogsByCursor()" + err.Path
+		return nil, nil, err
+	}
+
+	return logs, cursors, nil
+}
+
+// NewService creates a new audit log AppService
+func NewService(
+	logStorage Storage,
+) *Service {
+	return &Service{
+		logStorage: logStorage,
+	}
+}
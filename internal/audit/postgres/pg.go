@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/riskiramdan/evermos/internal/audit"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// PostgresStorage implements the audit log storage service interface
+type PostgresStorage struct {
+	Storage data.GenericStorage
+}
+
+// FindAll find all audit logs
+func (s *PostgresStorage) FindAll(ctx context.Context, params *audit.FindAllLogsParams) ([]*audit.Log, *types.Error) {
+
+	logs := []*audit.Log{}
+	where, args := logFilter(params)
+
+	if params.Page != 0 && params.Limit != 0 {
+		where = fmt.Sprintf(`%s ORDER BY "id" DESC LIMIT :limit OFFSET :offset`, where)
+	} else {
+		where = fmt.Sprintf(`%s ORDER BY "id" DESC`, where)
+	}
+
+	err := s.Storage.Where(ctx, &logs, where, args)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".AuditPostgresStorage->FindAll()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return logs, nil
+}
+
+// FindAllByCursor find all audit logs paginated by cursor
+func (s *PostgresStorage) FindAllByCursor(ctx context.Context, params *audit.FindAllLogsParams) ([]*audit.Log, *data.CursorPage, *types.Error) {
+
+	logs := []*audit.Log{}
+	where, args := logFilter(params)
+
+	cursors, err := s.Storage.WhereKeyset(ctx, &logs, where, args, &data.KeysetParams{
+		SortField: "created_at",
+		Cursor:    params.Cursor,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		return nil, nil, &types.Error{
+			Path:    ".AuditPostgresStorage->FindAllByCursor()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return logs, cursors, nil
+}
+
+// Count count audit logs
+func (s *PostgresStorage) Count(ctx context.Context, params *audit.FindAllLogsParams) (int, *types.Error) {
+	where, args := logFilter(params)
+
+	var count int
+	var err error
+	if params.EstimateCount {
+		count, err = s.Storage.CountEstimate(ctx, where, args)
+	} else {
+		count, err = s.Storage.Count(ctx, where, args)
+	}
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".AuditPostgresStorage->Count()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return count, nil
+}
+
+func logFilter(params *audit.FindAllLogsParams) (string, map[string]interface{}) {
+	where := `"deleted_at" IS NULL`
+
+	if params.Entity != "" {
+		where += ` AND "entity" = :entity`
+	}
+	if params.EntityID != 0 {
+		where += ` AND "entity_id" = :entityId`
+	}
+	if params.Action != "" {
+		where += ` AND "action" = :action`
+	}
+	if params.Actor != "" {
+		where += ` AND "actor" = :actor`
+	}
+	if params.RequestID != "" {
+		where += ` AND "request_id" = :requestId`
+	}
+	if params.From != nil {
+		where += ` AND "created_at" >= :from`
+	}
+	if params.To != nil {
+		where += ` AND "created_at" < :to`
+	}
+
+	return where, map[string]interface{}{
+		"entity":    params.Entity,
+		"entityId":  params.EntityID,
+		"action":    params.Action,
+		"actor":     params.Actor,
+		"requestId": params.RequestID,
+		"from":      params.From,
+		"to":        params.To,
+		"limit":     params.Limit,
+		"offset":    ((params.Page - 1) * params.Limit),
+	}
+}
+
+// NewPostgresStorage creates new audit log repository service
+func NewPostgresStorage(
+	storage data.GenericStorage,
+) *PostgresStorage {
+	return &PostgresStorage{
+		Storage: storage,
+	}
+}
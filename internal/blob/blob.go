@@ -0,0 +1,22 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Errors
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store represents the blob store interface
+// where uploaded files (e.g. product images) are kept
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
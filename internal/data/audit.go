@@ -0,0 +1,164 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+)
+
+// Audit actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// auditRedacted replaces the values of the columns tagged `audit:"-"`, e.g. password hashes
+const auditRedacted = "[redacted]"
+
+// auditIgnoredColumns are left out of the changes, the audit row itself records when and who
+var auditIgnoredColumns = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"created_by": true,
+	"updated_at": true,
+	"updated_by": true,
+}
+
+// AuditChange represents the value of a column before and after the change
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// WithAudit makes the storage record every change of its rows in the audit log.
+// The audit row is written with the same transaction as the change.
+func (r *PostgresStorage) WithAudit() *PostgresStorage {
+	r.audited = true
+	return r
+}
+
+// auditSnapshot loads the current row when the storage is audited
+func (r *PostgresStorage) auditSnapshot(ctx context.Context, id interface{}) (interface{}, error) {
+	if !r.audited {
+		return nil, nil
+	}
+
+	elem := reflect.New(r.elemType).Interface()
+	err := r.FindByID(ctx, elem, id)
+	if err != nil {
+		return nil, err
+	}
+	return elem, nil
+}
+
+// audit records the change of the row, before or after is nil when the row did not exist.
+// Updates which change nothing are not recorded.
+func (r *PostgresStorage) audit(ctx context.Context, action string, id interface{}, before interface{}, after interface{}) error {
+	if !r.audited {
+		return nil
+	}
+
+	changes := r.auditChanges(before, after)
+	if action == AuditUpdate && len(changes) == 0 {
+		return nil
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	statement, err := db.PrepareNamed(`
+	INSERT INTO "audit_log"("entity","entity_id","action","actor","request_id","changes","created_at","created_by","updated_at","updated_by")
+	VALUES (:entity,:entityId,:action,:actor,:requestId,:changes,:now,:actor,:now,:actor)`)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	var requestID *string
+	if v := appcontext.RequestID(ctx); v != "" {
+		requestID = &v
+	}
+
+	_, err = statement.Exec(map[string]interface{}{
+		"entity":    r.tableName,
+		"entityId":  id,
+		"action":    action,
+		"actor":     actor(ctx),
+		"requestId": requestID,
+		"changes":   changesJSON,
+		"now":       time.Now().UTC(),
+	})
+	return err
+}
+
+// auditChanges compares the columns of both elems and returns the changed ones by column name
+func (r *PostgresStorage) auditChanges(before interface{}, after interface{}) map[string]*AuditChange {
+	changes := map[string]*AuditChange{}
+
+	var bv, av reflect.Value
+	if before != nil {
+		bv = reflect.ValueOf(before).Elem()
+	}
+	if after != nil {
+		av = reflect.ValueOf(after).Elem()
+	}
+
+	for i := 0; i < r.elemType.NumField(); i++ {
+		field := r.elemType.Field(i)
+		dbTag := field.Tag.Get("db")
+		if emptyTag(dbTag) || auditIgnoredColumns[dbTag] {
+			continue
+		}
+
+		var from, to interface{}
+		if bv.IsValid() {
+			from = auditValue(bv.Field(i))
+		}
+		if av.IsValid() {
+			to = auditValue(av.Field(i))
+		}
+		if sameAuditValue(from, to) {
+			continue
+		}
+
+		if field.Tag.Get("audit") == "-" {
+			from, to = auditRedacted, auditRedacted
+		}
+		changes[dbTag] = &AuditChange{From: from, To: to}
+	}
+
+	return changes
+}
+
+func auditValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+func sameAuditValue(a interface{}, b interface{}) bool {
+	// times read from the database differ in location and monotonic clock
+	at, aok := a.(time.Time)
+	bt, bok := b.(time.Time)
+	if aok && bok {
+		return at.Equal(bt)
+	}
+	return reflect.DeepEqual(a, b)
+}
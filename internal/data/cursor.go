@@ -0,0 +1,87 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrInvalidCursor declare specific error for malformed cursor token
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+
+// Cursor directions
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+// Cursor is the decoded form of the opaque pagination token.
+// It points at the boundary row of a page by its sort key value and id
+type Cursor struct {
+	Value     string `json:"v"`
+	ID        int    `json:"i"`
+	Direction string `json:"d"`
+}
+
+// KeysetParams represents the params of a keyset (cursor) paginated query
+type KeysetParams struct {
+	SortField string
+	Cursor    string
+	Limit     int
+}
+
+// CursorPage holds the cursor tokens of the pages next to the current one
+type CursorPage struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// EncodeCursor encodes the cursor into an opaque token
+func EncodeCursor(c *Cursor) string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes the opaque token back into a cursor.
+// An empty token means the first page
+func DecodeCursor(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Direction != CursorNext && c.Direction != CursorPrev {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// cursorValue formats the field value of the element as cursor sort value
+func cursorValue(v reflect.Value) string {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch val := v.Interface().(type) {
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(val)
+	}
+}
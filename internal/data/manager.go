@@ -12,6 +12,18 @@ type Manager struct {
 	db *sqlx.DB
 }
 
+// hooks are the functions run once the transaction ended, for the changes outside of the database
+type hooks struct {
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+func runHooks(fs []func()) {
+	for _, f := range fs {
+		f()
+	}
+}
+
 // RunInTransaction runs the f with the transaction queryable inside the context
 func (m *Manager) RunInTransaction(ctx context.Context, f func(tctx context.Context) error) error {
 	tx, err := m.db.Beginx()
@@ -20,21 +32,44 @@ func (m *Manager) RunInTransaction(ctx context.Context, f func(tctx context.Cont
 		return fmt.Errorf("error when creating transction: %v", err)
 	}
 
-	ctx = NewContext(ctx, tx)
+	h := &hooks{}
+	ctx = context.WithValue(NewContext(ctx, tx), hooksKey, h)
 	err = f(ctx)
 	if err != nil {
 		tx.Rollback()
+		runHooks(h.afterRollback)
 		return err
 	}
 
 	err = tx.Commit()
 	if err != nil {
+		runHooks(h.afterRollback)
 		return fmt.Errorf("error when committing transaction: %v", err)
 	}
 
+	runHooks(h.afterCommit)
 	return nil
 }
 
+// AfterCommit runs the f once the transaction of the context is committed, right away outside of a transaction
+func AfterCommit(ctx context.Context, f func()) {
+	h, ok := ctx.Value(hooksKey).(*hooks)
+	if !ok {
+		f()
+		return
+	}
+	h.afterCommit = append(h.afterCommit, f)
+}
+
+// AfterRollback runs the f once the transaction of the context is rolled back, never outside of a transaction
+func AfterRollback(ctx context.Context, f func()) {
+	h, ok := ctx.Value(hooksKey).(*hooks)
+	if !ok {
+		return
+	}
+	h.afterRollback = append(h.afterRollback, f)
+}
+
 // NewManager creates a new manager
 func NewManager(db *sqlx.DB) *Manager {
 	return &Manager{
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// txDriver is a database driver whose transactions do nothing, its commits fail when commitErr is set
+type txDriver struct{}
+
+type txConn struct{}
+
+var commitErr error
+
+func (txDriver) Open(name string) (driver.Conn, error) { return txConn{}, nil }
+
+func (txConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("queries are not supported")
+}
+func (txConn) Close() error              { return nil }
+func (txConn) Begin() (driver.Tx, error) { return txConn{}, nil }
+func (txConn) Commit() error             { return commitErr }
+func (txConn) Rollback() error           { return nil }
+
+func init() {
+	sql.Register("datatest", txDriver{})
+}
+
+func TestRunInTransactionHooks(t *testing.T) {
+	db, err := sqlx.Open("datatest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(db)
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name          string
+		err           error
+		commitErr     error
+		wantCommitted bool
+	}{
+		{"committed", nil, nil, true},
+		{"rolled back", errFailed, nil, false},
+		{"commit failed", nil, errFailed, false},
+	}
+	for _, tt := range tests {
+		commitErr = tt.commitErr
+		committed, rolledBack := false, false
+		errTransaction := manager.RunInTransaction(context.Background(), func(ctx context.Context) error {
+			AfterCommit(ctx, func() { committed = true })
+			AfterRollback(ctx, func() { rolledBack = true })
+			if committed || rolledBack {
+				t.Errorf("%s: hooks ran before the transaction ended", tt.name)
+			}
+			return tt.err
+		})
+		if (errTransaction == nil) != tt.wantCommitted {
+			t.Errorf("%s: RunInTransaction() error = %v", tt.name, errTransaction)
+		}
+		if committed != tt.wantCommitted || rolledBack == tt.wantCommitted {
+			t.Errorf("%s: committed hook ran = %v, rolled back hook ran = %v", tt.name, committed, rolledBack)
+		}
+	}
+	commitErr = nil
+
+	// outside of a transaction the changes are committed already
+	committed, rolledBack := false, false
+	AfterCommit(context.Background(), func() { committed = true })
+	AfterRollback(context.Background(), func() { rolledBack = true })
+	if !committed || rolledBack {
+		t.Errorf("outside of a transaction: committed hook ran = %v, rolled back hook ran = %v", committed, rolledBack)
+	}
+}
@@ -10,7 +10,8 @@ import (
 type key int
 
 const (
-	txKey key = 0
+	txKey key = iota
+	hooksKey
 )
 
 // Queryer represents the database commands interface
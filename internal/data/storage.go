@@ -11,15 +11,24 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/riskiramdan/evermos/internal/appcontext"
 )
 
 //ErrNotEnough declare specific error for Not Enough
 //ErrExisted declare specific error for data already exist
 var (
-	ErrNotFound     = fmt.Errorf("data is not found")
-	ErrAlreadyExist = fmt.Errorf("data already exists")
+	ErrNotFound        = fmt.Errorf("data is not found")
+	ErrAlreadyExist    = fmt.Errorf("data already exists")
+	ErrStillReferenced = fmt.Errorf("data is still referenced by other data")
 )
 
+// SystemActor is recorded as the actor of changes made outside of a user request
+const SystemActor = "system"
+
+// foreignKeyViolation is the postgres error code of a foreign key violation
+const foreignKeyViolation = "23503"
+
 // GenericStorage represents the generic Storage
 // for the domain models that matches with its database models
 type GenericStorage interface {
@@ -28,11 +37,17 @@ type GenericStorage interface {
 	SelectWithQuery(ctx context.Context, elem interface{}, query string, args map[string]interface{}) error
 	FindByID(ctx context.Context, elem interface{}, id interface{}) error
 	FindAll(ctx context.Context, elems interface{}, page int, limit int) error
+	WhereKeyset(ctx context.Context, elems interface{}, where string, arg map[string]interface{}, params *KeysetParams) (*CursorPage, error)
+	Count(ctx context.Context, where string, arg map[string]interface{}) (int, error)
+	CountEstimate(ctx context.Context, where string, arg map[string]interface{}) (int, error)
 	Insert(ctx context.Context, elem interface{}) error
 	// InsertMany(ctx context.Context, elem interface{}) error
 	Update(ctx context.Context, elem interface{}) error
 	Delete(ctx context.Context, id interface{}) error
+	Restore(ctx context.Context, id interface{}) error
 	DeleteHard(ctx context.Context, id interface{}) error
+	DeleteHardWhere(ctx context.Context, where string, arg map[string]interface{}) (int, error)
+	UpdateWhere(ctx context.Context, elems interface{}, set string, where string, arg map[string]interface{}) error
 }
 
 // PostgresStorage is the postgres implementation of generic Storage
@@ -44,6 +59,7 @@ type PostgresStorage struct {
 	insertFields    string
 	insertParams    string
 	updateSetFields string
+	audited         bool
 }
 
 // Single queries an element according to the query & argument provided
@@ -161,10 +177,178 @@ func (r *PostgresStorage) FindAll(ctx context.Context, elems interface{}, page i
 	return nil
 }
 
+// Count counts the elements according to the query & argument provided
+func (r *PostgresStorage) Count(ctx context.Context, where string, arg map[string]interface{}) (int, error) {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE %s`, r.tableName, where)
+	query, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	query = db.Rebind(query)
+
+	var count int
+	err = db.Get(&count, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountEstimate estimates the count of the elements according to the query & argument provided.
+// It takes the row estimate of the query planner instead of scanning the table,
+// so it is cheap on very large tables but only as accurate as the table statistics.
+func (r *PostgresStorage) CountEstimate(ctx context.Context, where string, arg map[string]interface{}) (int, error) {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	query := fmt.Sprintf(`EXPLAIN (FORMAT JSON) SELECT 1 FROM "%s" WHERE %s`, r.tableName, where)
+	query, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	query = db.Rebind(query)
+
+	var explain string
+	err = db.Get(&explain, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	plans := []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}{}
+	err = json.Unmarshal([]byte(explain), &plans)
+	if err != nil {
+		return 0, err
+	}
+	if len(plans) < 1 {
+		return 0, nil
+	}
+
+	return int(plans[0].Plan.Rows), nil
+}
+
+// WhereKeyset queries the elements according to the query & argument provided,
+// paginated by cursor instead of offset.
+// The elements are ordered by the sort field and then "id", both descending,
+// and the returned page holds the cursor tokens of the neighbouring pages.
+func (r *PostgresStorage) WhereKeyset(ctx context.Context, elems interface{}, where string, arg map[string]interface{}, params *KeysetParams) (*CursorPage, error) {
+	sortField := params.SortField
+	if sortField == "" {
+		sortField = "id"
+	}
+	sortIndex, ok := r.fieldIndex(sortField)
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %s", sortField)
+	}
+	idIndex, _ := r.fieldIndex("id")
+
+	cursor, err := DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	keysetArg := map[string]interface{}{}
+	for k, v := range arg {
+		keysetArg[k] = v
+	}
+	keysetArg["keysetLimit"] = params.Limit + 1
+
+	backward := cursor != nil && cursor.Direction == CursorPrev
+	order := "DESC"
+	if cursor != nil {
+		operator := "<"
+		if backward {
+			operator = ">"
+			order = "ASC"
+		}
+		where = fmt.Sprintf(`%s AND ("%s", "id") %s (:keysetValue, :keysetId)`, where, sortField, operator)
+		keysetArg["keysetValue"] = cursor.Value
+		keysetArg["keysetId"] = cursor.ID
+	}
+	where = fmt.Sprintf(`%s ORDER BY "%s" %s, "id" %s LIMIT :keysetLimit`, where, sortField, order, order)
+
+	err = r.Where(ctx, elems, where, keysetArg)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(elems).Elem()
+	hasMore := v.Len() > params.Limit
+	if hasMore {
+		v.Set(v.Slice(0, params.Limit))
+	}
+	if backward {
+		swap := reflect.Swapper(v.Interface())
+		for i, j := 0, v.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	page := &CursorPage{}
+	if v.Len() == 0 {
+		return page, nil
+	}
+
+	if hasMore || backward {
+		last := reflect.Indirect(v.Index(v.Len() - 1))
+		page.Next = EncodeCursor(&Cursor{
+			Value:     cursorValue(last.Field(sortIndex)),
+			ID:        int(last.Field(idIndex).Int()),
+			Direction: CursorNext,
+		})
+	}
+	if backward && hasMore || !backward && cursor != nil {
+		first := reflect.Indirect(v.Index(0))
+		page.Prev = EncodeCursor(&Cursor{
+			Value:     cursorValue(first.Field(sortIndex)),
+			ID:        int(first.Field(idIndex).Int()),
+			Direction: CursorPrev,
+		})
+	}
+
+	return page, nil
+}
+
+// fieldIndex finds the index of the element field tagged with the db column
+func (r *PostgresStorage) fieldIndex(dbTag string) (int, bool) {
+	for i := 0; i < r.elemType.NumField(); i++ {
+		if r.elemType.Field(i).Tag.Get("db") == dbTag {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 // Insert inserts a new element into the database.
 // It assumes the primary key of the table is "id" with serial type.
 // It will set the "owner" field of the element with the current account in the context if exists.
 // It will set the "createdAt" and "updatedAt" fields with current time.
+// It will set the "createdBy" and "updatedBy" fields with the current user or SystemActor.
 // If immutable set true, it won't insert the updatedAt
 func (r *PostgresStorage) Insert(ctx context.Context, elem interface{}) error {
 	db := r.db
@@ -184,12 +368,13 @@ func (r *PostgresStorage) Insert(ctx context.Context, elem interface{}) error {
 	defer statement.Close()
 
 	dbArgs := r.insertArgs(elem, 0)
+	setActorArgs(ctx, dbArgs, "created_by", "updated_by")
 	err = statement.Get(elem, dbArgs)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.audit(ctx, AuditCreate, r.findID(elem), nil, elem)
 }
 
 // // InsertMany is function for creating many datas into specific table in database.
@@ -296,7 +481,7 @@ func (r *PostgresStorage) insertArgs(elem interface{}, index int) map[string]int
 
 	for i := 0; i < v.NumField(); i++ {
 		dbTag := r.elemType.Field(i).Tag.Get("db")
-		if !idTag(dbTag) && !emptyTag(dbTag) {
+		if writableTag(dbTag) {
 			var typeMapString map[string]interface{}
 			var val interface{}
 			if v.Field(i).Type() == reflect.TypeOf(typeMapString) {
@@ -332,7 +517,8 @@ func renamingKey(m map[string]interface{}, add string) map[string]interface{} {
 }
 
 // Update updates the element in the database.
-// It will update the "updatedAt" field.
+// It will update the "updatedAt" field and set "updatedBy" with the current user or SystemActor.
+// The "createdBy" field is never updated.
 func (r *PostgresStorage) Update(ctx context.Context, elem interface{}) error {
 	db := r.db
 	tx, ok := TxFromContext(ctx)
@@ -358,13 +544,14 @@ func (r *PostgresStorage) Update(ctx context.Context, elem interface{}) error {
 	defer statement.Close()
 
 	updateArgs := r.updateArgs(existingElem, elem)
+	setActorArgs(ctx, updateArgs, "updated_by")
 	updateArgs["id"] = id
 	err = statement.Get(elem, updateArgs)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.audit(ctx, AuditUpdate, id, existingElem, elem)
 }
 
 // it assumes the id column named "id"
@@ -386,7 +573,7 @@ func (r *PostgresStorage) updateArgs(existingElem interface{}, elem interface{})
 	ev := reflect.ValueOf(existingElem).Elem()
 	for i := 0; i < ev.NumField(); i++ {
 		dbTag := r.elemType.Field(i).Tag.Get("db")
-		if !idTag(dbTag) && !emptyTag(dbTag) {
+		if writableTag(dbTag) && !createdByTag(dbTag) {
 			var typeMapString map[string]interface{}
 			var val interface{}
 
@@ -408,14 +595,20 @@ func (r *PostgresStorage) updateArgs(existingElem interface{}, elem interface{})
 
 // Delete deletes the elem from database.
 // Delete not really deletes the elem from the db, but it will set the
-// "deletedAt" column to current time.
+// "deletedAt" column to current time and "deletedBy" to the current user.
 func (r *PostgresStorage) Delete(ctx context.Context, id interface{}) error {
 	db := r.db
 	tx, ok := TxFromContext(ctx)
 	if ok {
 		db = tx
 	}
-	statement, err := db.PrepareNamed(fmt.Sprintf(`UPDATE "%s" SET "deleted_at" = :deletedAt WHERE "id" = :id RETURNING %s
+
+	before, err := r.auditSnapshot(ctx, id)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	statement, err := db.PrepareNamed(fmt.Sprintf(`UPDATE "%s" SET "deleted_at" = :deletedAt, "deleted_by" = :deletedBy WHERE "id" = :id RETURNING %s
 	`, r.tableName, r.selectFields))
 	if err != nil {
 		return err
@@ -425,16 +618,72 @@ func (r *PostgresStorage) Delete(ctx context.Context, id interface{}) error {
 	deleteArgs := map[string]interface{}{
 		"id":        id,
 		"deletedAt": time.Now().UTC(),
+		"deletedBy": actor(ctx),
 	}
 
 	_, err = statement.Exec(deleteArgs)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	if before == nil {
+		return nil
+	}
+	after, err := r.auditSnapshot(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.audit(ctx, AuditDelete, id, before, after)
+}
+
+// Restore restores the soft deleted elem by clearing its "deletedAt" column.
+// It returns ErrNotFound when there is no deleted elem with the id.
+func (r *PostgresStorage) Restore(ctx context.Context, id interface{}) error {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	before, err := r.auditSnapshot(ctx, id)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	statement, err := db.PrepareNamed(fmt.Sprintf(`UPDATE "%s" SET "deleted_at" = NULL, "deleted_by" = NULL, "updated_at" = :updatedAt, "updated_by" = :updatedBy
+		WHERE "id" = :id AND "deleted_at" IS NOT NULL
+	`, r.tableName))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(map[string]interface{}{
+		"id":        id,
+		"updatedAt": time.Now().UTC(),
+		"updatedBy": actor(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	after, err := r.auditSnapshot(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.audit(ctx, AuditRestore, id, before, after)
 }
 
 // DeleteHard hard delete the elem from database.
+// It returns ErrStillReferenced when other rows still reference the elem.
 func (r *PostgresStorage) DeleteHard(ctx context.Context, id interface{}) error {
 	db := r.db
 	tx, ok := TxFromContext(ctx)
@@ -442,6 +691,11 @@ func (r *PostgresStorage) DeleteHard(ctx context.Context, id interface{}) error
 		db = tx
 	}
 
+	before, err := r.auditSnapshot(ctx, id)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
 	statement, err := db.PrepareNamed(fmt.Sprintf(`
 		DELETE FROM "%s" WHERE "id" = :id
 	`, r.tableName))
@@ -456,9 +710,99 @@ func (r *PostgresStorage) DeleteHard(ctx context.Context, id interface{}) error
 
 	_, err = statement.Exec(deleteArgs)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+			return ErrStillReferenced
+		}
 		return err
 	}
-	return nil
+
+	if before == nil {
+		return nil
+	}
+	return r.audit(ctx, AuditPurge, id, before, nil)
+}
+
+// DeleteHardWhere hard deletes the elems matching the query and returns how many were deleted.
+// No audit entry is written, it is meant for the tables which are not audited such as expired tokens.
+func (r *PostgresStorage) DeleteHardWhere(ctx context.Context, where string, arg map[string]interface{}) (int, error) {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	statement, err := db.PrepareNamed(fmt.Sprintf(`DELETE FROM "%s" WHERE %s`, r.tableName, where))
+	if err != nil {
+		return 0, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(arg)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+// UpdateWhere updates the columns of the set clause on the elems matching the query in a single statement
+// and returns the updated elems, so a condition checked by the query can not change before the update.
+// The "updatedAt" and "updatedBy" columns are set as well. No audit entry is written.
+func (r *PostgresStorage) UpdateWhere(ctx context.Context, elems interface{}, set string, where string, arg map[string]interface{}) error {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	updateArg := map[string]interface{}{}
+	for k, v := range arg {
+		updateArg[k] = v
+	}
+	updateArg["updateWhereAt"] = time.Now().UTC()
+	updateArg["updateWhereBy"] = actor(ctx)
+
+	query := fmt.Sprintf(`UPDATE "%s" SET %s, "updated_at" = :updateWhereAt, "updated_by" = :updateWhereBy WHERE %s RETURNING %s`,
+		r.tableName, set, where, r.selectFields)
+	query, args, err := sqlx.Named(query, updateArg)
+	if err != nil {
+		return err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+
+	query = db.Rebind(query)
+
+	return db.Select(elems, query, args...)
+}
+
+// setActorArgs sets the actor columns of the statement args,
+// columns the elem does not have are left to their database default
+func setActorArgs(ctx context.Context, args map[string]interface{}, columns ...string) {
+	for _, column := range columns {
+		if _, ok := args[column]; ok {
+			args[column] = actor(ctx)
+		}
+	}
+}
+
+// actor returns who is doing the change, the current user id, the api key client or SystemActor
+func actor(ctx context.Context) string {
+	if clientID := appcontext.ClientID(ctx); clientID != nil {
+		return "client:" + strconv.Itoa(*clientID)
+	}
+	userID := appcontext.UserID(ctx)
+	if userID == 0 {
+		return SystemActor
+	}
+	return strconv.Itoa(userID)
 }
 
 // NewPostgresStorage creates a new generic postgres Storage
@@ -492,7 +836,7 @@ func insertFields(elemType reflect.Type) string {
 	for i := 0; i < elemType.NumField(); i++ {
 		field := elemType.Field(i)
 		dbTag := field.Tag.Get("db")
-		if !idTag(dbTag) && !emptyTag(dbTag) {
+		if writableTag(dbTag) {
 			dbFields = append(dbFields, fmt.Sprintf("\"%s\"", dbTag))
 		}
 	}
@@ -504,7 +848,7 @@ func insertParams(elemType reflect.Type, index int) string {
 	for i := 0; i < elemType.NumField(); i++ {
 		field := elemType.Field(i)
 		dbTag := field.Tag.Get("db")
-		if !idTag(dbTag) && !emptyTag(dbTag) {
+		if writableTag(dbTag) {
 			dbParams = append(dbParams, fmt.Sprintf(":%s", dbTag))
 		}
 	}
@@ -524,7 +868,7 @@ func updateSetFields(elemType reflect.Type) string {
 	for i := 0; i < elemType.NumField(); i++ {
 		field := elemType.Field(i)
 		dbTag := field.Tag.Get("db")
-		if !idTag(dbTag) && !emptyTag(dbTag) {
+		if writableTag(dbTag) && !createdByTag(dbTag) {
 			setFields = append(setFields, fmt.Sprintf("\"%s\" = :%s", dbTag, dbTag))
 		}
 	}
@@ -535,6 +879,19 @@ func idTag(dbTag string) bool {
 	return dbTag == "id"
 }
 
+// deleted columns are only written by Delete and Restore
+func deletedTag(dbTag string) bool {
+	return dbTag == "deleted_at" || dbTag == "deleted_by"
+}
+
+func createdByTag(dbTag string) bool {
+	return dbTag == "created_by"
+}
+
+func writableTag(dbTag string) bool {
+	return !idTag(dbTag) && !emptyTag(dbTag) && !deletedTag(dbTag)
+}
+
 func emptyTag(dbTag string) bool {
 	emptyTags := []string{"", "-"}
 	for _, t := range emptyTags {
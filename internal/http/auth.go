@@ -2,18 +2,23 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/riskiramdan/evermos/internal/apikey"
 	"github.com/riskiramdan/evermos/internal/appcontext"
 	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
 	"github.com/riskiramdan/evermos/internal/http/response"
 	"github.com/riskiramdan/evermos/internal/types"
 	"github.com/riskiramdan/evermos/internal/user"
 )
 
+// errCSRFInvalid is returned when a change authenticated by the session cookie lacks the csrf token of the session
+var errCSRFInvalid = errors.New("missing or invalid X-CSRF-Token header")
+
 func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
@@ -21,6 +26,17 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 
 			ctx := r.Context()
 			token = getBearerToken(r)
+			if token == "" && getXAccessToken(r) != "" {
+				hs.apiKeyOnly(w, r, next, getXAccessToken(r))
+				return
+			}
+			// the browser clients are authenticated by the session cookie, which the browser also sends
+			// along with the requests forged by other sites, so their changes need the csrf token
+			fromCookie := false
+			if token == "" && hs.accessTokens == nil {
+				token = cookie.SessionToken(r)
+				fromCookie = token != ""
+			}
 			if token == "" {
 				response.Error(w, "Unauthorized", http.StatusUnauthorized, types.Error{
 					Path:    ".Server->authorizeOnly()",
@@ -31,14 +47,41 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 				return
 			}
 
-			if token == "siplandingpage" {
+			if hs.accessTokens != nil {
+				claims, errVerify := hs.accessTokens.Verify(token)
+				if errVerify != nil {
+					response.Error(w, "Unauthorized", http.StatusUnauthorized, types.Error{
+						Path:    ".Server->authorizeOnly()",
+						Message: errVerify.Error(),
+						Error:   errVerify,
+						Type:    "validation-error",
+					})
+					return
+				}
+				ctx = context.WithValue(ctx, appcontext.KeyUserID, claims.UserID())
+				ctx = context.WithValue(ctx, appcontext.KeyUserRole, claims.Role)
+				ctx = context.WithValue(ctx, appcontext.KeyUserSessionID, claims.SessionID)
+
+				if claims.ExpiresAt != nil {
+					response.SessionExpires(w, claims.ExpiresAt.Time)
+				}
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
-			singleUser, err := userService.GetByToken(ctx, token)
+			if fromCookie && !cookie.SafeMethod(r.Method) && !cookie.ValidCSRF(r, token) {
+				response.Error(w, "Forbidden", http.StatusForbidden, types.Error{
+					Path:    ".Server->authorizeOnly()",
+					Message: errCSRFInvalid.Error(),
+					Error:   errCSRFInvalid,
+					Type:    "validation-error",
+				})
+				return
+			}
+
+			session, err := userService.GetSession(ctx, token)
 			if err != nil {
-				if err.Error != data.ErrNotFound {
+				if err.Error != data.ErrNotFound && err.Error != user.ErrSessionExpired {
 					response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
 					return
 				}
@@ -50,7 +93,12 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 				})
 				return
 			}
-			if singleUser.TokenExpiredAt.Before(time.Now()) {
+			singleUser, err := userService.GetUser(ctx, session.UserID)
+			if err != nil {
+				if err.Error != data.ErrNotFound {
+					response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+					return
+				}
 				response.Error(w, "Unauthorized", http.StatusUnauthorized, types.Error{
 					Path:    ".Server->authorizeOnly()",
 					Message: "",
@@ -59,8 +107,21 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 				})
 				return
 			}
+			expiredAt := session.ExpiredAt
+			err = userService.TouchSession(ctx, session)
+			if err != nil {
+				response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+				return
+			}
+			// the cookies of a renewed session would otherwise expire with the session they were set for
+			if fromCookie && session.ExpiredAt.After(expiredAt) {
+				hs.cookies.SetSession(w, token, session.ExpiredAt)
+			}
+			response.SessionExpires(w, session.ExpiredAt)
 			ctx = context.WithValue(ctx, appcontext.KeyUserID, singleUser.ID)
-			ctx = context.WithValue(ctx, appcontext.KeySessionID, *singleUser.Token)
+			ctx = context.WithValue(ctx, appcontext.KeyUserRole, singleUser.Role)
+			ctx = context.WithValue(ctx, appcontext.KeySessionID, token)
+			ctx = context.WithValue(ctx, appcontext.KeyUserSessionID, session.ID)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
@@ -69,6 +130,31 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 	}
 }
 
+// apiKeyOnly authenticates a service-to-service client by its api key,
+// the client and the permissions of its key are put into the context instead of a user
+func (hs *Server) apiKeyOnly(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	ctx := r.Context()
+
+	apiKey, err := hs.apiKeyService.Authenticate(ctx, key)
+	if err != nil {
+		if err.Error != data.ErrNotFound && err.Error != apikey.ErrKeyExpired && err.Error != apikey.ErrKeyRevoked {
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+		response.Error(w, "Unauthorized", http.StatusUnauthorized, types.Error{
+			Path:    ".Server->apiKeyOnly()",
+			Message: "",
+			Error:   nil,
+			Type:    "",
+		})
+		return
+	}
+	ctx = context.WithValue(ctx, appcontext.KeyClientID, apiKey.ID)
+	ctx = context.WithValue(ctx, appcontext.KeyCurrentClientAccess, []string(apiKey.Permissions))
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func getBearerToken(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 	splitToken := strings.Split(token, "Bearer")
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/controller"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
+	"github.com/riskiramdan/evermos/internal/token"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+const sessionToken = "session-token"
+
+// stubUserService knows the session of the session token,
+// the methods the tests do not call are left to the nil interface
+type stubUserService struct {
+	user.ServiceInterface
+	loggedOut int
+}
+
+func (s *stubUserService) GetSession(ctx context.Context, token string) (*user.Session, *types.Error) {
+	if token != sessionToken {
+		return nil, &types.Error{
+			Path:    ".stubUserService->GetSession()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+	return &user.Session{ID: 3, UserID: 7, ExpiredAt: time.Now().Add(time.Hour)}, nil
+}
+
+func (s *stubUserService) GetUser(ctx context.Context, userID int) (*user.User, *types.Error) {
+	return &user.User{ID: userID, Role: user.RoleCustomer}, nil
+}
+
+func (s *stubUserService) TouchSession(ctx context.Context, session *user.Session) *types.Error {
+	return nil
+}
+
+func (s *stubUserService) Logout(ctx context.Context, sessionID int) *types.Error {
+	s.loggedOut = sessionID
+	return nil
+}
+
+func newAuthTestServer(userService *stubUserService, accessTokens *token.Manager) *Server {
+	cookies := cookie.NewPolicy(true, "lax", "")
+	return &Server{
+		userService:    userService,
+		userController: controller.NewUserController(userService, nil, nil, nil, cookies),
+		accessTokens:   accessTokens,
+		cookies:        cookies,
+	}
+}
+
+// authorized answers with the id of the authenticated user
+func authorized(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(strconv.Itoa(appcontext.UserID(r.Context()))))
+}
+
+func authRequest(method string, sessionCookie string, csrfToken string, bearer string) *http.Request {
+	r := httptest.NewRequest(method, "/v1/me", nil)
+	if sessionCookie != "" {
+		r.AddCookie(&http.Cookie{Name: cookie.SessionName, Value: sessionCookie})
+		r.AddCookie(&http.Cookie{Name: cookie.CSRFName, Value: cookie.CSRFToken(sessionCookie)})
+	}
+	if csrfToken != "" {
+		r.Header.Set(cookie.CSRFHeader, csrfToken)
+	}
+	if bearer != "" {
+		r.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return r
+}
+
+func TestAuthorizedOnlyCSRF(t *testing.T) {
+	hs := newAuthTestServer(&stubUserService{}, nil)
+	handler := hs.authorizedOnly(hs.userService)(http.HandlerFunc(authorized))
+
+	tests := []struct {
+		name       string
+		r          *http.Request
+		wantStatus int
+	}{
+		{"cookie post without csrf token", authRequest(http.MethodPost, sessionToken, "", ""), http.StatusForbidden},
+		{"cookie post with a wrong csrf token", authRequest(http.MethodPost, sessionToken, cookie.CSRFToken("other-session"), ""), http.StatusForbidden},
+		{"cookie post with the csrf token of the session", authRequest(http.MethodPost, sessionToken, cookie.CSRFToken(sessionToken), ""), http.StatusOK},
+		{"cookie delete without csrf token", authRequest(http.MethodDelete, sessionToken, "", ""), http.StatusForbidden},
+		{"cookie get without csrf token", authRequest(http.MethodGet, sessionToken, "", ""), http.StatusOK},
+		{"bearer post without csrf token", authRequest(http.MethodPost, "", "", sessionToken), http.StatusOK},
+		{"unknown cookie", authRequest(http.MethodGet, "unknown", "", ""), http.StatusUnauthorized},
+		{"no credentials", authRequest(http.MethodGet, "", "", ""), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, tt.r)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d, body %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+		}
+		if tt.wantStatus == http.StatusOK && w.Body.String() != "7" {
+			t.Errorf("%s: authenticated user = %q, want 7", tt.name, w.Body.String())
+		}
+	}
+}
+
+func TestAuthorizedOnlyJWTIgnoresCookie(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "hs.secret"), []byte("0123456789abcdef0123456789abcdef"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := token.LoadKeySet(dir, "hs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessTokens := token.NewManager(keys, "evermos", 15*time.Minute)
+	accessToken, _, err := accessTokens.Issue(7, user.RoleCustomer, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs := newAuthTestServer(&stubUserService{}, accessTokens)
+	handler := hs.authorizedOnly(hs.userService)(http.HandlerFunc(authorized))
+
+	tests := []struct {
+		name       string
+		r          *http.Request
+		wantStatus int
+	}{
+		{"session cookie", authRequest(http.MethodGet, sessionToken, "", ""), http.StatusUnauthorized},
+		{"session cookie with the csrf token", authRequest(http.MethodPost, sessionToken, cookie.CSRFToken(sessionToken), ""), http.StatusUnauthorized},
+		{"session token as bearer", authRequest(http.MethodGet, "", "", sessionToken), http.StatusUnauthorized},
+		{"access token", authRequest(http.MethodPost, "", "", accessToken), http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, tt.r)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d, body %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+		}
+	}
+}
+
+func TestLogoutClearsCookies(t *testing.T) {
+	userService := &stubUserService{}
+	hs := newAuthTestServer(userService, nil)
+	handler := hs.authorizedOnly(hs.userService)(http.HandlerFunc(hs.userController.Logout))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, authRequest(http.MethodPost, sessionToken, cookie.CSRFToken(sessionToken), ""))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusNoContent, w.Body.String())
+	}
+	if userService.loggedOut != 3 {
+		t.Errorf("logged out session %d, want 3", userService.loggedOut)
+	}
+
+	cleared := map[string]bool{}
+	for _, c := range w.Result().Cookies() {
+		if c.Value == "" && c.MaxAge < 0 {
+			cleared[c.Name] = true
+		}
+	}
+	for _, name := range []string{cookie.SessionName, cookie.CSRFName} {
+		if !cleared[name] {
+			t.Errorf("cookie %s not cleared, got %v", name, w.Header()["Set-Cookie"])
+		}
+	}
+}
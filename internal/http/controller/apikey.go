@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/apikey"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// APIKeyController represents the api key controller
+type APIKeyController struct {
+	apiKeyService apikey.ServiceInterface
+	dataManager   *data.Manager
+}
+
+// APIKeyList api key list
+type APIKeyList struct {
+	Data []*apikey.APIKey `json:"data"`
+}
+
+// ListAPIKey Function for listing api keys
+func (a *APIKeyController) ListAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeys, err := a.apiKeyService.ListAPIKeys(r.Context())
+	if err != nil {
+		err.Path = ".APIKeyController->ListAPIKey()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, APIKeyList{
+		Data: apiKeys,
+	})
+}
+
+// CreateAPIKey Function for creating an api key, the key is only returned in this response
+func (a *APIKeyController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params *apikey.CreateAPIKeyParams
+	if !decodeAndValidate(w, r, ".APIKeyController->CreateAPIKey()", &params) {
+		return
+	}
+
+	var created *apikey.CreateAPIKeyResponse
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		created, err = a.apiKeyService.CreateAPIKey(ctx, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".APIKeyController->CreateAPIKey()" + err.Path
+		switch errTransaction {
+		case apikey.ErrNameRequired, apikey.ErrPermissionInvalid, apikey.ErrExpiredAtInPast:
+			response.Error(w, err.Message, http.StatusUnprocessableEntity, *err)
+		default:
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, created)
+}
+
+// RevokeAPIKey Function for revoking an api key
+func (a *APIKeyController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	apiKeyID, errConversion := strconv.Atoi(chi.URLParam(r, "id"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".APIKeyController->RevokeAPIKey()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.apiKeyService.RevokeAPIKey(ctx, apiKeyID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".APIKeyController->RevokeAPIKey()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(w, "Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// NewAPIKeyController creates a new api key controller
+func NewAPIKeyController(
+	apiKeyService apikey.ServiceInterface,
+	dataManager *data.Manager,
+) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+		dataManager:   dataManager,
+	}
+}
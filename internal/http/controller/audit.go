@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/audit"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// AuditController represents the audit log controller
+type AuditController struct {
+	auditService audit.ServiceInterface
+	dataManager  *data.Manager
+}
+
+// AuditList audit log list and count
+type AuditList struct {
+	Data  []*audit.Log `json:"data"`
+	Count int          `json:"count"`
+}
+
+// AuditCursorList audit log list with the cursors of neighbouring pages
+type AuditCursorList struct {
+	Data    []*audit.Log     `json:"data"`
+	Cursors *data.CursorPage `json:"cursors"`
+}
+
+func parseTime(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// auditFilter gets the audit log filters from the query params,
+// from and to are RFC 3339 times
+func auditFilter(r *http.Request) (*audit.FindAllLogsParams, *types.Error) {
+	queryValues := r.URL.Query()
+	params := &audit.FindAllLogsParams{
+		Entity:    queryValues.Get("entity"),
+		Action:    queryValues.Get("action"),
+		Actor:     queryValues.Get("actor"),
+		RequestID: queryValues.Get("requestId"),
+	}
+
+	var errConversion error
+	if queryValues.Get("entityId") != "" {
+		params.EntityID, errConversion = strconv.Atoi(queryValues.Get("entityId"))
+	}
+	if errConversion == nil && queryValues.Get("from") != "" {
+		params.From, errConversion = parseTime(queryValues.Get("from"))
+	}
+	if errConversion == nil && queryValues.Get("to") != "" {
+		params.To, errConversion = parseTime(queryValues.Get("to"))
+	}
+	if errConversion != nil {
+		return nil, &types.Error{
+			Path:    ".AuditController->auditFilter()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+	}
+
+	return params, nil
+}
+
+// ListAudit Function for listing the audit log
+func (a *AuditController) ListAudit(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := pageParams(r, ".AuditController->ListAudit()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	params, err := auditFilter(r)
+	if err != nil {
+		err.Path = ".AuditController->ListAudit()" + err.Path
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+	params.Limit = limit
+
+	if cursor, ok := cursorParam(r); ok {
+		if params.Limit == 0 {
+			params.Limit = 10
+		}
+		params.Cursor = cursor
+		logList, cursors, err := a.auditService.ListLogsByCursor(r.Context(), params)
+		if err != nil {
+			err.Path = ".AuditController->ListAudit()" + err.Path
+			if err.Error == data.ErrInvalidCursor {
+				response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+				return
+			}
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+
+		setLinkHeader(w, r, cursors)
+		response.JSON(w, http.StatusOK, AuditCursorList{
+			Data:    logList,
+			Cursors: cursors,
+		})
+		return
+	}
+
+	params.Page = page
+	params.EstimateCount = r.URL.Query().Get("count") == "estimated"
+	logList, count, err := a.auditService.ListLogs(r.Context(), params)
+	if err != nil {
+		err.Path = ".AuditController->ListAudit()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, AuditList{
+		Data:  logList,
+		Count: count,
+	})
+}
+
+// NewAuditController creates a new audit log controller
+func NewAuditController(
+	auditService audit.ServiceInterface,
+	dataManager *data.Manager,
+) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+		dataManager:  dataManager,
+	}
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// pageParams gets the page and limit query params of an offset paginated listing
+func pageParams(r *http.Request, path string) (int, int, *types.Error) {
+	queryValues := r.URL.Query()
+	var limit = 10
+	var page = 1
+	var errConversion error
+	if queryValues.Get("limit") != "" {
+		limit, errConversion = strconv.Atoi(queryValues.Get("limit"))
+		if errConversion != nil {
+			return 0, 0, &types.Error{
+				Path:    path,
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    "golang-error",
+			}
+		}
+	}
+	if queryValues.Get("page") != "" {
+		page, errConversion = strconv.Atoi(queryValues.Get("page"))
+		if errConversion != nil {
+			return 0, 0, &types.Error{
+				Path:    path,
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    "golang-error",
+			}
+		}
+	}
+
+	if limit < 0 {
+		limit = 10
+	}
+	if page < 0 {
+		page = 1
+	}
+
+	return page, limit, nil
+}
+
+// cursorParam gets the cursor query param, ok is true when the listing
+// is requested with cursor pagination (an empty cursor means the first page)
+func cursorParam(r *http.Request) (string, bool) {
+	values, ok := r.URL.Query()["cursor"]
+	if !ok || len(values) < 1 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// setLinkHeader writes the Link header pointing to the neighbouring pages
+func setLinkHeader(w http.ResponseWriter, r *http.Request, cursors *data.CursorPage) {
+	if cursors == nil {
+		return
+	}
+
+	links := []string{}
+	if cursors.Next != "" {
+		links = append(links, pageLink(r, cursors.Next, "next"))
+	}
+	if cursors.Prev != "" {
+		links = append(links, pageLink(r, cursors.Prev, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageLink(r *http.Request, cursor string, rel string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("cursor", cursor)
+	query.Del("page")
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
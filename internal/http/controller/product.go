@@ -2,11 +2,12 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/appcontext"
 	"github.com/riskiramdan/evermos/internal/data"
 	"github.com/riskiramdan/evermos/internal/http/response"
 	"github.com/riskiramdan/evermos/internal/product"
@@ -25,6 +26,24 @@ type ProductList struct {
 	Count int                `json:"count"`
 }
 
+// ProductCursorList product list with the cursors of neighbouring pages
+type ProductCursorList struct {
+	Data    []*product.Product `json:"data"`
+	Cursors *data.CursorPage   `json:"cursors"`
+}
+
+// OrderList order list and count
+type OrderList struct {
+	Data  []*product.OrderHistory `json:"data"`
+	Count int                     `json:"count"`
+}
+
+// OrderCursorList order list with the cursors of neighbouring pages
+type OrderCursorList struct {
+	Data    []*product.OrderHistory `json:"data"`
+	Cursors *data.CursorPage        `json:"cursors"`
+}
+
 // ListProduct Function for listing data product
 func (a *ProductController) ListProduct(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
@@ -69,10 +88,39 @@ func (a *ProductController) ListProduct(w http.ResponseWriter, r *http.Request)
 	if page < 0 {
 		page = 1
 	}
+
+	if cursor, ok := cursorParam(r); ok {
+		if limit == 0 {
+			limit = 10
+		}
+		productList, cursors, err := a.productService.ListProductsByCursor(r.Context(), &product.FindAllProductsParams{
+			Limit:  limit,
+			Search: search,
+			Cursor: cursor,
+		})
+		if err != nil {
+			err.Path = ".ProductController->ListProduct()" + err.Path
+			if err.Error == data.ErrInvalidCursor {
+				response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+				return
+			}
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+
+		setLinkHeader(w, r, cursors)
+		response.JSON(w, http.StatusOK, ProductCursorList{
+			Data:    productList,
+			Cursors: cursors,
+		})
+		return
+	}
+
 	productList, count, err := a.productService.ListProducts(r.Context(), &product.FindAllProductsParams{
-		Limit:  limit,
-		Search: search,
-		Page:   page,
+		Limit:         limit,
+		Search:        search,
+		Page:          page,
+		EstimateCount: queryValues.Get("count") == "estimated",
 	})
 	if err != nil {
 		err.Path = ".ProductController->ListProduct()" + err.Path
@@ -91,22 +139,44 @@ func (a *ProductController) ListProduct(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetProduct Function for getting a product by its id or slug.
+// Previous slugs of the product are redirected to the current one.
+func (a *ProductController) GetProduct(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	var singleProduct *product.Product
+
+	idOrSlug := chi.URLParam(r, "idOrSlug")
+	productID, errConversion := strconv.Atoi(idOrSlug)
+	if errConversion == nil {
+		singleProduct, err = a.productService.GetProduct(r.Context(), productID)
+	} else {
+		singleProduct, err = a.productService.GetProductBySlug(r.Context(), idOrSlug)
+	}
+	if err != nil {
+		err.Path = ".ProductController->GetProduct()" + err.Path
+		if err.Error == data.ErrNotFound {
+			response.Error(w, "Not Found", http.StatusNotFound, *err)
+			return
+		}
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	if errConversion != nil && singleProduct.Slug != idOrSlug {
+		location := strings.TrimSuffix(r.URL.Path, idOrSlug) + singleProduct.Slug
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, singleProduct)
+}
+
 // CreateProduct Function for Create data product
 func (a *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
-
 	var params *product.TransactionProductParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".ProductController->CreateProduct()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+	if !decodeAndValidate(w, r, ".ProductController->CreateProduct()", &params) {
 		return
 	}
 
@@ -134,18 +204,8 @@ func (a *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request
 func (a *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
-
 	var params *product.TransactionProductParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".ProductController->UpdateProduct()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+	if !decodeAndValidate(w, r, ".ProductController->UpdateProduct()", &params) {
 		return
 	}
 	var sProductID = chi.URLParam(r, "id")
@@ -217,18 +277,8 @@ func (a *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Request
 func (a *ProductController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
-
 	var params *product.TransactionOrderHistorytParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".ProductController->CreateOrder()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+	if !decodeAndValidate(w, r, ".ProductController->CreateOrder()", &params) {
 		return
 	}
 
@@ -248,6 +298,100 @@ func (a *ProductController) CreateOrder(w http.ResponseWriter, r *http.Request)
 	response.JSON(w, http.StatusOK, "Order Created Successfully")
 }
 
+// ListOrder Function for listing order of the current user
+func (a *ProductController) ListOrder(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	queryValues := r.URL.Query()
+	var limit = 10
+	var errConversion error
+	if queryValues.Get("limit") != "" {
+		limit, errConversion = strconv.Atoi(queryValues.Get("limit"))
+		if errConversion != nil {
+			err = &types.Error{
+				Path:    ".ProductController->ListOrder()",
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    "golang-error",
+			}
+			response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+	}
+
+	var page = 1
+	if queryValues.Get("page") != "" {
+		page, errConversion = strconv.Atoi(queryValues.Get("page"))
+		if errConversion != nil {
+			err = &types.Error{
+				Path:    ".ProductController->ListOrder()",
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    "golang-error",
+			}
+			response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+	}
+
+	if limit < 0 {
+		limit = 10
+	}
+	if page < 0 {
+		page = 1
+	}
+	userID := appcontext.UserID(r.Context())
+
+	if cursor, ok := cursorParam(r); ok {
+		if limit == 0 {
+			limit = 10
+		}
+		orderList, cursors, err := a.productService.ListOrdersByCursor(r.Context(), &product.FindAllOrderHistorysParams{
+			Limit:  limit,
+			UserID: userID,
+			Cursor: cursor,
+		})
+		if err != nil {
+			err.Path = ".ProductController->ListOrder()" + err.Path
+			if err.Error == data.ErrInvalidCursor {
+				response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+				return
+			}
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+
+		setLinkHeader(w, r, cursors)
+		response.JSON(w, http.StatusOK, OrderCursorList{
+			Data:    orderList,
+			Cursors: cursors,
+		})
+		return
+	}
+
+	orderList, count, err := a.productService.ListOrders(r.Context(), &product.FindAllOrderHistorysParams{
+		Limit:         limit,
+		Page:          page,
+		UserID:        userID,
+		EstimateCount: queryValues.Get("count") == "estimated",
+	})
+	if err != nil {
+		err.Path = ".ProductController->ListOrder()" + err.Path
+		if err.Error != data.ErrNotFound {
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+	}
+	if orderList == nil {
+		orderList = []*product.OrderHistory{}
+	}
+
+	response.JSON(w, http.StatusOK, OrderList{
+		Data:  orderList,
+		Count: count,
+	})
+}
+
 // NewProductController creates a new product controller
 func NewProductController(
 	productService product.ServiceInterface,
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// ProductImageList product image list
+type ProductImageList struct {
+	Data []*product.ProductImage `json:"data"`
+}
+
+func imageErrorStatus(err error) (string, int) {
+	switch err {
+	case product.ErrImageInvalidType, product.ErrImageTooLarge, product.ErrImageCorrupted,
+		product.ErrImageRequired, product.ErrImageTooMany, product.ErrImageOrderMismatch:
+		return err.Error(), http.StatusUnprocessableEntity
+	case data.ErrNotFound:
+		return "Not Found", http.StatusNotFound
+	}
+	return "Internal Server Error", http.StatusInternalServerError
+}
+
+func imageURLParams(r *http.Request, path string) (int, int, *types.Error) {
+	productID, errConversion := strconv.Atoi(chi.URLParam(r, "id"))
+	if errConversion != nil {
+		return 0, 0, &types.Error{
+			Path:    path,
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+	}
+
+	imageID := 0
+	if sImageID := chi.URLParam(r, "imageId"); sImageID != "" {
+		imageID, errConversion = strconv.Atoi(sImageID)
+		if errConversion != nil {
+			return 0, 0, &types.Error{
+				Path:    path,
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    "golang-error",
+			}
+		}
+	}
+
+	return productID, imageID, nil
+}
+
+// ListProductImage Function for listing images of a product
+func (a *ProductController) ListProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, _, err := imageURLParams(r, ".ProductController->ListProductImage()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	images, err := a.productService.ListProductImages(r.Context(), productID)
+	if err != nil {
+		err.Path = ".ProductController->ListProductImage()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ProductImageList{
+		Data: images,
+	})
+}
+
+// UploadProductImage Function for uploading images of a product.
+// The images are sent as multipart/form-data under the "images" field
+func (a *ProductController) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, _, err := imageURLParams(r, ".ProductController->UploadProductImage()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, product.MaxImagesPerUpload*product.MaxImageSize+(1<<20))
+	errParse := r.ParseMultipartForm(32 << 20)
+	if errParse != nil {
+		err = &types.Error{
+			Path:    ".ProductController->UploadProductImage()",
+			Message: errParse.Error(),
+			Error:   errParse,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	uploads := []*product.ImageUpload{}
+	for _, fileHeader := range r.MultipartForm.File["images"] {
+		file, errOpen := fileHeader.Open()
+		if errOpen != nil {
+			err = &types.Error{
+				Path:    ".ProductController->UploadProductImage()",
+				Message: errOpen.Error(),
+				Error:   errOpen,
+				Type:    "golang-error",
+			}
+			response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+
+		// read one byte more than allowed so the service can tell the file is too large
+		fileData, errRead := ioutil.ReadAll(io.LimitReader(file, product.MaxImageSize+1))
+		file.Close()
+		if errRead != nil {
+			err = &types.Error{
+				Path:    ".ProductController->UploadProductImage()",
+				Message: errRead.Error(),
+				Error:   errRead,
+				Type:    "golang-error",
+			}
+			response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+
+		uploads = append(uploads, &product.ImageUpload{
+			Filename: fileHeader.Filename,
+			Data:     fileData,
+		})
+	}
+
+	var images []*product.ProductImage
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		images, err = a.productService.UploadProductImages(ctx, productID, uploads)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->UploadProductImage()" + err.Path
+		message, status := imageErrorStatus(errTransaction)
+		if status == http.StatusUnprocessableEntity {
+			message = err.Message
+		}
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ProductImageList{
+		Data: images,
+	})
+}
+
+// ReorderProductImage Function for reordering images of a product
+func (a *ProductController) ReorderProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, _, err := imageURLParams(r, ".ProductController->ReorderProductImage()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var params *product.ReorderProductImagesParams
+	if !decodeAndValidate(w, r, ".ProductController->ReorderProductImage()", &params) {
+		return
+	}
+
+	var images []*product.ProductImage
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		images, err = a.productService.ReorderProductImages(ctx, productID, params.ImageIDs)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->ReorderProductImage()" + err.Path
+		message, status := imageErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ProductImageList{
+		Data: images,
+	})
+}
+
+// SetPrimaryProductImage Function for choosing the primary image of a product
+func (a *ProductController) SetPrimaryProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, imageID, err := imageURLParams(r, ".ProductController->SetPrimaryProductImage()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var images []*product.ProductImage
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		images, err = a.productService.SetPrimaryProductImage(ctx, productID, imageID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->SetPrimaryProductImage()" + err.Path
+		message, status := imageErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ProductImageList{
+		Data: images,
+	})
+}
+
+// DeleteProductImage Function for deleting an image of a product
+func (a *ProductController) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, imageID, err := imageURLParams(r, ".ProductController->DeleteProductImage()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.productService.DeleteProductImage(ctx, productID, imageID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->DeleteProductImage()" + err.Path
+		message, status := imageErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/spreadsheet"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// maxImportFileSize is the largest accepted import file
+const maxImportFileSize = 20 << 20
+
+func writeSpreadsheet(w http.ResponseWriter, format string, filename string, rows [][]string) {
+	if format == spreadsheet.FormatCSV {
+		response.CSV(w, http.StatusOK, filename+".csv", rows)
+		return
+	}
+	response.EXCEL(w, http.StatusOK, filename+".xlsx", rows)
+}
+
+func spreadsheetFormat(r *http.Request) (string, *types.Error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return spreadsheet.FormatXLSX, nil
+	}
+	if format != spreadsheet.FormatXLSX && format != spreadsheet.FormatCSV {
+		return "", &types.Error{
+			Path:    ".ProductController->spreadsheetFormat()",
+			Message: spreadsheet.ErrUnsupportedFormat.Error(),
+			Error:   spreadsheet.ErrUnsupportedFormat,
+			Type:    "validation-error",
+		}
+	}
+	return format, nil
+}
+
+// ImportTemplate Function for downloading the product import template
+func (a *ProductController) ImportTemplate(w http.ResponseWriter, r *http.Request) {
+	format, err := spreadsheetFormat(r)
+	if err != nil {
+		response.Error(w, err.Message, http.StatusBadRequest, *err)
+		return
+	}
+
+	writeSpreadsheet(w, format, "Template-Upload", a.productService.ImportTemplate())
+}
+
+// ExportProduct Function for downloading the current product catalog
+func (a *ProductController) ExportProduct(w http.ResponseWriter, r *http.Request) {
+	format, err := spreadsheetFormat(r)
+	if err != nil {
+		response.Error(w, err.Message, http.StatusBadRequest, *err)
+		return
+	}
+
+	rows, err := a.productService.ExportProducts(r.Context())
+	if err != nil {
+		err.Path = ".ProductController->ExportProduct()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	writeSpreadsheet(w, format, "Products", rows)
+}
+
+// ImportProduct Function for importing products from an xlsx or csv file.
+// The file is sent as multipart/form-data under the "file" field.
+// Large files are imported in the background, the response is then
+// the import job which status can be polled.
+func (a *ProductController) ImportProduct(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+(1<<20))
+	file, fileHeader, errForm := r.FormFile("file")
+	if errForm != nil {
+		err = &types.Error{
+			Path:    ".ProductController->ImportProduct()",
+			Message: errForm.Error(),
+			Error:   errForm,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+	defer file.Close()
+
+	format, errFormat := spreadsheet.FormatFromFilename(fileHeader.Filename)
+	if errFormat != nil {
+		err = &types.Error{
+			Path:    ".ProductController->ImportProduct()",
+			Message: errFormat.Error(),
+			Error:   errFormat,
+			Type:    "validation-error",
+		}
+		response.Error(w, errFormat.Error(), http.StatusUnprocessableEntity, *err)
+		return
+	}
+
+	fileData, errRead := ioutil.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if errRead != nil {
+		err = &types.Error{
+			Path:    ".ProductController->ImportProduct()",
+			Message: errRead.Error(),
+			Error:   errRead,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	rows, errRead := spreadsheet.Read(format, fileData)
+	if errRead != nil {
+		err = &types.Error{
+			Path:    ".ProductController->ImportProduct()",
+			Message: errRead.Error(),
+			Error:   errRead,
+			Type:    "validation-error",
+		}
+		response.Error(w, errRead.Error(), http.StatusUnprocessableEntity, *err)
+		return
+	}
+
+	if len(rows)-1 > product.ImportAsyncThreshold {
+		job, err := a.productService.CreateImportJob(r.Context(), fileHeader.Filename, len(rows)-1)
+		if err != nil {
+			err.Path = ".ProductController->ImportProduct()" + err.Path
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+
+		// the request context is cancelled once responded, keep only the caller and request id
+		ctx := context.WithValue(context.Background(), appcontext.KeyUserID, appcontext.UserID(r.Context()))
+		if clientID := appcontext.ClientID(r.Context()); clientID != nil {
+			ctx = context.WithValue(ctx, appcontext.KeyClientID, *clientID)
+		}
+		ctx = context.WithValue(ctx, appcontext.KeyRequestID, appcontext.RequestID(r.Context()))
+		go a.runImportJob(ctx, job, rows)
+
+		response.JSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	var result *product.ImportResult
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		result, err = a.productService.ImportProducts(ctx, rows)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->ImportProduct()" + err.Path
+		switch errTransaction {
+		case product.ErrImportInvalidRows:
+			response.JSON(w, http.StatusUnprocessableEntity, result)
+		case product.ErrImportEmpty, product.ErrImportHeader:
+			response.Error(w, errTransaction.Error(), http.StatusUnprocessableEntity, *err)
+		default:
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+func (a *ProductController) runImportJob(ctx context.Context, job *product.ImportJob, rows [][]string) {
+	job, err := a.productService.StartImportJob(ctx, job)
+	if err != nil {
+		log.Printf("DETAIL [%s - %s]: %s\n", ".ProductController->runImportJob()"+err.Path, err.Type, err.Message)
+		return
+	}
+
+	var result *product.ImportResult
+	var importErr *types.Error
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, importErr = a.productService.ImportProducts(ctx, rows)
+		if importErr != nil {
+			return importErr.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		if importErr == nil {
+			importErr = &types.Error{
+				Path:    ".ProductController->runImportJob()",
+				Message: errTransaction.Error(),
+				Error:   errTransaction,
+				Type:    "pq-error",
+			}
+		}
+		log.Printf("DETAIL [%s - %s]: %s\n", ".ProductController->runImportJob()"+importErr.Path, importErr.Type, importErr.Message)
+	}
+
+	_, err = a.productService.FinishImportJob(ctx, job, result, importErr)
+	if err != nil {
+		log.Printf("DETAIL [%s - %s]: %s\n", ".ProductController->runImportJob()"+err.Path, err.Type, err.Message)
+	}
+}
+
+// GetImportJob Function for polling the status of a background product import
+func (a *ProductController) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	jobID, errConversion := strconv.Atoi(chi.URLParam(r, "jobId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".ProductController->GetImportJob()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	job, err := a.productService.GetImportJob(r.Context(), jobID)
+	if err != nil {
+		err.Path = ".ProductController->GetImportJob()" + err.Path
+		if err.Error == data.ErrNotFound {
+			response.Error(w, "Not Found", http.StatusNotFound, *err)
+			return
+		}
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, job)
+}
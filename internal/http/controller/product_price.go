@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// ProductPriceList product price list
+type ProductPriceList struct {
+	Data []*product.ProductPrice `json:"data"`
+}
+
+func priceErrorStatus(err error) (string, int) {
+	switch err {
+	case product.ErrPriceInvalid, product.ErrPriceNotInFuture:
+		return err.Error(), http.StatusUnprocessableEntity
+	case product.ErrPriceAlreadyApplied:
+		return err.Error(), http.StatusConflict
+	case data.ErrNotFound:
+		return "Not Found", http.StatusNotFound
+	}
+	return "Internal Server Error", http.StatusInternalServerError
+}
+
+func priceURLParams(r *http.Request, path string) (int, int, *types.Error) {
+	productID, errConversion := strconv.Atoi(chi.URLParam(r, "id"))
+	if errConversion != nil {
+		return 0, 0, &types.Error{
+			Path:    path,
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+	}
+
+	priceID := 0
+	if sPriceID := chi.URLParam(r, "priceId"); sPriceID != "" {
+		priceID, errConversion = strconv.Atoi(sPriceID)
+		if errConversion != nil {
+			return 0, 0, &types.Error{
+				Path:    path,
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    "golang-error",
+			}
+		}
+	}
+
+	return productID, priceID, nil
+}
+
+// ListProductPrice Function for listing the price history and the scheduled prices of a product
+func (a *ProductController) ListProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, _, err := priceURLParams(r, ".ProductController->ListProductPrice()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	prices, err := a.productService.ListProductPrices(r.Context(), productID)
+	if err != nil {
+		err.Path = ".ProductController->ListProductPrice()" + err.Path
+		message, status := priceErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ProductPriceList{
+		Data: prices,
+	})
+}
+
+// ScheduleProductPrice Function for scheduling a price change of a product
+func (a *ProductController) ScheduleProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, _, err := priceURLParams(r, ".ProductController->ScheduleProductPrice()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var params *product.SchedulePriceParams
+	if !decodeAndValidate(w, r, ".ProductController->ScheduleProductPrice()", &params) {
+		return
+	}
+
+	var price *product.ProductPrice
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		price, err = a.productService.SchedulePrice(ctx, productID, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->ScheduleProductPrice()" + err.Path
+		message, status := priceErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, price)
+}
+
+// CancelProductPrice Function for cancelling a scheduled price change of a product
+func (a *ProductController) CancelProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, priceID, err := priceURLParams(r, ".ProductController->CancelProductPrice()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.productService.CancelScheduledPrice(ctx, productID, priceID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->CancelProductPrice()" + err.Path
+		message, status := priceErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
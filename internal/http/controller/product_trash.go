@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// ListDeletedProduct Function for listing soft deleted products
+func (a *ProductController) ListDeletedProduct(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := pageParams(r, ".ProductController->ListDeletedProduct()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	productList, count, err := a.productService.ListDeletedProducts(r.Context(), &product.FindAllProductsParams{
+		Limit:  limit,
+		Page:   page,
+		Search: r.URL.Query().Get("search"),
+	})
+	if err != nil {
+		err.Path = ".ProductController->ListDeletedProduct()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ProductList{
+		Data:  productList,
+		Count: count,
+	})
+}
+
+// RestoreProduct Function for restoring a soft deleted product
+func (a *ProductController) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	productID, errConversion := strconv.Atoi(chi.URLParam(r, "id"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".ProductController->RestoreProduct()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var singleProduct *product.Product
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		singleProduct, err = a.productService.RestoreProduct(ctx, productID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->RestoreProduct()" + err.Path
+		message, status := trashErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, singleProduct)
+}
+
+// PurgeProduct Function for permanently deleting a soft deleted product
+func (a *ProductController) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	productID, errConversion := strconv.Atoi(chi.URLParam(r, "id"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".ProductController->PurgeProduct()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.productService.PurgeProduct(ctx, productID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".ProductController->PurgeProduct()" + err.Path
+		message, status := trashErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
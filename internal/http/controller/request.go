@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/validation"
+)
+
+// errBodyRequired is returned when the json body is empty or null
+var errBodyRequired = errors.New("request body is required")
+
+// decodeAndValidate decodes the json body into the params and validates them against their validate tags.
+// It writes a 400 response for a malformed body and a 422 response listing every failing field,
+// and returns false when the request should not go on.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, path string, params interface{}) bool {
+	errDecode := json.NewDecoder(r.Body).Decode(params)
+	if errDecode == nil && reflect.Indirect(reflect.ValueOf(params)).Kind() == reflect.Ptr &&
+		reflect.Indirect(reflect.ValueOf(params)).IsNil() {
+		errDecode = errBodyRequired
+	}
+	if errDecode != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, types.Error{
+			Path:    path,
+			Message: errDecode.Error(),
+			Error:   errDecode,
+			Type:    "golang-error",
+		})
+		return false
+	}
+
+	errValidation := validation.Validate(reflect.Indirect(reflect.ValueOf(params)).Interface())
+	if errValidation != nil {
+		response.Error(w, "Validation error", http.StatusUnprocessableEntity, types.Error{
+			Path:    path,
+			Message: errValidation.Error(),
+			Error:   errValidation,
+			Type:    "validation-error",
+		})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// trashErrorStatus maps the errors of restoring and purging soft deleted data
+func trashErrorStatus(err error) (string, int) {
+	switch err {
+	case data.ErrNotFound:
+		return "Not Found", http.StatusNotFound
+	case data.ErrStillReferenced, product.ErrProductAlreadyExists, user.ErrEmailAlreadyExists:
+		return err.Error(), http.StatusConflict
+	}
+	return "Internal Server Error", http.StatusInternalServerError
+}
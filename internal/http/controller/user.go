@@ -2,15 +2,16 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/go-chi/chi"
 	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/audit"
 	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
 	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/product"
 	"github.com/riskiramdan/evermos/internal/types"
 	"github.com/riskiramdan/evermos/internal/user"
 )
@@ -18,19 +19,29 @@ import (
 // UserController represents the user controller
 // swagger:ignore
 type UserController struct {
-	userService user.ServiceInterface
-	dataManager *data.Manager
+	userService    user.ServiceInterface
+	productService product.ServiceInterface
+	auditService   audit.ServiceInterface
+	dataManager    *data.Manager
+	cookies        *cookie.Policy
 }
 
 // UserList user list and count
 type UserList struct {
-	Data  []*user.User `json:"data"`
-	Count int          `json:"count"`
+	Data  interface{} `json:"data"`
+	Count int         `json:"count"`
+}
+
+// UserCursorList user list with the cursors of neighbouring pages
+type UserCursorList struct {
+	Data    interface{}      `json:"data"`
+	Cursors *data.CursorPage `json:"cursors"`
 }
 
 // Login swagger:operation POST /v1/login Users Login
 //
-// Login.
+// Login. When the user has to pass a second factor no session is opened,
+// the response holds a challenge token to complete the login at /v1/login/mfa.
 //
 // ---
 // parameters:
@@ -52,24 +63,17 @@ type UserList struct {
 func (a *UserController) Login(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
-
 	var params user.LoginParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".UserController->Login()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+	if !decodeAndValidate(w, r, ".UserController->Login()", &params) {
 		return
 	}
 
 	var sess *user.LoginResponse
 	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
-		sess, err = a.userService.Login(r.Context(), params.Email, params.Password)
+		sess, err = a.userService.Login(r.Context(), params.Email, params.Password, &user.ClientInfo{
+			UserAgent: r.UserAgent(),
+			IP:        clientIP(r),
+		})
 		if err != nil {
 			return err.Error
 		}
@@ -77,20 +81,19 @@ func (a *UserController) Login(w http.ResponseWriter, r *http.Request) {
 	})
 	if errTransaction != nil {
 		err.Path = ".UserController->Login()" + err.Path
-		if err.Error == user.ErrWrongPassword || err.Error == data.ErrNotFound {
+		if err.Error == user.ErrInvalidCredentials {
 			response.Error(w, "Email / password is wrong", http.StatusBadRequest, *err)
+		} else if err.Error == user.ErrLoginLocked {
+			response.Error(w, err.Message, http.StatusTooManyRequests, *err)
+		} else if err.Error == user.ErrEmailNotVerified {
+			response.Error(w, err.Message, http.StatusForbidden, *err)
 		} else {
 			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
 		}
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:  "sessionId",
-		Value: sess.SessionID,
-	})
-
-	response.JSON(w, http.StatusOK, sess)
+	response.JSON(w, http.StatusOK, a.sessionResponse(w, sess))
 }
 
 // ChangePassword swagger:operation POST /v1/login Users ChangePassword
@@ -115,17 +118,8 @@ func (a *UserController) Login(w http.ResponseWriter, r *http.Request) {
 func (a *UserController) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
 	var params user.ChangePasswordParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".UserController->ChangePassword()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+	if !decodeAndValidate(w, r, ".UserController->ChangePassword()", &params) {
 		return
 	}
 
@@ -145,22 +139,27 @@ func (a *UserController) ChangePassword(w http.ResponseWriter, r *http.Request)
 	response.JSON(w, http.StatusNoContent, "")
 }
 
-// UpdateUser swagger:operation PUT /v1/users Users UpdateUser
+// UpdateUser swagger:operation PUT /v1/users/{userId} Users UpdateUser
 //
-// Update user.
+// Update user. The administrators update any user, the others only their own profile
+// whose new email is used once the link sent to it is opened.
 //
 // ---
 // parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
 // - name: body
 //   in: body
 //   required: true
 //   schema:
-//     $ref: "#/definitions/ChangePasswordParams"
+//     $ref: "#/definitions/UpdateUserParams"
 // responses:
 //   200:
 //     description: "Ok"
 //     schema:
-//       $ref: "#/definitions/Profile"
+//       $ref: "#/definitions/AdminUserResponse"
 //   default:
 //     description: "Error"
 //     schema:
@@ -171,36 +170,41 @@ func (a *UserController) ChangePassword(w http.ResponseWriter, r *http.Request)
 func (a *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
-
 	var params *user.UpdateUserParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".UserController->UpdateUser()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
+	if !decodeAndValidate(w, r, ".UserController->UpdateUser()", &params) {
+		return
+	}
+	userID, err := ownerOnly(r, ".UserController->UpdateUser()")
+	if err != nil {
+		message, status := profileErrorStatus(err.Error)
+		if status == http.StatusInternalServerError {
+			message, status = "Bad Request", http.StatusBadRequest
 		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		response.Error(w, message, status, *err)
 		return
 	}
-	var sUserID = chi.URLParam(r, "userId")
-	userID, errConversion := strconv.Atoi(sUserID)
-	if errConversion != nil {
+	manager := canManageUsers(r.Context())
+	if !manager && params.Role != "" && params.Role != appcontext.UserRole(r.Context()) {
 		err = &types.Error{
 			Path:    ".UserController->UpdateUser()",
-			Message: errConversion.Error(),
-			Error:   errConversion,
-			Type:    "golang-error",
+			Message: "only the administrators can change roles",
+			Error:   ErrNotOwner,
+			Type:    "validation-error",
 		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		response.Error(w, "Forbidden", http.StatusForbidden, *err)
 		return
 	}
 
 	var singleUser *user.User
 	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
-		singleUser, err = a.userService.UpdateUser(ctx, userID, params)
+		if manager {
+			singleUser, err = a.userService.UpdateUser(ctx, userID, params)
+		} else {
+			singleUser, err = a.userService.UpdateProfile(ctx, userID, &user.UpdateProfileParams{
+				Name:  params.Name,
+				Email: params.Email,
+			})
+		}
 		if err != nil {
 			return err.Error
 		}
@@ -208,15 +212,16 @@ func (a *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	})
 	if errTransaction != nil {
 		err.Path = ".UserController->UpdateUser()" + err.Path
-		if errTransaction == user.ErrEmailAlreadyExists {
-			response.Error(w, "Alamat Email Sudah Terdaftar", http.StatusUnprocessableEntity, *err)
-		} else {
-			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-		}
+		message, status := profileErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
 		return
 	}
-	response.JSON(w, http.StatusOK, singleUser)
 
+	if manager {
+		response.JSON(w, http.StatusOK, newAdminUserResponse(singleUser))
+		return
+	}
+	response.JSON(w, http.StatusOK, newSelfUserResponse(singleUser))
 }
 
 // CreateUser swagger:operation POST /v1/users Users CreateUser
@@ -245,18 +250,8 @@ func (a *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 func (a *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	decoder := json.NewDecoder(r.Body)
-
 	var params *user.CreateUserParams
-	errDecode := decoder.Decode(&params)
-	if errDecode != nil {
-		err = &types.Error{
-			Path:    ".UserController->CreateUser()",
-			Message: errDecode.Error(),
-			Error:   errDecode,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+	if !decodeAndValidate(w, r, ".UserController->CreateUser()", &params) {
 		return
 	}
 
@@ -271,6 +266,8 @@ func (a *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 		err.Path = ".UserController->CreateUser()" + err.Path
 		if errTransaction == user.ErrEmailAlreadyExists {
 			response.Error(w, "Alamat Email Sudah Terdaftar", http.StatusUnprocessableEntity, *err)
+		} else if errTransaction == user.ErrInvalidRole {
+			response.Error(w, err.Message, http.StatusUnprocessableEntity, *err)
 		} else {
 			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
 		}
@@ -281,60 +278,6 @@ func (a *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, "User Created Successfully")
 }
 
-// DeleteUser swagger:operation POST /v1/users Users DeleteUser
-//
-// Delete user.
-//
-// ---
-// parameters:
-// - name: body
-//   in: body
-//   required: true
-//   schema:
-//     $ref: "#/definitions/ChangePasswordParams"
-// responses:
-//   200:
-//     description: "Ok"
-//     schema:
-//       $ref: "#/definitions/Profile"
-//   default:
-//     description: "Error"
-//     schema:
-//       $ref: "#/definitions/ErrorResponse"
-// security:
-//   - bearerAuth: []
-//
-func (a *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	var err *types.Error
-	var sUserID = chi.URLParam(r, "userId")
-	userID, errConversion := strconv.Atoi(sUserID)
-	if errConversion != nil {
-		err = &types.Error{
-			Path:    ".UserController->DeleteUser()",
-			Message: errConversion.Error(),
-			Error:   errConversion,
-			Type:    "golang-error",
-		}
-		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-		return
-	}
-
-	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
-		err = a.userService.DeleteUser(ctx, userID)
-		if err != nil {
-			return err.Error
-		}
-		return nil
-	})
-	if errTransaction != nil {
-		err.Path = ".USerController->DeleteUser()" + err.Path
-		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-		return
-	}
-	response.JSON(w, http.StatusNoContent, "")
-
-}
-
 // ListUser swagger:operation POST /v1/users Users ListUser
 //
 // List user.
@@ -401,10 +344,39 @@ func (a *UserController) ListUser(w http.ResponseWriter, r *http.Request) {
 	if page < 0 {
 		page = 1
 	}
+
+	if cursor, ok := cursorParam(r); ok {
+		if limit == 0 {
+			limit = 10
+		}
+		userList, cursors, err := a.userService.ListUsersByCursor(r.Context(), &user.FindAllUsersParams{
+			Limit:  limit,
+			Search: search,
+			Cursor: cursor,
+		})
+		if err != nil {
+			err.Path = ".UserController->ListUser()" + err.Path
+			if err.Error == data.ErrInvalidCursor {
+				response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+				return
+			}
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+
+		setLinkHeader(w, r, cursors)
+		response.JSON(w, http.StatusOK, UserCursorList{
+			Data:    newUserResponses(r.Context(), userList),
+			Cursors: cursors,
+		})
+		return
+	}
+
 	userList, count, err := a.userService.ListUsers(r.Context(), &user.FindAllUsersParams{
-		Limit:  limit,
-		Search: search,
-		Page:   page,
+		Limit:         limit,
+		Search:        search,
+		Page:          page,
+		EstimateCount: queryValues.Get("count") == "estimated",
 	})
 	if err != nil {
 		err.Path = ".UserController->ListUser()" + err.Path
@@ -413,24 +385,20 @@ func (a *UserController) ListUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	if userList == nil {
-		userList = []*user.User{}
-	}
-
 	response.JSON(w, http.StatusOK, UserList{
-		Data:  userList,
+		Data:  newUserResponses(r.Context(), userList),
 		Count: count,
 	})
 }
 
-// Logout swagger:operation GET /v1/logout Users Logout
+// Logout swagger:operation POST /v1/logout Users Logout
 //
 // Logout from the system.
 //
 // ---
 // responses:
-//   200:
-//     description: "Ok"
+//   204:
+//     description: "No Content"
 //   default:
 //     description: "Error"
 //     schema:
@@ -441,37 +409,44 @@ func (a *UserController) ListUser(w http.ResponseWriter, r *http.Request) {
 func (a *UserController) Logout(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
 
-	// get token from the context
+	// get the session from the context
 	// log it out!
-	loginToken, ok := r.Context().Value(appcontext.KeySessionID).(string)
-	if !ok {
-		errUserID := errors.New("failed to get user id from request context")
+	sessionID := appcontext.UserSessionID(r.Context())
+	if sessionID == 0 {
+		errSession := errors.New("failed to get session id from request context")
 		response.Error(w, "Internal Server Error", http.StatusInternalServerError, types.Error{
 			Path:    ".UserController->Logout()",
-			Message: errUserID.Error(),
-			Error:   errUserID,
+			Message: errSession.Error(),
+			Error:   errSession,
 			Type:    "golang-error",
 		})
 		return
 	}
 
-	err = a.userService.Logout(r.Context(), loginToken)
+	err = a.userService.Logout(r.Context(), sessionID)
 	if err != nil {
 		err.Path = ".UserController->Logout()" + err.Path
 		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
 		return
 	}
 
+	a.cookies.ClearSession(w)
 	response.JSON(w, http.StatusNoContent, "")
 }
 
 // NewUserController creates a new user controller
 func NewUserController(
 	userService user.ServiceInterface,
+	productService product.ServiceInterface,
+	auditService audit.ServiceInterface,
 	dataManager *data.Manager,
+	cookies *cookie.Policy,
 ) *UserController {
 	return &UserController{
-		userService: userService,
-		dataManager: dataManager,
+		userService:    userService,
+		productService: productService,
+		auditService:   auditService,
+		dataManager:    dataManager,
+		cookies:        cookies,
 	}
 }
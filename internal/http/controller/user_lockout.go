@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// UnlockUser swagger:operation POST /v1/users/{userId}/unlock Users UnlockUser
+//
+// Lift the lockout of the user after too many failed logins.
+//
+// ---
+// parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	userID, errConversion := strconv.Atoi(chi.URLParam(r, "userId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->UnlockUser()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.UnlockUser(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->UnlockUser()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(w, "User not found", http.StatusNotFound, *err)
+		} else {
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// ErrNotOwner is returned when a user acts on another user without the permission to write users
+var ErrNotOwner = errors.New("only the administrators can access other users")
+
+// canManageUsers tells whether the current user may read and update the other users
+func canManageUsers(ctx context.Context) bool {
+	return user.HasPermission(appcontext.UserRole(ctx), user.PermissionUserWrite)
+}
+
+// ownerOnly checks that the user of the url is the current user unless the current user manages users
+func ownerOnly(r *http.Request, path string) (int, *types.Error) {
+	userID, errConversion := strconv.Atoi(chi.URLParam(r, "userId"))
+	if errConversion != nil {
+		return 0, &types.Error{
+			Path:    path,
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+	}
+
+	if userID != appcontext.UserID(r.Context()) && !canManageUsers(r.Context()) {
+		return 0, &types.Error{
+			Path:    path,
+			Message: ErrNotOwner.Error(),
+			Error:   ErrNotOwner,
+			Type:    "validation-error",
+		}
+	}
+
+	return userID, nil
+}
+
+func profileErrorStatus(err error) (string, int) {
+	switch err {
+	case ErrNotOwner:
+		return "Forbidden", http.StatusForbidden
+	case data.ErrNotFound:
+		return "User not found", http.StatusNotFound
+	case user.ErrEmailAlreadyExists:
+		return "Alamat Email Sudah Terdaftar", http.StatusUnprocessableEntity
+	case user.ErrInvalidRole:
+		return err.Error(), http.StatusUnprocessableEntity
+	case user.ErrTooManyRequests:
+		return err.Error(), http.StatusTooManyRequests
+	}
+	return "Internal Server Error", http.StatusInternalServerError
+}
+
+// GetMe swagger:operation GET /v1/me Users GetMe
+//
+// Get the profile of the logged-in user.
+//
+// ---
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/SelfUserResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) GetMe(w http.ResponseWriter, r *http.Request) {
+	singleUser, err := a.userService.GetUser(r.Context(), appcontext.UserID(r.Context()))
+	if err != nil {
+		err.Path = ".UserController->GetMe()" + err.Path
+		message, status := profileErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newSelfUserResponse(singleUser))
+}
+
+// UpdateMe swagger:operation PUT /v1/me Users UpdateMe
+//
+// Update the profile of the logged-in user, a new email is used once the link sent to it is opened.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/UpdateProfileParams"
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/SelfUserResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.UpdateProfileParams
+	if !decodeAndValidate(w, r, ".UserController->UpdateMe()", &params) {
+		return
+	}
+
+	var singleUser *user.User
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		singleUser, err = a.userService.UpdateProfile(ctx, appcontext.UserID(ctx), &params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->UpdateMe()" + err.Path
+		message, status := profileErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newSelfUserResponse(singleUser))
+}
+
+// GetUser swagger:operation GET /v1/users/{userId} Users GetUser
+//
+// Get a user, the users other than the logged-in one are only available to the administrators.
+//
+// ---
+// parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/AdminUserResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := ownerOnly(r, ".UserController->GetUser()")
+	if err != nil {
+		message, status := profileErrorStatus(err.Error)
+		if status == http.StatusInternalServerError {
+			message, status = "Bad Request", http.StatusBadRequest
+		}
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	singleUser, err := a.userService.GetUser(r.Context(), userID)
+	if err != nil {
+		err.Path = ".UserController->GetUser()" + err.Path
+		message, status := profileErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	if canManageUsers(r.Context()) {
+		response.JSON(w, http.StatusOK, newAdminUserResponse(singleUser))
+		return
+	}
+	response.JSON(w, http.StatusOK, newSelfUserResponse(singleUser))
+}
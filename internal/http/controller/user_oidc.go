@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+	"github.com/riskiramdan/evermos/internal/validation"
+)
+
+func oidcErrorStatus(err error) (string, int) {
+	switch err {
+	case user.ErrOIDCDisabled:
+		return err.Error(), http.StatusNotFound
+	case user.ErrOIDCStateInvalid, user.ErrOIDCFailed:
+		return err.Error(), http.StatusBadRequest
+	case user.ErrOIDCEmailNotVerified:
+		return err.Error(), http.StatusForbidden
+	case data.ErrNotFound:
+		return "User not found", http.StatusForbidden
+	}
+	return "Internal Server Error", http.StatusInternalServerError
+}
+
+// StartOIDCLogin swagger:operation GET /v1/oidc/login Users StartOIDCLogin
+//
+// Redirect to the identity provider to sign in, the provider redirects back to /v1/oidc/callback.
+// The state of the login is kept in the oidcState cookie, the callback is only accepted by the same browser.
+//
+// ---
+// responses:
+//   302:
+//     description: "Found"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var login *user.OIDCLogin
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		login, err = a.userService.StartOIDCLogin(ctx)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->StartOIDCLogin()" + err.Path
+		message, status := oidcErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	a.cookies.SetOIDCState(w, login.State, login.ExpiredAt)
+	http.Redirect(w, r, login.AuthURL, http.StatusFound)
+}
+
+// CompleteOIDCLogin swagger:operation GET /v1/oidc/callback Users CompleteOIDCLogin
+//
+// Complete the login at the identity provider. The user is linked by the verified email or created,
+// the response is the one of /v1/login. The state has to match the oidcState cookie set by /v1/oidc/login,
+// the cookie is removed.
+//
+// ---
+// parameters:
+// - name: code
+//   in: query
+//   required: true
+//   type: string
+// - name: state
+//   in: query
+//   required: true
+//   type: string
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/LoginResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	// the state is single use whatever the outcome
+	browserState := cookie.OIDCState(r)
+	a.cookies.ClearOIDCState(w)
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		err = &types.Error{
+			Path:    ".UserController->CompleteOIDCLogin()",
+			Message: query.Get("error") + ": " + query.Get("error_description"),
+			Error:   user.ErrOIDCFailed,
+			Type:    "validation-error",
+		}
+		response.Error(w, user.ErrOIDCFailed.Error(), http.StatusBadRequest, *err)
+		return
+	}
+
+	params := user.OIDCCallbackParams{
+		Code:         query.Get("code"),
+		State:        query.Get("state"),
+		BrowserState: browserState,
+	}
+	errValidation := validation.Validate(&params)
+	if errValidation != nil {
+		err = &types.Error{
+			Path:    ".UserController->CompleteOIDCLogin()",
+			Message: errValidation.Error(),
+			Error:   errValidation,
+			Type:    "validation-error",
+		}
+		response.Error(w, "Validation error", http.StatusUnprocessableEntity, *err)
+		return
+	}
+
+	var sess *user.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		sess, err = a.userService.CompleteOIDCLogin(ctx, &params, &user.ClientInfo{
+			UserAgent: r.UserAgent(),
+			IP:        clientIP(r),
+		})
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->CompleteOIDCLogin()" + err.Path
+		message, status := oidcErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, a.sessionResponse(w, sess))
+}
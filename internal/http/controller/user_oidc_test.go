@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+func (s *stubUserService) StartOIDCLogin(ctx context.Context) (*user.OIDCLogin, *types.Error) {
+	return &user.OIDCLogin{
+		AuthURL:   "https://accounts.example.com/authorize?state=login-state",
+		State:     "login-state",
+		ExpiredAt: time.Now().Add(10 * time.Minute),
+	}, nil
+}
+
+// CompleteOIDCLogin refuses the callback the way the service does when the browser state does not match
+func (s *stubUserService) CompleteOIDCLogin(ctx context.Context, params *user.OIDCCallbackParams, client *user.ClientInfo) (*user.LoginResponse, *types.Error) {
+	if params.BrowserState != params.State {
+		return nil, &types.Error{
+			Path:    ".stubUserService->CompleteOIDCLogin()",
+			Message: user.ErrOIDCStateInvalid.Error(),
+			Error:   user.ErrOIDCStateInvalid,
+			Type:    "validation-error",
+		}
+	}
+	return s.Login(ctx, "jane@example.com", "", client)
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestStartOIDCLoginSetsStateCookie(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/oidc/login", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	c := findCookie(rec, "oidcState")
+	if c == nil {
+		t.Fatal("no oidcState cookie")
+	}
+	if c.Value != "login-state" || !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteLaxMode || c.Path != "/v1/oidc" {
+		t.Errorf("cookie = %+v, want the state, HttpOnly, Secure, SameSite=Lax and Path=/v1/oidc", c)
+	}
+	if time.Until(c.Expires) > 10*time.Minute {
+		t.Errorf("cookie expires at %v, want within the state lifetime", c.Expires)
+	}
+}
+
+func TestCompleteOIDCLoginChecksStateCookie(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		cookie string
+		status int
+	}{
+		{"matching cookie", "login-state", http.StatusOK},
+		{"other cookie", "other-state", http.StatusBadRequest},
+		{"no cookie", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/oidc/callback?code=code&state=login-state", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "oidcState", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			c := findCookie(rec, "oidcState")
+			if c == nil || c.MaxAge >= 0 || c.Path != "/v1/oidc" {
+				t.Errorf("cookie = %+v, want the oidcState cookie removed", c)
+			}
+		})
+	}
+}
+
+func TestExportUserHasIdentities(t *testing.T) {
+	router := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/7/export", nil)
+	ctx := context.WithValue(req.Context(), appcontext.KeyUserID, 7)
+	ctx = context.WithValue(ctx, appcontext.KeyUserRole, user.RoleCustomer)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req.WithContext(ctx))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var body struct {
+		Identities []*user.Identity `json:"identities"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Identities) != 1 || body.Identities[0].Subject != "jane" {
+		t.Errorf("identities = %+v, want the linked account", body.Identities)
+	}
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// RequestResetPassword swagger:operation POST /v1/forgotPassword Users RequestResetPassword
+//
+// Send a reset password link, the response is the same whether the email is registered or not.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/RequestResetPasswordParams"
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) RequestResetPassword(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.RequestResetPasswordParams
+	if !decodeAndValidate(w, r, ".UserController->RequestResetPassword()", &params) {
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.RequestResetPassword(ctx, params.Email)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->RequestResetPassword()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// resetPasswordPage is the form the reset password links open unless RESET_PASSWORD_URL points to a frontend page.
+// The token is read from the url by the script and sent along with the new password to POST /v1/resetPassword.
+const resetPasswordPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Reset your password</title>
+</head>
+<body>
+<h1>Reset your password</h1>
+<form id="reset">
+<label for="password">New password</label>
+<input id="password" name="password" type="password" autocomplete="new-password" required>
+<button type="submit">Reset password</button>
+</form>
+<p id="result" role="status"></p>
+<script>
+document.getElementById("reset").addEventListener("submit", function (event) {
+	event.preventDefault();
+	var result = document.getElementById("result");
+	fetch(window.location.pathname, {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({
+			token: new URLSearchParams(window.location.search).get("token") || "",
+			password: document.getElementById("password").value
+		})
+	}).then(function (res) {
+		if (res.status === 204) {
+			document.getElementById("reset").hidden = true;
+			result.textContent = "Your password was reset, you can log in with it now.";
+			return;
+		}
+		return res.json().then(function (body) {
+			var fields = (body.fields || []).map(function (f) { return f.field + " " + f.message; });
+			result.textContent = [body.message].concat(fields).join(". ");
+		});
+	}).catch(function () {
+		result.textContent = "The password could not be reset, please try again.";
+	});
+});
+</script>
+</body>
+</html>
+`
+
+// ResetPasswordForm swagger:operation GET /v1/resetPassword Users ResetPasswordForm
+//
+// The page of the reset password link, a form setting the new password with the token of the link.
+//
+// ---
+// produces:
+// - text/html
+// parameters:
+// - name: token
+//   in: query
+//   required: true
+//   type: string
+// responses:
+//   200:
+//     description: "Ok"
+func (a *UserController) ResetPasswordForm(w http.ResponseWriter, r *http.Request) {
+	// the token is in the url, it is neither cached nor sent to other sites
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; connect-src 'self'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(resetPasswordPage))
+}
+
+// ResetPassword swagger:operation POST /v1/resetPassword Users ResetPassword
+//
+// Set a new password with the token of the reset password link, all sessions of the user are logged out.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/ResetPasswordParams"
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params *user.ResetPasswordParams
+	if !decodeAndValidate(w, r, ".UserController->ResetPassword()", &params) {
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.ResetPassword(ctx, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->ResetPassword()" + err.Path
+		switch errTransaction {
+		case user.ErrTokenInvalid:
+			response.Error(w, err.Message, http.StatusBadRequest, *err)
+		case user.ErrNoInput:
+			response.Error(w, "Token and password are required", http.StatusUnprocessableEntity, *err)
+		default:
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
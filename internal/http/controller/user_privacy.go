@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/audit"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// UserExport is everything kept about a user
+// swagger:model
+type UserExport struct {
+	ExportedAt time.Time               `json:"exportedAt"`
+	Profile    *AdminUserResponse      `json:"profile"`
+	Sessions   []*user.Session         `json:"sessions"`
+	Tokens     []*user.UserToken       `json:"tokens"`
+	Identities []*user.Identity        `json:"identities"`
+	Orders     []*product.OrderHistory `json:"orders"`
+	Activity   []*audit.Log            `json:"activity"`
+	History    []*audit.Log            `json:"history"`
+}
+
+// DeleteUser swagger:operation DELETE /v1/users/{userId} Users DeleteUser
+//
+// Delete user, the users other than the logged-in one are only deleted by the administrators.
+// The sessions of the user are logged out.
+//
+// ---
+// parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := ownerOnly(r, ".UserController->DeleteUser()")
+	if err != nil {
+		message, status := profileErrorStatus(err.Error)
+		if status == http.StatusInternalServerError {
+			message, status = "Bad Request", http.StatusBadRequest
+		}
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.DeleteUser(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->DeleteUser()" + err.Path
+		message, status := profileErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// AnonymizeUser swagger:operation POST /v1/users/{userId}/anonymize Users AnonymizeUser
+//
+// Scrub the personal data of the user and delete it, its orders are kept.
+// The users other than the logged-in one are only anonymized by the administrators.
+//
+// ---
+// parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) AnonymizeUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := ownerOnly(r, ".UserController->AnonymizeUser()")
+	if err != nil {
+		message, status := profileErrorStatus(err.Error)
+		if status == http.StatusInternalServerError {
+			message, status = "Bad Request", http.StatusBadRequest
+		}
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.AnonymizeUser(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->AnonymizeUser()" + err.Path
+		message, status := profileErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// ExportUser swagger:operation GET /v1/users/{userId}/export Users ExportUser
+//
+// Export everything kept about the user, the users other than the logged-in one are only exported by the administrators.
+//
+// ---
+// parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/UserExport"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) ExportUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := ownerOnly(r, ".UserController->ExportUser()")
+	if err != nil {
+		message, status := profileErrorStatus(err.Error)
+		if status == http.StatusInternalServerError {
+			message, status = "Bad Request", http.StatusBadRequest
+		}
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	export, err := a.exportUser(r.Context(), userID)
+	if err != nil {
+		err.Path = ".UserController->ExportUser()" + err.Path
+		message, status := profileErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=user-"+strconv.Itoa(userID)+".json")
+	response.JSON(w, http.StatusOK, export)
+}
+
+func (a *UserController) exportUser(ctx context.Context, userID int) (*UserExport, *types.Error) {
+	userExport, err := a.userService.ExportUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, _, err := a.productService.ListOrders(ctx, &product.FindAllOrderHistorysParams{
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activity, _, err := a.auditService.ListLogs(ctx, &audit.FindAllLogsParams{
+		Actor: strconv.Itoa(userID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history, _, err := a.auditService.ListLogs(ctx, &audit.FindAllLogsParams{
+		Entity:   "user",
+		EntityID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserExport{
+		ExportedAt: time.Now(),
+		Profile:    newAdminUserResponse(userExport.User),
+		Sessions:   userExport.Sessions,
+		Tokens:     userExport.Tokens,
+		Identities: userExport.Identities,
+		Orders:     orders,
+		Activity:   activity,
+		History:    history,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// Register swagger:operation POST /v1/register Users Register
+//
+// Register a new customer, the verification link is sent to the email.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/RegisterParams"
+// responses:
+//   201:
+//     description: "Created"
+//     schema:
+//       $ref: "#/definitions/SelfUserResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) Register(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params *user.RegisterParams
+	if !decodeAndValidate(w, r, ".UserController->Register()", &params) {
+		return
+	}
+
+	var singleUser *user.User
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		singleUser, err = a.userService.Register(ctx, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->Register()" + err.Path
+		switch errTransaction {
+		case user.ErrEmailAlreadyExists:
+			response.Error(w, "Alamat Email Sudah Terdaftar", http.StatusUnprocessableEntity, *err)
+		case user.ErrNoInput:
+			response.Error(w, "Name, email and password are required", http.StatusUnprocessableEntity, *err)
+		default:
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, newSelfUserResponse(singleUser))
+}
+
+// VerifyEmail swagger:operation GET /v1/verifyEmail Users VerifyEmail
+//
+// Verify the email with the token of the verification link.
+//
+// ---
+// parameters:
+// - name: token
+//   in: query
+//   required: true
+//   type: string
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/SelfUserResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var singleUser *user.User
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		singleUser, err = a.userService.VerifyEmail(ctx, r.URL.Query().Get("token"))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->VerifyEmail()" + err.Path
+		if errTransaction == user.ErrTokenInvalid || errTransaction == data.ErrNotFound {
+			response.Error(w, err.Message, http.StatusBadRequest, *err)
+		} else if errTransaction == user.ErrEmailAlreadyExists {
+			response.Error(w, "Alamat Email Sudah Terdaftar", http.StatusUnprocessableEntity, *err)
+		} else {
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newSelfUserResponse(singleUser))
+}
+
+// ResendVerification swagger:operation POST /v1/resendVerification Users ResendVerification
+//
+// Send a new verification link, the response is the same whether the email is registered or not.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/ResendVerificationParams"
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.ResendVerificationParams
+	if !decodeAndValidate(w, r, ".UserController->ResendVerification()", &params) {
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.ResendVerification(ctx, params.Email)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->ResendVerification()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// PublicUserResponse is a user as seen by the callers which may only read users
+// swagger:model
+type PublicUserResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SelfUserResponse is the logged-in user as seen by itself
+// swagger:model
+type SelfUserResponse struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	VerifiedAt *time.Time `json:"verifiedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  *time.Time `json:"updatedAt"`
+}
+
+// AdminUserResponse is a user as seen by the administrators
+// swagger:model
+type AdminUserResponse struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	VerifiedAt   *time.Time `json:"verifiedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	CreatedBy    *string    `json:"createdBy"`
+	UpdatedAt    *time.Time `json:"updatedAt"`
+	UpdatedBy    *string    `json:"updatedBy"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy    *string    `json:"deletedBy,omitempty"`
+	AnonymizedAt *time.Time `json:"anonymizedAt,omitempty"`
+}
+
+// LoginResponse is the session or the tokens of a login with the logged-in user
+// swagger:model
+type LoginResponse struct {
+	SessionID        string     `json:"sessionId,omitempty"`
+	SessionExpiredAt *time.Time `json:"sessionExpiredAt,omitempty"`
+	// CSRFToken is sent back in the X-CSRF-Token header by the browser clients authenticated by the session cookie
+	CSRFToken            string            `json:"csrfToken,omitempty"`
+	AccessToken          string            `json:"accessToken,omitempty"`
+	AccessTokenExpiredAt *time.Time        `json:"accessTokenExpiredAt,omitempty"`
+	RefreshToken         string            `json:"refreshToken,omitempty"`
+	User                 *SelfUserResponse `json:"user,omitempty"`
+	// SecondFactor is "totp" or "totp-enroll" when the login has to be completed at /v1/login/mfa
+	SecondFactor       string     `json:"secondFactor,omitempty"`
+	ChallengeToken     string     `json:"challengeToken,omitempty"`
+	ChallengeExpiredAt *time.Time `json:"challengeExpiredAt,omitempty"`
+	RecoveryCodes      []string   `json:"recoveryCodes,omitempty"`
+}
+
+func newPublicUserResponse(u *user.User) *PublicUserResponse {
+	return &PublicUserResponse{
+		ID:        u.ID,
+		Name:      u.Name,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func newSelfUserResponse(u *user.User) *SelfUserResponse {
+	return &SelfUserResponse{
+		ID:         u.ID,
+		Name:       u.Name,
+		Email:      u.Email,
+		Role:       u.Role,
+		VerifiedAt: u.VerifiedAt,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+func newAdminUserResponse(u *user.User) *AdminUserResponse {
+	return &AdminUserResponse{
+		ID:           u.ID,
+		Name:         u.Name,
+		Email:        u.Email,
+		Role:         u.Role,
+		VerifiedAt:   u.VerifiedAt,
+		CreatedAt:    u.CreatedAt,
+		CreatedBy:    u.CreatedBy,
+		UpdatedAt:    u.UpdatedAt,
+		UpdatedBy:    u.UpdatedBy,
+		DeletedAt:    u.DeletedAt,
+		DeletedBy:    u.DeletedBy,
+		AnonymizedAt: u.AnonymizedAt,
+	}
+}
+
+func newAdminUserResponses(users []*user.User) []*AdminUserResponse {
+	responses := make([]*AdminUserResponse, 0, len(users))
+	for _, u := range users {
+		responses = append(responses, newAdminUserResponse(u))
+	}
+	return responses
+}
+
+// newUserResponses maps the listed users to the admin view for the callers which can write users
+// and to the public view for the others, api keys are never granted to write users
+func newUserResponses(ctx context.Context, users []*user.User) interface{} {
+	if appcontext.ClientID(ctx) == nil && user.HasPermission(appcontext.UserRole(ctx), user.PermissionUserWrite) {
+		return newAdminUserResponses(users)
+	}
+
+	responses := make([]*PublicUserResponse, 0, len(users))
+	for _, u := range users {
+		responses = append(responses, newPublicUserResponse(u))
+	}
+	return responses
+}
+
+// sessionResponse sets the session cookies for the browser clients when a session was opened and the X-Session-Expires header,
+// and returns the login response with the csrf token of the session
+func (a *UserController) sessionResponse(w http.ResponseWriter, sess *user.LoginResponse) *LoginResponse {
+	loginResponse := newLoginResponse(sess)
+	if sess.SessionID != "" && sess.SessionExpiredAt != nil {
+		a.cookies.SetSession(w, sess.SessionID, *sess.SessionExpiredAt)
+		loginResponse.CSRFToken = cookie.CSRFToken(sess.SessionID)
+		response.SessionExpires(w, *sess.SessionExpiredAt)
+	} else if sess.AccessTokenExpiredAt != nil {
+		response.SessionExpires(w, *sess.AccessTokenExpiredAt)
+	}
+	return loginResponse
+}
+
+func newLoginResponse(sess *user.LoginResponse) *LoginResponse {
+	response := &LoginResponse{
+		SessionID:            sess.SessionID,
+		SessionExpiredAt:     sess.SessionExpiredAt,
+		AccessToken:          sess.AccessToken,
+		AccessTokenExpiredAt: sess.AccessTokenExpiredAt,
+		RefreshToken:         sess.RefreshToken,
+		SecondFactor:         sess.SecondFactor,
+		ChallengeToken:       sess.ChallengeToken,
+		ChallengeExpiredAt:   sess.ChallengeExpiredAt,
+		RecoveryCodes:        sess.RecoveryCodes,
+	}
+	if sess.User != nil {
+		response.User = newSelfUserResponse(sess.User)
+	}
+	return response
+}
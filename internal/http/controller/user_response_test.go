@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jmoiron/sqlx"
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/audit"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// the secrets kept by the stubbed services, none of them may reach a response
+const (
+	passwordHash = "$2a$10$passwordhashpasswordhashpasswordhash"
+	tokenHash    = "0123456789abcdef0123456789abcdef-tokenhash"
+)
+
+// sensitiveKeys are the json keys of the secrets
+var sensitiveKeys = map[string]bool{
+	"password":  true,
+	"tokenHash": true,
+	"token":     true,
+	"secret":    true,
+	"codeHash":  true,
+}
+
+// txDriver is a database driver whose transactions do nothing,
+// the stubbed services do not query the database
+type txDriver struct{}
+
+type txConn struct{}
+
+func (txDriver) Open(name string) (driver.Conn, error) { return txConn{}, nil }
+
+func (txConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("queries are not supported")
+}
+func (txConn) Close() error              { return nil }
+func (txConn) Begin() (driver.Tx, error) { return txConn{}, nil }
+func (txConn) Commit() error             { return nil }
+func (txConn) Rollback() error           { return nil }
+
+func init() {
+	sql.Register("controllertest", txDriver{})
+}
+
+func testUser() *user.User {
+	now := time.Now()
+	createdBy := "1"
+	return &user.User{
+		ID:         7,
+		Name:       "Jane",
+		Email:      "jane@example.com",
+		Role:       user.RoleCustomer,
+		Password:   passwordHash,
+		VerifiedAt: &now,
+		CreatedAt:  now,
+		CreatedBy:  &createdBy,
+		UpdatedAt:  &now,
+	}
+}
+
+// stubUserService answers with users carrying every secret,
+// the methods the tests do not call are left to the nil interface
+type stubUserService struct {
+	user.ServiceInterface
+}
+
+func (s *stubUserService) Login(ctx context.Context, email string, password string, client *user.ClientInfo) (*user.LoginResponse, *types.Error) {
+	expiredAt := time.Now().Add(time.Hour)
+	return &user.LoginResponse{
+		SessionID:        "session-token",
+		SessionExpiredAt: &expiredAt,
+		User:             testUser(),
+	}, nil
+}
+
+func (s *stubUserService) ListUsers(ctx context.Context, params *user.FindAllUsersParams) ([]*user.User, int, *types.Error) {
+	return []*user.User{testUser(), testUser()}, 2, nil
+}
+
+func (s *stubUserService) ListUsersByCursor(ctx context.Context, params *user.FindAllUsersParams) ([]*user.User, *data.CursorPage, *types.Error) {
+	return []*user.User{testUser()}, &data.CursorPage{Next: "next"}, nil
+}
+
+func (s *stubUserService) ListDeletedUsers(ctx context.Context, params *user.FindAllUsersParams) ([]*user.User, int, *types.Error) {
+	deleted := testUser()
+	now := time.Now()
+	deleted.DeletedAt = &now
+	return []*user.User{deleted}, 1, nil
+}
+
+func (s *stubUserService) GetUser(ctx context.Context, userID int) (*user.User, *types.Error) {
+	return testUser(), nil
+}
+
+func (s *stubUserService) Register(ctx context.Context, params *user.RegisterParams) (*user.User, *types.Error) {
+	registered := testUser()
+	registered.VerifiedAt = nil
+	return registered, nil
+}
+
+func (s *stubUserService) ExportUser(ctx context.Context, userID int) (*user.Export, *types.Error) {
+	now := time.Now()
+	return &user.Export{
+		User: testUser(),
+		Sessions: []*user.Session{{
+			ID:        1,
+			UserID:    7,
+			TokenHash: tokenHash,
+			ExpiredAt: now,
+			CreatedAt: now,
+		}},
+		Tokens: []*user.UserToken{{
+			ID:        1,
+			UserID:    7,
+			Purpose:   user.TokenResetPassword,
+			TokenHash: tokenHash,
+			ExpiredAt: now,
+			CreatedAt: now,
+		}},
+		Identities: []*user.Identity{{
+			ID:        1,
+			UserID:    7,
+			Issuer:    "https://accounts.example.com",
+			Subject:   "jane",
+			CreatedAt: now,
+		}},
+	}, nil
+}
+
+type stubProductService struct {
+	product.ServiceInterface
+}
+
+func (s *stubProductService) ListOrders(ctx context.Context, params *product.FindAllOrderHistorysParams) ([]*product.OrderHistory, int, *types.Error) {
+	return []*product.OrderHistory{}, 0, nil
+}
+
+type stubAuditService struct {
+	audit.ServiceInterface
+}
+
+// the audit log keeps the changed columns by name, the secrets among them are redacted
+func (s *stubAuditService) ListLogs(ctx context.Context, params *audit.FindAllLogsParams) ([]*audit.Log, int, *types.Error) {
+	return []*audit.Log{{
+		ID:       1,
+		Entity:   "user",
+		EntityID: 7,
+		Action:   "update",
+		Actor:    "7",
+		Changes:  json.RawMessage(`{"password":{"from":"[redacted]","to":"[redacted]"},"role":{"from":"customer","to":"staff"}}`),
+	}}, 1, nil
+}
+
+func newTestRouter(t *testing.T) http.Handler {
+	db, err := sqlx.Open("controllertest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	a := NewUserController(&stubUserService{}, &stubProductService{}, &stubAuditService{},
+		data.NewManager(db), cookie.NewPolicy(true, "lax", ""))
+
+	r := chi.NewRouter()
+	r.Post("/v1/login", a.Login)
+	r.Post("/v1/register", a.Register)
+	r.Get("/v1/me", a.GetMe)
+	r.Get("/v1/users", a.ListUser)
+	r.Get("/v1/users/trash", a.ListDeletedUser)
+	r.Get("/v1/users/{userId}", a.GetUser)
+	r.Get("/v1/users/{userId}/export", a.ExportUser)
+	r.Get("/v1/resetPassword", a.ResetPasswordForm)
+	r.Get("/v1/oidc/login", a.StartOIDCLogin)
+	r.Get("/v1/oidc/callback", a.CompleteOIDCLogin)
+	return r
+}
+
+// assertNoSecrets fails when a sensitive key or a secret value is in the response body.
+// Sensitive keys are only allowed as redacted audit changes.
+func assertNoSecrets(t *testing.T, body []byte) {
+	t.Helper()
+
+	for _, secret := range []string{passwordHash, tokenHash} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("response contains the secret %q: %s", secret, body)
+		}
+	}
+
+	var decoded interface{}
+	err := json.Unmarshal(body, &decoded)
+	if err != nil {
+		t.Fatalf("response is not json: %v: %s", err, body)
+	}
+
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if sensitiveKeys[key] && !isRedactedChange(value) {
+					t.Errorf("response has the sensitive key %s.%s: %s", path, key, body)
+				}
+				walk(path+"."+key, value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(path+"[]", value)
+			}
+		}
+	}
+	walk("", decoded)
+}
+
+func isRedactedChange(v interface{}) bool {
+	change, ok := v.(map[string]interface{})
+	return ok && len(change) == 2 && change["from"] == "[redacted]" && change["to"] == "[redacted]"
+}
+
+func TestUserResponsesHaveNoSecrets(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		role   string
+		status int
+	}{
+		{"login", http.MethodPost, "/v1/login", `{"email":"jane@example.com","password":"secret"}`, "", http.StatusOK},
+		{"register", http.MethodPost, "/v1/register", `{"name":"Jane","email":"jane@example.com","password":"Sup3r-Secret-Passw0rd"}`, "", http.StatusCreated},
+		{"me", http.MethodGet, "/v1/me", "", user.RoleCustomer, http.StatusOK},
+		{"own user", http.MethodGet, "/v1/users/7", "", user.RoleCustomer, http.StatusOK},
+		{"user as admin", http.MethodGet, "/v1/users/7", "", user.RoleAdmin, http.StatusOK},
+		{"list as customer", http.MethodGet, "/v1/users", "", user.RoleCustomer, http.StatusOK},
+		{"list as admin", http.MethodGet, "/v1/users", "", user.RoleAdmin, http.StatusOK},
+		{"list by cursor", http.MethodGet, "/v1/users?cursor=", "", user.RoleAdmin, http.StatusOK},
+		{"trash", http.MethodGet, "/v1/users/trash", "", user.RoleAdmin, http.StatusOK},
+		{"export", http.MethodGet, "/v1/users/7/export", "", user.RoleCustomer, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.role != "" {
+				ctx := context.WithValue(req.Context(), appcontext.KeyUserID, 7)
+				ctx = context.WithValue(ctx, appcontext.KeyUserRole, tt.role)
+				req = req.WithContext(ctx)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			assertNoSecrets(t, rec.Body.Bytes())
+		})
+	}
+}
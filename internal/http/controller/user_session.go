@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// SessionList session list
+type SessionList struct {
+	Data []*user.Session `json:"data"`
+}
+
+// clientIP returns the ip of the caller, RealIP already replaced the remote address behind a proxy
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ListSession Function for listing the sessions of the current user
+func (a *UserController) ListSession(w http.ResponseWriter, r *http.Request) {
+	sessions, err := a.userService.ListSessions(r.Context(), appcontext.UserID(r.Context()))
+	if err != nil {
+		err.Path = ".UserController->ListSession()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, SessionList{
+		Data: sessions,
+	})
+}
+
+// RevokeSession Function for logging the current user out of one of its sessions
+func (a *UserController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	sessionID, errConversion := strconv.Atoi(chi.URLParam(r, "sessionId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->RevokeSession()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.RevokeSession(ctx, appcontext.UserID(ctx), sessionID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->RevokeSession()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(w, "Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// LogoutAll swagger:operation POST /v1/logout/all Users LogoutAll
+//
+// Logout the current user from all of its sessions.
+//
+// ---
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.LogoutAll(ctx, appcontext.UserID(ctx))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->LogoutAll()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	a.cookies.ClearSession(w)
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// RefreshToken swagger:operation POST /v1/token/refresh Users RefreshToken
+//
+// Rotate the refresh token and issue a new access token.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/RefreshTokenParams"
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/LoginResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.RefreshTokenParams
+	if !decodeAndValidate(w, r, ".UserController->RefreshToken()", &params) {
+		return
+	}
+
+	var sess *user.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		sess, err = a.userService.RefreshToken(ctx, params.RefreshToken)
+		if err != nil {
+			if err.Error == user.ErrRefreshTokenReused {
+				// commit the revocation of the session
+				return nil
+			}
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction == nil && err != nil {
+		errTransaction = err.Error
+	}
+	if errTransaction != nil {
+		err.Path = ".UserController->RefreshToken()" + err.Path
+		switch errTransaction {
+		case user.ErrRefreshTokenInvalid, user.ErrRefreshTokenReused, user.ErrSessionExpired, data.ErrNotFound:
+			response.Error(w, "Unauthorized", http.StatusUnauthorized, *err)
+		case user.ErrAccessTokensDisabled:
+			response.Error(w, err.Message, http.StatusBadRequest, *err)
+		default:
+			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, a.sessionResponse(w, sess))
+}
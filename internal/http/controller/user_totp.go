@@ -0,0 +1,391 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// RecoveryCodesResponse lists the recovery codes, they are shown only once
+// swagger:model
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func totpErrorStatus(err error) (string, int) {
+	switch err {
+	case user.ErrTOTPInvalid, user.ErrTokenInvalid:
+		return err.Error(), http.StatusBadRequest
+	case user.ErrTOTPNotEnabled, user.ErrTOTPNotEnrolled, user.ErrTOTPAlreadyEnabled:
+		return err.Error(), http.StatusConflict
+	case user.ErrTOTPRequired:
+		return err.Error(), http.StatusForbidden
+	case user.ErrLoginLocked:
+		return err.Error(), http.StatusTooManyRequests
+	case data.ErrNotFound:
+		return "User not found", http.StatusNotFound
+	}
+	return "Internal Server Error", http.StatusInternalServerError
+}
+
+// CompleteLogin swagger:operation POST /v1/login/mfa Users CompleteLogin
+//
+// Complete a login challenged for the second factor with a TOTP code or a recovery code.
+// The recovery codes are returned once when the TOTP was enrolled during the login.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/CompleteLoginParams"
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/LoginResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) CompleteLogin(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.CompleteLoginParams
+	if !decodeAndValidate(w, r, ".UserController->CompleteLogin()", &params) {
+		return
+	}
+
+	var sess *user.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		sess, err = a.userService.CompleteLogin(ctx, &params, &user.ClientInfo{
+			UserAgent: r.UserAgent(),
+			IP:        clientIP(r),
+		})
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->CompleteLogin()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		if err.Error == user.ErrLoginLocked {
+			message = err.Message
+		}
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, a.sessionResponse(w, sess))
+}
+
+// EnrollTOTPForLogin swagger:operation POST /v1/login/totp/enroll Users EnrollTOTPForLogin
+//
+// Enroll the TOTP during a login challenged with "totp-enroll", the login is completed with a first code.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/LoginChallengeParams"
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/TOTPEnrollment"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+func (a *UserController) EnrollTOTPForLogin(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.LoginChallengeParams
+	if !decodeAndValidate(w, r, ".UserController->EnrollTOTPForLogin()", &params) {
+		return
+	}
+
+	var enrollment *user.TOTPEnrollment
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		enrollment, err = a.userService.EnrollTOTPForLogin(ctx, params.ChallengeToken)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->EnrollTOTPForLogin()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, enrollment)
+}
+
+// GetTOTP swagger:operation GET /v1/me/totp Users GetTOTP
+//
+// Get the two-factor authentication state of the logged-in user.
+//
+// ---
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/TOTPStatus"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) GetTOTP(w http.ResponseWriter, r *http.Request) {
+	status, err := a.userService.GetTOTPStatus(r.Context(), appcontext.UserID(r.Context()))
+	if err != nil {
+		err.Path = ".UserController->GetTOTP()" + err.Path
+		message, code := totpErrorStatus(err.Error)
+		response.Error(w, message, code, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, status)
+}
+
+// EnrollTOTP swagger:operation POST /v1/me/totp Users EnrollTOTP
+//
+// Generate a new TOTP secret for the logged-in user, it is enabled once a first code is verified.
+//
+// ---
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/TOTPEnrollment"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var enrollment *user.TOTPEnrollment
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		enrollment, err = a.userService.EnrollTOTP(ctx, appcontext.UserID(ctx))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->EnrollTOTP()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, enrollment)
+}
+
+// ActivateTOTP swagger:operation POST /v1/me/totp/verify Users ActivateTOTP
+//
+// Enable the enrolled TOTP with a first code, the recovery codes are returned once.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/ActivateTOTPParams"
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/RecoveryCodesResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) ActivateTOTP(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.ActivateTOTPParams
+	if !decodeAndValidate(w, r, ".UserController->ActivateTOTP()", &params) {
+		return
+	}
+
+	var recoveryCodes []string
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		recoveryCodes, err = a.userService.ActivateTOTP(ctx, appcontext.UserID(ctx), params.Code)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->ActivateTOTP()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, &RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// DisableTOTP swagger:operation DELETE /v1/me/totp Users DisableTOTP
+//
+// Disable the TOTP of the logged-in user with a code or a recovery code, unless the role requires it.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/SecondFactorParams"
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.SecondFactorParams
+	if !decodeAndValidate(w, r, ".UserController->DisableTOTP()", &params) {
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.DisableTOTP(ctx, appcontext.UserID(ctx), &params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->DisableTOTP()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// RegenerateRecoveryCodes swagger:operation POST /v1/me/totp/recoveryCodes Users RegenerateRecoveryCodes
+//
+// Replace the recovery codes of the logged-in user, the new codes are returned once.
+//
+// ---
+// parameters:
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: "#/definitions/SecondFactorParams"
+// responses:
+//   200:
+//     description: "Ok"
+//     schema:
+//       $ref: "#/definitions/RecoveryCodesResponse"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+
+	var params user.SecondFactorParams
+	if !decodeAndValidate(w, r, ".UserController->RegenerateRecoveryCodes()", &params) {
+		return
+	}
+
+	var recoveryCodes []string
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		recoveryCodes, err = a.userService.RegenerateRecoveryCodes(ctx, appcontext.UserID(ctx), &params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->RegenerateRecoveryCodes()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, &RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// ResetTOTP swagger:operation DELETE /v1/users/{userId}/totp Users ResetTOTP
+//
+// Remove the TOTP of a user who lost the authenticator and the recovery codes.
+//
+// ---
+// parameters:
+// - name: userId
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   204:
+//     description: "No Content"
+//   default:
+//     description: "Error"
+//     schema:
+//       $ref: "#/definitions/ErrorResponse"
+// security:
+//   - bearerAuth: []
+func (a *UserController) ResetTOTP(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	userID, errConversion := strconv.Atoi(chi.URLParam(r, "userId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->ResetTOTP()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.ResetTOTP(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->ResetTOTP()" + err.Path
+		message, status := totpErrorStatus(err.Error)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
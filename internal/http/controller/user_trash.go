@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// ListDeletedUser Function for listing soft deleted users
+func (a *UserController) ListDeletedUser(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := pageParams(r, ".UserController->ListDeletedUser()")
+	if err != nil {
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	userList, count, err := a.userService.ListDeletedUsers(r.Context(), &user.FindAllUsersParams{
+		Limit:  limit,
+		Page:   page,
+		Search: r.URL.Query().Get("search"),
+	})
+	if err != nil {
+		err.Path = ".UserController->ListDeletedUser()" + err.Path
+		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, UserList{
+		Data:  newAdminUserResponses(userList),
+		Count: count,
+	})
+}
+
+// RestoreUser Function for restoring a soft deleted user
+func (a *UserController) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	userID, errConversion := strconv.Atoi(chi.URLParam(r, "userId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->RestoreUser()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var singleUser *user.User
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		singleUser, err = a.userService.RestoreUser(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->RestoreUser()" + err.Path
+		message, status := trashErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newAdminUserResponse(singleUser))
+}
+
+// PurgeUser Function for permanently deleting a soft deleted user
+func (a *UserController) PurgeUser(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	userID, errConversion := strconv.Atoi(chi.URLParam(r, "userId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->PurgeUser()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    "golang-error",
+		}
+		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
+		err = a.userService.PurgeUser(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->PurgeUser()" + err.Path
+		message, status := trashErrorStatus(errTransaction)
+		response.Error(w, message, status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,136 @@
+package cookie
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Names of the cookies and of the header the csrf token is sent back in
+const (
+	SessionName   = "sessionId"
+	CSRFName      = "csrfToken"
+	CSRFHeader    = "X-CSRF-Token"
+	OIDCStateName = "oidcState"
+)
+
+// oidcStatePath is where the oidc state cookie is sent, the login and the callback of the identity provider
+const oidcStatePath = "/v1/oidc"
+
+// Policy is how the session cookies are set for the browser clients
+type Policy struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+// SetSession sets the session cookie, unreadable by scripts, along with the csrf cookie the scripts echo
+// in the X-CSRF-Token header. Both expire with the session.
+func (p *Policy) SetSession(w http.ResponseWriter, sessionToken string, expiredAt time.Time) {
+	http.SetCookie(w, p.cookie(SessionName, sessionToken, expiredAt, true))
+	http.SetCookie(w, p.cookie(CSRFName, CSRFToken(sessionToken), expiredAt, false))
+}
+
+// ClearSession removes the session and the csrf cookies
+func (p *Policy) ClearSession(w http.ResponseWriter) {
+	for _, name := range []string{SessionName, CSRFName} {
+		c := p.cookie(name, "", time.Unix(0, 0), name == SessionName)
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+	}
+}
+
+// SetOIDCState keeps the state of the login started at the identity provider in the browser until
+// the provider redirects back. The cookie is SameSite=Lax whatever the policy, the redirect of the
+// provider is a navigation from another site.
+func (p *Policy) SetOIDCState(w http.ResponseWriter, state string, expiredAt time.Time) {
+	http.SetCookie(w, p.oidcStateCookie(state, expiredAt))
+}
+
+// ClearOIDCState removes the oidc state cookie
+func (p *Policy) ClearOIDCState(w http.ResponseWriter) {
+	c := p.oidcStateCookie("", time.Unix(0, 0))
+	c.MaxAge = -1
+	http.SetCookie(w, c)
+}
+
+func (p *Policy) oidcStateCookie(state string, expiredAt time.Time) *http.Cookie {
+	c := p.cookie(OIDCStateName, state, expiredAt, true)
+	c.Path = oidcStatePath
+	c.SameSite = http.SameSiteLaxMode
+	return c
+}
+
+func (p *Policy) cookie(name string, value string, expiredAt time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   p.Domain,
+		Expires:  expiredAt,
+		Secure:   p.Secure,
+		HttpOnly: httpOnly,
+		SameSite: p.SameSite,
+	}
+}
+
+// SessionToken returns the session token of the cookie, empty without cookie
+func SessionToken(r *http.Request) string {
+	c, err := r.Cookie(SessionName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// OIDCState returns the state of the oidc state cookie, empty without cookie
+func OIDCState(r *http.Request) string {
+	c, err := r.Cookie(OIDCStateName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// CSRFToken derives the csrf token from the session token. A csrf cookie planted by another site
+// does not match the session, and the token does not reveal the session token.
+func CSRFToken(sessionToken string) string {
+	sum := sha256.Sum256([]byte("csrf:" + sessionToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidCSRF tells whether the X-CSRF-Token header holds the csrf token of the session
+func ValidCSRF(r *http.Request, sessionToken string) bool {
+	header := r.Header.Get(CSRFHeader)
+	return header != "" && subtle.ConstantTimeCompare([]byte(header), []byte(CSRFToken(sessionToken))) == 1
+}
+
+// SafeMethod tells whether the method does not change anything, such requests need no csrf token
+func SafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// NewPolicy creates a new cookie policy, sameSite is "strict", "lax" or "none".
+// SameSite=None cookies are only accepted by the browsers when secure.
+func NewPolicy(secure bool, sameSite string, domain string) *Policy {
+	policy := &Policy{
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+		Domain:   domain,
+	}
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		policy.SameSite = http.SameSiteStrictMode
+	case "none":
+		policy.SameSite = http.SameSiteNoneMode
+		policy.Secure = true
+	}
+	return policy
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/http/response"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// ErrForbidden is returned when the role of the current user is not granted the permission of the route
+var ErrForbidden = errors.New("forbidden")
+
+// permitted tells whether the user role or the api key of the client is granted the permission
+func permitted(ctx context.Context, permission string) bool {
+	if appcontext.ClientID(ctx) != nil {
+		// routes without permission act on the current user, which a client does not have
+		if permission == "" {
+			return false
+		}
+		for _, granted := range appcontext.CurrentClientAccess(ctx) {
+			if granted == permission {
+				return true
+			}
+		}
+		return false
+	}
+
+	return permission == "" || user.HasPermission(appcontext.UserRole(ctx), permission)
+}
+
+// permittedOnly lets through the users whose role is granted the permission and the clients whose api key is,
+// it runs after authorizedOnly which puts the role or the api key permissions into the context.
+// An empty permission lets through every logged-in user.
+func (hs *Server) permittedOnly(permission string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if !permitted(r.Context(), permission) {
+				response.Error(w, "Forbidden", http.StatusForbidden, types.Error{
+					Path:    ".Server->permittedOnly()",
+					Message: "missing permission " + permission,
+					Error:   ErrForbidden,
+					Type:    "validation-error",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
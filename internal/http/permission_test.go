@@ -0,0 +1,43 @@
+package http
+
+import (
+	"context"
+	"testing"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+func TestPermitted(t *testing.T) {
+	userContext := func(role string) context.Context {
+		ctx := context.WithValue(context.Background(), appcontext.KeyUserID, 7)
+		return context.WithValue(ctx, appcontext.KeyUserRole, role)
+	}
+	clientContext := func(permissions ...string) context.Context {
+		ctx := context.WithValue(context.Background(), appcontext.KeyClientID, 3)
+		return context.WithValue(ctx, appcontext.KeyCurrentClientAccess, permissions)
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		permission string
+		want       bool
+	}{
+		{"admin", userContext(user.RoleAdmin), user.PermissionAPIKeyManage, true},
+		{"staff granted", userContext(user.RoleStaff), user.PermissionProductWrite, true},
+		{"customer not granted", userContext(user.RoleCustomer), user.PermissionProductWrite, false},
+		{"user without permission", userContext(user.RoleCustomer), "", true},
+		{"api key granted", clientContext(user.PermissionProductRead, user.PermissionProductWrite), user.PermissionProductWrite, true},
+		{"api key not granted", clientContext(user.PermissionProductRead), user.PermissionProductWrite, false},
+		{"api key without permissions", clientContext(), user.PermissionProductRead, false},
+		{"api key on a route of the current user", clientContext(user.PermissionProductRead), "", false},
+		// the role of a user is not looked at for a client
+		{"api key with a role", context.WithValue(clientContext(), appcontext.KeyUserRole, user.RoleAdmin), user.PermissionProductRead, false},
+	}
+	for _, tt := range tests {
+		if got := permitted(tt.ctx, tt.permission); got != tt.want {
+			t.Errorf("%s: permitted(%q) = %v, want %v", tt.name, tt.permission, got, tt.want)
+		}
+	}
+}
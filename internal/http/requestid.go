@@ -0,0 +1,20 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/riskiramdan/evermos/internal/appcontext"
+)
+
+// requestID copies the id given by middleware.RequestID into the app context,
+// so the layers below http can refer to the request (e.g. in the audit log)
+func requestID(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), appcontext.KeyRequestID, middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/validation"
 	validator "gopkg.in/go-playground/validator.v9"
 
 	"github.com/pkg/errors"
@@ -56,11 +57,11 @@ func Error(w http.ResponseWriter, data string, status int, err types.Error) {
 
 	switch err.Error.(type) {
 	case validator.ValidationErrors:
-		data = "Bad Request"
+		data = "Validation error"
 		for _, err := range err.Error.(validator.ValidationErrors) {
 			e := MakeFieldError(
 				err.Field(),
-				err.ActualTag())
+				validation.Message(err))
 
 			errorFields = append(errorFields, e)
 		}
@@ -1,17 +1,35 @@
 package response
 
 import (
-	"fmt"
+	"bytes"
 	"net/http"
-	"strings"
+	"strconv"
+
+	"github.com/riskiramdan/evermos/internal/spreadsheet"
 )
 
 // EXCEL writes excel http response
-func EXCEL(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Disposition", "attachment; filename="+"Template-Upload.xlsx")
-	w.Header().Set("Content-Transfer-Encoding", "binary")
+func EXCEL(w http.ResponseWriter, status int, filename string, rows [][]string) {
+	spreadsheetFile(w, status, filename, spreadsheet.FormatXLSX, spreadsheet.ContentTypeXLSX, rows)
+}
+
+// CSV writes csv http response
+func CSV(w http.ResponseWriter, status int, filename string, rows [][]string) {
+	spreadsheetFile(w, status, filename, spreadsheet.FormatCSV, spreadsheet.ContentTypeCSV, rows)
+}
+
+func spreadsheetFile(w http.ResponseWriter, status int, filename string, format string, contentType string, rows [][]string) {
+	buff := &bytes.Buffer{}
+	err := spreadsheet.Write(buff, format, "Sheet1", rows)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(filename))
+	w.Header().Set("Content-Length", strconv.Itoa(buff.Len()))
 	w.Header().Set("Expires", "0")
-	writer := strings.NewReader(string([]byte(fmt.Sprintf("%s", data))))
-	writer.WriteTo(w)
+	w.WriteHeader(status)
+	buff.WriteTo(w)
 }
@@ -0,0 +1,14 @@
+package response
+
+import (
+	"net/http"
+	"time"
+)
+
+// SessionExpiresHeader tells the clients when their session or access token expires so they can refresh it in time
+const SessionExpiresHeader = "X-Session-Expires"
+
+// SessionExpires sets the expiry of the session or the access token of the request
+func SessionExpires(w http.ResponseWriter, expiredAt time.Time) {
+	w.Header().Set(SessionExpiresHeader, expiredAt.UTC().Format(time.RFC3339))
+}
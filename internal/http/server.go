@@ -11,25 +11,38 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/riskiramdan/evermos/config"
+	"github.com/riskiramdan/evermos/internal/apikey"
+	"github.com/riskiramdan/evermos/internal/audit"
 	"github.com/riskiramdan/evermos/internal/data"
 	"github.com/riskiramdan/evermos/internal/http/controller"
+	"github.com/riskiramdan/evermos/internal/http/cookie"
+	"github.com/riskiramdan/evermos/internal/http/response"
 	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/token"
 	"github.com/riskiramdan/evermos/internal/user"
 	"github.com/rs/cors"
 )
 
 // Server represents the http server that handles the requests
 type Server struct {
+	config            *config.Config
 	dataManager       *data.Manager
 	userService       user.ServiceInterface
 	userController    *controller.UserController
 	productService    product.ServiceInterface
 	productController *controller.ProductController
+	auditController   *controller.AuditController
+	apiKeyService     apikey.ServiceInterface
+	apiKeyController  *controller.APIKeyController
+	accessTokens      *token.Manager
+	cookies           *cookie.Policy
 }
 
-func (hs *Server) authMethod(r chi.Router, method string, path string, handler http.HandlerFunc) {
+// authMethod registers the route for the logged-in users whose role is granted the permission
+func (hs *Server) authMethod(r chi.Router, method string, path string, permission string, handler http.HandlerFunc) {
 	r.With(
 		hs.instrument(method, "/v1"+path),
+		hs.permittedOnly(permission),
 	).Method(method, path, handler)
 }
 
@@ -40,6 +53,7 @@ func (hs *Server) compileRouter() chi.Router {
 	//
 
 	r.Use(middleware.RequestID)
+	r.Use(requestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
 
@@ -52,12 +66,11 @@ func (hs *Server) compileRouter() chi.Router {
 	//Routes()
 	// for more ideas, see: https://developer.github.com/v3/#cross-origin-resource-sharing
 	cors := cors.New(cors.Options{
-		// AllowedOrigins: []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"*"},
+		AllowedOrigins: hs.config.CORSAllowedOrigins,
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Access-Token", "X-Requested-With"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", response.SessionExpiresHeader},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	})
@@ -70,26 +83,85 @@ func (hs *Server) compileRouter() chi.Router {
 
 	// Add routes
 
+	// Uploaded images are served from the image path when kept on the local filesystem
+	if hs.config.BlobDriver == "local" {
+		r.Handle("/images/*", http.StripPrefix("/images/", http.FileServer(http.Dir(hs.config.ImagePath))))
+	}
+
 	r.HandleFunc("/v1/login", hs.userController.Login)
-	// r.HandleFunc("/v1/forgotPassword", hs.userController.RequestResetPassword)
-	// r.HandleFunc("/v1/resetPassword", hs.userController.ResetPassword)
+	r.Post("/v1/login/mfa", hs.userController.CompleteLogin)
+	r.Post("/v1/login/totp/enroll", hs.userController.EnrollTOTPForLogin)
+	if hs.config.OIDCIssuer != "" {
+		r.Get("/v1/oidc/login", hs.userController.StartOIDCLogin)
+		r.Get("/v1/oidc/callback", hs.userController.CompleteOIDCLogin)
+	}
+	r.Post("/v1/token/refresh", hs.userController.RefreshToken)
+	r.Post("/v1/register", hs.userController.Register)
+	r.Get("/v1/verifyEmail", hs.userController.VerifyEmail)
+	r.Post("/v1/resendVerification", hs.userController.ResendVerification)
+	r.Post("/v1/forgotPassword", hs.userController.RequestResetPassword)
+	r.Get("/v1/resetPassword", hs.userController.ResetPasswordForm)
+	r.Post("/v1/resetPassword", hs.userController.ResetPassword)
 
 	r.Route("/v1", func(r chi.Router) {
 		r.Use(hs.authorizedOnly(hs.userService))
 
-		hs.authMethod(r, "GET", "/logout", hs.userController.Logout)
-		hs.authMethod(r, "PUT", "/users/changePassword", hs.userController.ChangePassword)
-		hs.authMethod(r, "PUT", "/users/{userId}", hs.userController.UpdateUser)
-		hs.authMethod(r, "GET", "/users", hs.userController.ListUser)
-		hs.authMethod(r, "POST", "/users", hs.userController.CreateUser)
+		hs.authMethod(r, "POST", "/logout", "", hs.userController.Logout)
+		hs.authMethod(r, "POST", "/logout/all", "", hs.userController.LogoutAll)
+		hs.authMethod(r, "GET", "/sessions", "", hs.userController.ListSession)
+		hs.authMethod(r, "DELETE", "/sessions/{sessionId}", "", hs.userController.RevokeSession)
+		hs.authMethod(r, "PUT", "/users/changePassword", "", hs.userController.ChangePassword)
+		hs.authMethod(r, "GET", "/me", "", hs.userController.GetMe)
+		hs.authMethod(r, "PUT", "/me", "", hs.userController.UpdateMe)
+		hs.authMethod(r, "GET", "/me/totp", "", hs.userController.GetTOTP)
+		hs.authMethod(r, "POST", "/me/totp", "", hs.userController.EnrollTOTP)
+		hs.authMethod(r, "DELETE", "/me/totp", "", hs.userController.DisableTOTP)
+		hs.authMethod(r, "POST", "/me/totp/verify", "", hs.userController.ActivateTOTP)
+		hs.authMethod(r, "POST", "/me/totp/recoveryCodes", "", hs.userController.RegenerateRecoveryCodes)
+		hs.authMethod(r, "GET", "/users/{userId}", "", hs.userController.GetUser)
+		hs.authMethod(r, "PUT", "/users/{userId}", "", hs.userController.UpdateUser)
+		hs.authMethod(r, "DELETE", "/users/{userId}", "", hs.userController.DeleteUser)
+		hs.authMethod(r, "POST", "/users/{userId}/anonymize", "", hs.userController.AnonymizeUser)
+		hs.authMethod(r, "GET", "/users/{userId}/export", "", hs.userController.ExportUser)
+		hs.authMethod(r, "GET", "/users", user.PermissionUserRead, hs.userController.ListUser)
+		hs.authMethod(r, "POST", "/users", user.PermissionUserWrite, hs.userController.CreateUser)
+		hs.authMethod(r, "POST", "/users/{userId}/unlock", user.PermissionUserWrite, hs.userController.UnlockUser)
+		hs.authMethod(r, "DELETE", "/users/{userId}/totp", user.PermissionUserWrite, hs.userController.ResetTOTP)
 
 		// hs.authMethod(r, "PUT", "/users/{userId}", hs.productController.)
-		hs.authMethod(r, "GET", "/products", hs.productController.ListProduct)
-		hs.authMethod(r, "POST", "/product", hs.productController.CreateProduct)
-		hs.authMethod(r, "PUT", "/product/{id}", hs.productController.UpdateProduct)
-		hs.authMethod(r, "DELETE", "/product/{id}", hs.productController.DeleteProduct)
-
-		hs.authMethod(r, "POST", "/order", hs.productController.CreateOrder)
+		hs.authMethod(r, "GET", "/products", user.PermissionProductRead, hs.productController.ListProduct)
+		hs.authMethod(r, "GET", "/products/export", user.PermissionProductExport, hs.productController.ExportProduct)
+		hs.authMethod(r, "GET", "/products/import/template", user.PermissionProductWrite, hs.productController.ImportTemplate)
+		hs.authMethod(r, "POST", "/products/import", user.PermissionProductWrite, hs.productController.ImportProduct)
+		hs.authMethod(r, "GET", "/products/import/{jobId}", user.PermissionProductWrite, hs.productController.GetImportJob)
+		hs.authMethod(r, "GET", "/products/{idOrSlug}", user.PermissionProductRead, hs.productController.GetProduct)
+		hs.authMethod(r, "GET", "/products/{id}/prices", user.PermissionProductRead, hs.productController.ListProductPrice)
+		hs.authMethod(r, "POST", "/products/{id}/prices", user.PermissionProductWrite, hs.productController.ScheduleProductPrice)
+		hs.authMethod(r, "DELETE", "/products/{id}/prices/{priceId}", user.PermissionProductWrite, hs.productController.CancelProductPrice)
+		hs.authMethod(r, "POST", "/product", user.PermissionProductWrite, hs.productController.CreateProduct)
+		hs.authMethod(r, "PUT", "/product/{id}", user.PermissionProductWrite, hs.productController.UpdateProduct)
+		hs.authMethod(r, "DELETE", "/product/{id}", user.PermissionProductWrite, hs.productController.DeleteProduct)
+		hs.authMethod(r, "GET", "/product/{id}/images", user.PermissionProductRead, hs.productController.ListProductImage)
+		hs.authMethod(r, "POST", "/product/{id}/images", user.PermissionProductWrite, hs.productController.UploadProductImage)
+		hs.authMethod(r, "PUT", "/product/{id}/images/order", user.PermissionProductWrite, hs.productController.ReorderProductImage)
+		hs.authMethod(r, "PUT", "/product/{id}/images/{imageId}/primary", user.PermissionProductWrite, hs.productController.SetPrimaryProductImage)
+		hs.authMethod(r, "DELETE", "/product/{id}/images/{imageId}", user.PermissionProductWrite, hs.productController.DeleteProductImage)
+
+		hs.authMethod(r, "GET", "/trash/products", user.PermissionTrashManage, hs.productController.ListDeletedProduct)
+		hs.authMethod(r, "POST", "/trash/products/{id}/restore", user.PermissionTrashManage, hs.productController.RestoreProduct)
+		hs.authMethod(r, "DELETE", "/trash/products/{id}", user.PermissionTrashManage, hs.productController.PurgeProduct)
+		hs.authMethod(r, "GET", "/trash/users", user.PermissionTrashManage, hs.userController.ListDeletedUser)
+		hs.authMethod(r, "POST", "/trash/users/{userId}/restore", user.PermissionTrashManage, hs.userController.RestoreUser)
+		hs.authMethod(r, "DELETE", "/trash/users/{userId}", user.PermissionTrashManage, hs.userController.PurgeUser)
+
+		hs.authMethod(r, "GET", "/audit", user.PermissionAuditRead, hs.auditController.ListAudit)
+
+		hs.authMethod(r, "GET", "/apikeys", user.PermissionAPIKeyManage, hs.apiKeyController.ListAPIKey)
+		hs.authMethod(r, "POST", "/apikeys", user.PermissionAPIKeyManage, hs.apiKeyController.CreateAPIKey)
+		hs.authMethod(r, "DELETE", "/apikeys/{id}", user.PermissionAPIKeyManage, hs.apiKeyController.RevokeAPIKey)
+
+		hs.authMethod(r, "POST", "/order", user.PermissionOrderCreate, hs.productController.CreateOrder)
+		hs.authMethod(r, "GET", "/orders", user.PermissionOrderRead, hs.productController.ListOrder)
 	})
 
 	return r
@@ -132,16 +204,28 @@ func (hs *Server) Serve() {
 func NewServer(
 	userService user.ServiceInterface,
 	productService product.ServiceInterface,
+	auditService audit.ServiceInterface,
+	apiKeyService apikey.ServiceInterface,
 	dataManager *data.Manager,
 	config *config.Config,
+	accessTokens *token.Manager,
 ) *Server {
-	userController := controller.NewUserController(userService, dataManager)
+	cookies := cookie.NewPolicy(config.SessionCookieSecure, config.SessionCookieSameSite, config.SessionCookieDomain)
+	userController := controller.NewUserController(userService, productService, auditService, dataManager, cookies)
 	productController := controller.NewProductController(productService, dataManager)
+	auditController := controller.NewAuditController(auditService, dataManager)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService, dataManager)
 	return &Server{
+		config:            config,
 		dataManager:       dataManager,
 		userService:       userService,
 		userController:    userController,
 		productService:    productService,
 		productController: productController,
+		auditController:   auditController,
+		apiKeyService:     apiKeyService,
+		apiKeyController:  apiKeyController,
+		accessTokens:      accessTokens,
+		cookies:           cookies,
 	}
 }
@@ -0,0 +1,162 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when the account or the ip is locked out after too many failed logins
+var ErrLocked = errors.New("Too many failed logins, try again later")
+
+// Attempt holds the failed logins of a key, the key is either an account or an ip
+type Attempt struct {
+	Key          string     `json:"key" db:"key"`
+	Failures     int        `json:"failures" db:"failures"`
+	LastFailedAt time.Time  `json:"lastFailedAt" db:"last_failed_at"`
+	LockedUntil  *time.Time `json:"lockedUntil" db:"locked_until"`
+}
+
+// Store represents the storage of the failed logins
+type Store interface {
+	// Get returns the attempt of the key, an empty attempt when the key has no failed logins
+	Get(ctx context.Context, key string) (*Attempt, error)
+	// Fail counts a failed login of the key, the failures before since are forgotten
+	Fail(ctx context.Context, key string, now time.Time, since time.Time) (*Attempt, error)
+	// Lock locks the key until the time and clears its failures
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset forgets the failed logins and the lock of the key
+	Reset(ctx context.Context, key string) error
+	// DeleteExpired deletes the attempts which last failed before since and are not locked at now
+	// and returns how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time, since time.Time) (int, error)
+}
+
+// Policy configures when the failed logins slow down and lock out
+type Policy struct {
+	// Window is how long a failed login is remembered
+	Window time.Duration
+	// AccountMaxFailures locks the account after as many failed logins within the window
+	AccountMaxFailures int
+	// IPMaxFailures locks the ip after as many failed logins within the window
+	IPMaxFailures int
+	// LockDuration is how long a lockout lasts
+	LockDuration time.Duration
+	// DelayStep is added to the response time of every failed login after the first one of the account
+	DelayStep time.Duration
+	// MaxDelay caps the added response time
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is the policy used when the configuration leaves it out
+var DefaultPolicy = Policy{
+	Window:             15 * time.Minute,
+	AccountMaxFailures: 5,
+	IPMaxFailures:      20,
+	LockDuration:       15 * time.Minute,
+	DelayStep:          500 * time.Millisecond,
+	MaxDelay:           3 * time.Second,
+}
+
+// Guard tracks the failed logins per account and per ip
+type Guard struct {
+	store  Store
+	policy Policy
+}
+
+// AccountKey returns the key of the account of the email, it does not matter whether the account exists
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the key of the ip
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns ErrLocked and the time the lockout ends when the account or the ip is locked
+func (g *Guard) Check(ctx context.Context, email string, ip string) (*time.Time, error) {
+	now := time.Now()
+	for _, key := range g.keys(email, ip) {
+		attempt, err := g.store.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			return attempt.LockedUntil, ErrLocked
+		}
+	}
+
+	return nil, nil
+}
+
+// Fail counts a failed login of the account and the ip, locks them when they reach their limit
+// and returns how long the response should be delayed
+func (g *Guard) Fail(ctx context.Context, email string, ip string) (time.Duration, error) {
+	now := time.Now()
+	since := now.Add(-g.policy.Window)
+
+	var delay time.Duration
+	for _, key := range g.keys(email, ip) {
+		attempt, err := g.store.Fail(ctx, key, now, since)
+		if err != nil {
+			return 0, err
+		}
+
+		max := g.policy.IPMaxFailures
+		if key == AccountKey(email) {
+			max = g.policy.AccountMaxFailures
+			delay = time.Duration(attempt.Failures-1) * g.policy.DelayStep
+			if delay > g.policy.MaxDelay {
+				delay = g.policy.MaxDelay
+			}
+		}
+
+		if max > 0 && attempt.Failures >= max {
+			err = g.store.Lock(ctx, key, now.Add(g.policy.LockDuration))
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return delay, nil
+}
+
+// Succeed forgets the failed logins of the account, the failures of the ip are kept
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, AccountKey(email))
+}
+
+// Unlock lifts the lockout of the account
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, AccountKey(email))
+}
+
+// DeleteExpired deletes the failed logins which are out of the window and no longer lock out
+// and returns how many keys were deleted
+func (g *Guard) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	return g.store.DeleteExpired(ctx, now, now.Add(-g.policy.Window))
+}
+
+// Attempt returns the failed logins of the account
+func (g *Guard) Attempt(ctx context.Context, email string) (*Attempt, error) {
+	return g.store.Get(ctx, AccountKey(email))
+}
+
+func (g *Guard) keys(email string, ip string) []string {
+	keys := []string{AccountKey(email)}
+	if ip != "" {
+		keys = append(keys, IPKey(ip))
+	}
+	return keys
+}
+
+// NewGuard creates a new login guard
+func NewGuard(store Store, policy Policy) *Guard {
+	return &Guard{
+		store:  store,
+		policy: policy,
+	}
+}
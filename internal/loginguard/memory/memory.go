@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/loginguard"
+)
+
+// sweepSize is the number of keys above which the forgotten attempts are removed
+const sweepSize = 10000
+
+// Store implements the login guard store in memory, the failed logins are lost on restart
+// and not shared between instances
+type Store struct {
+	mu       sync.Mutex
+	attempts map[string]*loginguard.Attempt
+}
+
+// Get returns the attempt of the key
+func (s *Store) Get(ctx context.Context, key string) (*loginguard.Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return &loginguard.Attempt{Key: key}, nil
+	}
+	copied := *attempt
+	return &copied, nil
+}
+
+// Fail counts a failed login of the key
+func (s *Store) Fail(ctx context.Context, key string, now time.Time, since time.Time) (*loginguard.Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.attempts) > sweepSize {
+		s.sweep(now, since)
+	}
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &loginguard.Attempt{Key: key}
+		s.attempts[key] = attempt
+	}
+	if attempt.LastFailedAt.Before(since) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+
+	copied := *attempt
+	return &copied, nil
+}
+
+// Lock locks the key until the time
+func (s *Store) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &loginguard.Attempt{Key: key, LastFailedAt: time.Now()}
+		s.attempts[key] = attempt
+	}
+	attempt.Failures = 0
+	attempt.LockedUntil = &until
+	return nil
+}
+
+// Reset forgets the key
+func (s *Store) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// DeleteExpired deletes the attempts which are neither remembered nor locked anymore
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sweep(now, since), nil
+}
+
+// sweep removes the attempts which are neither remembered nor locked anymore
+func (s *Store) sweep(now time.Time, since time.Time) int {
+	deleted := 0
+	for key, attempt := range s.attempts {
+		if attempt.LastFailedAt.Before(since) && (attempt.LockedUntil == nil || !attempt.LockedUntil.After(now)) {
+			delete(s.attempts, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// NewStore creates a new in-memory login guard store
+func NewStore() *Store {
+	return &Store{
+		attempts: map[string]*loginguard.Attempt{},
+	}
+}
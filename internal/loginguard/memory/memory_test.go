@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/loginguard"
+)
+
+var testPolicy = loginguard.Policy{
+	Window:             15 * time.Minute,
+	AccountMaxFailures: 3,
+	IPMaxFailures:      5,
+	LockDuration:       15 * time.Minute,
+	DelayStep:          time.Second,
+	MaxDelay:           1500 * time.Millisecond,
+}
+
+// age moves the failed logins and the lock of the key back in time
+func (s *Store) age(key string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	attempt.LastFailedAt = attempt.LastFailedAt.Add(-d)
+	if attempt.LockedUntil != nil {
+		lockedUntil := attempt.LockedUntil.Add(-d)
+		attempt.LockedUntil = &lockedUntil
+	}
+}
+
+func TestGuardFail(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		wantDelays []time.Duration
+		wantLocked bool
+	}{
+		{"first failure", 1, []time.Duration{0}, false},
+		{"below the limit", 2, []time.Duration{0, time.Second}, false},
+		{"at the limit", 3, []time.Duration{0, time.Second, 1500 * time.Millisecond}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			guard := loginguard.NewGuard(NewStore(), testPolicy)
+
+			for i := 0; i < tt.failures; i++ {
+				delay, err := guard.Fail(ctx, "jane@example.com", "10.0.0.1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if delay != tt.wantDelays[i] {
+					t.Errorf("failure %d: delay = %v, want %v", i+1, delay, tt.wantDelays[i])
+				}
+			}
+
+			lockedUntil, err := guard.Check(ctx, " Jane@Example.com", "10.0.0.2")
+			if tt.wantLocked {
+				if err != loginguard.ErrLocked || lockedUntil == nil || lockedUntil.Before(time.Now().Add(14*time.Minute)) {
+					t.Errorf("Check() = %v, %v, want locked for the lock duration", lockedUntil, err)
+				}
+			} else if err != nil {
+				t.Errorf("Check() error = %v, want not locked", err)
+			}
+		})
+	}
+}
+
+func TestGuardLocksIP(t *testing.T) {
+	ctx := context.Background()
+	guard := loginguard.NewGuard(NewStore(), testPolicy)
+
+	// every email stays below the account limit, the ip reaches its own
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		_, err := guard.Fail(ctx, email, "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := guard.Check(ctx, "f@example.com", "10.0.0.1")
+	if err != loginguard.ErrLocked {
+		t.Errorf("Check() from the ip error = %v, want %v", err, loginguard.ErrLocked)
+	}
+	_, err = guard.Check(ctx, "f@example.com", "10.0.0.2")
+	if err != nil {
+		t.Errorf("Check() from another ip error = %v, want not locked", err)
+	}
+}
+
+func TestGuardWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	guard := loginguard.NewGuard(store, testPolicy)
+
+	for i := 0; i < 2; i++ {
+		_, err := guard.Fail(ctx, "jane@example.com", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.age(loginguard.AccountKey("jane@example.com"), 16*time.Minute)
+
+	// the failures out of the window are forgotten, the third one starts over
+	delay, err := guard.Fail(ctx, "jane@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attempt, err := guard.Attempt(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 1 || attempt.LockedUntil != nil || delay != 0 {
+		t.Errorf("attempt = %+v, delay = %v, want the failures counted from one", attempt, delay)
+	}
+}
+
+func TestGuardSucceedAndUnlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset func(ctx context.Context, guard *loginguard.Guard) error
+	}{
+		{"succeed", func(ctx context.Context, guard *loginguard.Guard) error {
+			return guard.Succeed(ctx, "jane@example.com")
+		}},
+		{"unlock", func(ctx context.Context, guard *loginguard.Guard) error {
+			return guard.Unlock(ctx, "JANE@example.com")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			guard := loginguard.NewGuard(NewStore(), testPolicy)
+
+			for i := 0; i < testPolicy.AccountMaxFailures; i++ {
+				_, err := guard.Fail(ctx, "jane@example.com", "10.0.0.1")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := guard.Check(ctx, "jane@example.com", "")
+			if err != loginguard.ErrLocked {
+				t.Fatalf("Check() error = %v, want %v", err, loginguard.ErrLocked)
+			}
+
+			err = tt.reset(ctx, guard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = guard.Check(ctx, "jane@example.com", "")
+			if err != nil {
+				t.Errorf("Check() error = %v, want the account unlocked", err)
+			}
+			attempt, err := guard.Attempt(ctx, "jane@example.com")
+			if err != nil || attempt.Failures != 0 {
+				t.Errorf("Attempt() = %+v, %v, want the failures forgotten", attempt, err)
+			}
+
+			// the failures of the ip are kept
+			_, err = guard.Fail(ctx, "john@example.com", "10.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = guard.Fail(ctx, "john@example.com", "10.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = guard.Check(ctx, "someone@example.com", "10.0.0.1")
+			if err != loginguard.ErrLocked {
+				t.Errorf("Check() from the ip error = %v, want %v", err, loginguard.ErrLocked)
+			}
+		})
+	}
+}
+
+func TestGuardDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	guard := loginguard.NewGuard(store, testPolicy)
+
+	fail := func(email string, times int) {
+		for i := 0; i < times; i++ {
+			_, err := guard.Fail(ctx, email, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fail("recent@example.com", 1)
+	fail("old@example.com", 1)
+	store.age(loginguard.AccountKey("old@example.com"), 16*time.Minute)
+	// locked for an hour, it still locks out once the failures are out of the window
+	fail("locked@example.com", 1)
+	store.age(loginguard.AccountKey("locked@example.com"), 16*time.Minute)
+	err := store.Lock(ctx, loginguard.AccountKey("locked@example.com"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the lock of the lock duration is over
+	fail("unlocked@example.com", 3)
+	store.age(loginguard.AccountKey("unlocked@example.com"), 31*time.Minute)
+
+	deleted, err := guard.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteExpired() = %d, want 2", deleted)
+	}
+	for _, email := range []string{"recent@example.com", "locked@example.com"} {
+		if _, ok := store.attempts[loginguard.AccountKey(email)]; !ok {
+			t.Errorf("the attempt of %s was deleted", email)
+		}
+	}
+}
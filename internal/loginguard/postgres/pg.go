@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/riskiramdan/evermos/internal/loginguard"
+)
+
+// Store implements the login guard store in postgres.
+// It writes outside of the request transaction so failed logins are kept when the login is rolled back.
+type Store struct {
+	db *sqlx.DB
+}
+
+// Get returns the attempt of the key
+func (s *Store) Get(ctx context.Context, key string) (*loginguard.Attempt, error) {
+	attempt := &loginguard.Attempt{}
+	err := s.db.GetContext(ctx, attempt,
+		`SELECT "key", "failures", "last_failed_at", "locked_until" FROM "login_attempt" WHERE "key" = $1`, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &loginguard.Attempt{Key: key}, nil
+		}
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+// Fail counts a failed login of the key in a single statement so concurrent logins are all counted
+func (s *Store) Fail(ctx context.Context, key string, now time.Time, since time.Time) (*loginguard.Attempt, error) {
+	attempt := &loginguard.Attempt{}
+	err := s.db.GetContext(ctx, attempt, `
+	INSERT INTO "login_attempt" ("key", "failures", "last_failed_at", "created_at", "updated_at")
+	VALUES ($1, 1, $2, $2, $2)
+	ON CONFLICT ("key") DO UPDATE SET
+		"failures" = CASE WHEN "login_attempt"."last_failed_at" < $3 THEN 1 ELSE "login_attempt"."failures" + 1 END,
+		"last_failed_at" = $2,
+		"updated_at" = $2
+	RETURNING "key", "failures", "last_failed_at", "locked_until"`, key, now, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+// Lock locks the key until the time
+func (s *Store) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+	UPDATE "login_attempt" SET "failures" = 0, "locked_until" = $2, "updated_at" = now()
+	WHERE "key" = $1`, key, until)
+	return err
+}
+
+// Reset forgets the key
+func (s *Store) Reset(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM "login_attempt" WHERE "key" = $1`, key)
+	return err
+}
+
+// DeleteExpired deletes the attempts which are neither remembered nor locked anymore
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time, since time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+	DELETE FROM "login_attempt"
+	WHERE "last_failed_at" < $2 AND ("locked_until" IS NULL OR "locked_until" <= $1)`, now, since)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+// NewStore creates a new postgres login guard store
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
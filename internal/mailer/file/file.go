@@ -0,0 +1,44 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/mailer"
+)
+
+// Mailer implements the mailer interface for local development,
+// every email is logged and written as a file into the directory instead of being sent
+type Mailer struct {
+	dir string
+}
+
+// Send writes the email into the directory
+func (m *Mailer) Send(ctx context.Context, message *mailer.Message) error {
+	log.Printf("mailer: email to %s: %s\n", message.To, message.Subject)
+	if m.dir == "" {
+		log.Println(message.Body)
+		return nil
+	}
+
+	err := os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", message.To, message.Subject, message.Body)
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return ioutil.WriteFile(filepath.Join(m.dir, name), []byte(content), 0644)
+}
+
+// NewMailer creates a new file mailer, an empty directory only logs the emails
+func NewMailer(dir string) *Mailer {
+	return &Mailer{
+		dir: dir,
+	}
+}
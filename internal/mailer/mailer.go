@@ -0,0 +1,18 @@
+package mailer
+
+import (
+	"context"
+)
+
+// Message represents a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer represents the mailer interface
+// emails to the users (e.g. verification links) are sent through
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
@@ -0,0 +1,48 @@
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/riskiramdan/evermos/internal/mailer"
+)
+
+// Mailer implements the mailer interface on a SMTP server
+type Mailer struct {
+	addr     string
+	username string
+	password string
+	from     string
+}
+
+// Send sends the email through the SMTP server
+func (m *Mailer) Send(ctx context.Context, message *mailer.Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		host, _, err := net.SplitHostPort(m.addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.username, m.password, host)
+	}
+
+	// the header values can not hold line breaks, they would inject headers
+	replacer := strings.NewReplacer("\r", "", "\n", "")
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.from, replacer.Replace(message.To), replacer.Replace(message.Subject), message.Body)
+
+	return smtp.SendMail(m.addr, auth, m.from, []string{message.To}, []byte(content))
+}
+
+// NewMailer creates a new SMTP mailer
+func NewMailer(addr string, username string, password string, from string) *Mailer {
+	return &Mailer{
+		addr:     addr,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
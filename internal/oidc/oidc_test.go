@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/riskiramdan/evermos/internal/oidc/oidctest"
+)
+
+const clientID = "evermos"
+
+func newTestProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	srv := oidctest.NewServer(t, clientID)
+	p := NewProvider(Config{
+		Issuer:      srv.URL,
+		ClientID:    clientID,
+		RedirectURL: "https://shop.example.com/v1/oidc/callback",
+		Scopes:      []string{"email", "profile"},
+	}, srv.Client())
+	return srv, p
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	srv, p := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, err := GenerateVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             clientID,
+		"redirect_uri":          "https://shop.example.com/v1/oidc/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        Challenge(verifier),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if query.Get("code_challenge") == verifier {
+		t.Error("the code verifier is sent in the url")
+	}
+
+	claims := srv.Claims("jane", "nonce")
+	claims["email"] = "jane@example.com"
+	claims["email_verified"] = true
+	code := srv.IssueCode(query.Get("code_challenge"), srv.Sign(t, "key-1", claims))
+
+	rawIDToken, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	got, err := p.Verify(ctx, rawIDToken, "nonce")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.Subject != "jane" || got.Email != "jane@example.com" || !got.EmailVerified {
+		t.Errorf("Verify() = %+v", got)
+	}
+
+	// the code is single use
+	_, err = p.Exchange(ctx, code, verifier)
+	if !errors.Is(err, ErrExchange) {
+		t.Errorf("Exchange() of a used code error = %v, want %v", err, ErrExchange)
+	}
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	srv, p := newTestProvider(t)
+
+	verifier, _ := GenerateVerifier()
+	other, _ := GenerateVerifier()
+	code := srv.IssueCode(Challenge(verifier), srv.Sign(t, "key-1", srv.Claims("jane", "nonce")))
+
+	_, err := p.Exchange(context.Background(), code, other)
+	if !errors.Is(err, ErrExchange) {
+		t.Errorf("Exchange() error = %v, want %v", err, ErrExchange)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	srv := oidctest.NewServer(t, clientID)
+	p := NewProvider(Config{Issuer: srv.URL + "/", ClientID: clientID}, srv.Client())
+
+	_, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if !errors.Is(err, ErrDiscovery) {
+		t.Errorf("AuthCodeURL() error = %v, want %v", err, ErrDiscovery)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	srv, p := newTestProvider(t)
+
+	signed := func(change func(claims jwt.MapClaims)) string {
+		claims := srv.Claims("jane", "nonce")
+		change(claims)
+		return srv.Sign(t, "key-1", claims)
+	}
+	unsigned := func(method jwt.SigningMethod, key interface{}) string {
+		token := jwt.NewWithClaims(method, srv.Claims("jane", "nonce"))
+		token.Header["kid"] = "key-1"
+		raw, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"wrong nonce", signed(func(jwt.MapClaims) {}), "other"},
+		{"no nonce expected", signed(func(claims jwt.MapClaims) { claims["nonce"] = "" }), ""},
+		{"wrong issuer", signed(func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }), "nonce"},
+		{"wrong audience", signed(func(claims jwt.MapClaims) { claims["aud"] = "other-client" }), "nonce"},
+		{"expired", signed(func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }), "nonce"},
+		{"no expiry", signed(func(claims jwt.MapClaims) { delete(claims, "exp") }), "nonce"},
+		{"no subject", signed(func(claims jwt.MapClaims) { delete(claims, "sub") }), "nonce"},
+		{"alg none", unsigned(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), "nonce"},
+		{"HS256", unsigned(jwt.SigningMethodHS256, []byte("the public key or any secret")), "nonce"},
+		{"tampered", signed(func(jwt.MapClaims) {}) + "x", "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.Verify(context.Background(), tt.token, tt.nonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Verify() = %+v, %v, want %v", claims, err, ErrInvalidIDToken)
+			}
+		})
+	}
+
+	// the valid token passes, so the cases above are refused for their own reason
+	_, err := p.Verify(context.Background(), signed(func(jwt.MapClaims) {}), "nonce")
+	if err != nil {
+		t.Errorf("Verify() of the valid token error = %v", err)
+	}
+}
+
+func TestVerifyUnknownKeyRefreshesKeys(t *testing.T) {
+	srv, p := newTestProvider(t)
+	ctx := context.Background()
+
+	_, err := p.Verify(ctx, srv.Sign(t, "key-1", srv.Claims("jane", "nonce")), "nonce")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got := srv.KeyFetches(); got != 1 {
+		t.Fatalf("keys fetched %d times, want 1", got)
+	}
+
+	// the provider rotates its key
+	srv.AddKey(t, "key-2")
+	rotated := srv.Sign(t, "key-2", srv.Claims("jane", "nonce"))
+
+	// the keys were fetched just now, they are not fetched again for every unknown key
+	_, err = p.Verify(ctx, rotated, "nonce")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Verify() right after the fetch error = %v, want %v", err, ErrInvalidIDToken)
+	}
+	if got := srv.KeyFetches(); got != 1 {
+		t.Errorf("keys fetched %d times, want 1", got)
+	}
+
+	p.keys.mu.Lock()
+	p.keys.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	p.keys.mu.Unlock()
+
+	_, err = p.Verify(ctx, rotated, "nonce")
+	if err != nil {
+		t.Errorf("Verify() with the rotated key error = %v", err)
+	}
+	if got := srv.KeyFetches(); got != 2 {
+		t.Errorf("keys fetched %d times, want 2", got)
+	}
+
+	// the known keys are used without fetching
+	_, err = p.Verify(ctx, srv.Sign(t, "key-1", srv.Claims("jane", "nonce")), "nonce")
+	if err != nil {
+		t.Errorf("Verify() with the first key error = %v", err)
+	}
+	if got := srv.KeyFetches(); got != 2 {
+		t.Errorf("keys fetched %d times, want 2", got)
+	}
+}
@@ -0,0 +1,192 @@
+// Package oidctest runs an in-process OpenID Connect provider for the tests of the login with the provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// code is an authorization code issued by the provider, redeemed with the verifier of the challenge
+type code struct {
+	challenge string
+	idToken   string
+}
+
+// Server is a provider serving the discovery document, the key set and the token endpoint.
+// Its url is the issuer.
+type Server struct {
+	*httptest.Server
+	ClientID string
+
+	mu         sync.Mutex
+	keys       map[string]*rsa.PrivateKey
+	published  []string
+	codes      map[string]*code
+	issued     int
+	keyFetches int
+}
+
+// NewServer starts a provider publishing the RSA key "key-1", the server is closed with the test
+func NewServer(t *testing.T, clientID string) *Server {
+	s := &Server{
+		ClientID: clientID,
+		keys:     map[string]*rsa.PrivateKey{},
+		codes:    map[string]*code{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "the user signs in here", http.StatusNotImplemented)
+	})
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	s.AddKey(t, "key-1")
+	return s
+}
+
+// AddKey generates an RSA key and publishes it under the key id
+func (s *Server) AddKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+	s.published = append(s.published, kid)
+}
+
+// KeyFetches returns how many times the key set was fetched
+func (s *Server) KeyFetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keyFetches
+}
+
+// Claims returns the claims of a valid id token of the subject
+func (s *Server) Claims(subject string, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"sub":   subject,
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+// Sign signs the claims with the RSA key of the key id using RS256
+func (s *Server) Sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	s.mu.Unlock()
+	if !ok {
+		t.Fatalf("unknown key %q", kid)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// IssueCode returns an authorization code redeemed for the id token with the verifier of the S256 challenge
+func (s *Server) IssueCode(challenge string, idToken string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issued++
+	c := "code-" + strconv.Itoa(s.issued)
+	s.codes[c] = &code{
+		challenge: challenge,
+		idToken:   idToken,
+	}
+	return c
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyFetches++
+
+	keys := []map[string]string{}
+	for _, kid := range s.published {
+		public := s.keys[kid].PublicKey
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+// token redeems the authorization code once, the sha256 of the code verifier has to be the challenge
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("client_id") != s.ClientID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	c, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != c.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_grant",
+			"error_description": "unknown code or wrong code verifier",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     c.idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
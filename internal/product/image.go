@@ -0,0 +1,509 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	// register the gif decoder for image.Decode
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// Image upload limits
+const (
+	MaxImageSize         = 5 << 20
+	MaxImagesPerUpload   = 10
+	ThumbnailMaxPixel    = 200
+	maxImagePixels       = 40000000
+	thumbnailJPEGQuality = 80
+)
+
+// Errors
+var (
+	ErrImageInvalidType   = errors.New("Image type should be jpeg, png or gif")
+	ErrImageTooLarge      = fmt.Errorf("Image should not be larger than %d MB", MaxImageSize>>20)
+	ErrImageCorrupted     = errors.New("Image can not be decoded")
+	ErrImageRequired      = errors.New("At least one image is required")
+	ErrImageTooMany       = fmt.Errorf("At most %d images can be uploaded at once", MaxImagesPerUpload)
+	ErrImageOrderMismatch = errors.New("Image order should list every image of the product exactly once")
+)
+
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ProductImage product image
+type ProductImage struct {
+	ID           int        `json:"id" db:"id"`
+	ProductID    int        `json:"productId" db:"product_id"`
+	Key          string     `json:"-" db:"key"`
+	ThumbnailKey string     `json:"-" db:"thumbnail_key"`
+	ContentType  string     `json:"contentType" db:"content_type"`
+	Size         int        `json:"size" db:"size"`
+	Position     int        `json:"position" db:"position"`
+	IsPrimary    bool       `json:"isPrimary" db:"is_primary"`
+	URL          string     `json:"url" db:"-"`
+	ThumbnailURL string     `json:"thumbnailUrl" db:"-"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy    *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt    *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy    *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// ImageUpload represents an uploaded image file
+type ImageUpload struct {
+	Filename string
+	Data     []byte
+}
+
+// ReorderProductImagesParams represent the http request data for reordering product images
+type ReorderProductImagesParams struct {
+	ImageIDs []int `json:"imageIds" validate:"required,min=1,dive,gt=0"`
+}
+
+type processedImage struct {
+	contentType string
+	ext         string
+	data        []byte
+	thumbnail   []byte
+	thumbExt    string
+	thumbType   string
+}
+
+// processImage validates the uploaded image and generates its thumbnail
+func processImage(upload *ImageUpload) (*processedImage, error) {
+	if len(upload.Data) > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(upload.Data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return nil, ErrImageInvalidType
+	}
+
+	// check the dimensions before decoding so a tiny file can not blow up the memory
+	config, _, err := image.DecodeConfig(bytes.NewReader(upload.Data))
+	if err != nil {
+		return nil, ErrImageCorrupted
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(upload.Data))
+	if err != nil {
+		return nil, ErrImageCorrupted
+	}
+
+	thumb := thumbnail(src, ThumbnailMaxPixel)
+	buff := &bytes.Buffer{}
+	processed := &processedImage{
+		contentType: contentType,
+		ext:         ext,
+		data:        upload.Data,
+	}
+
+	// keep transparency for png and gif, jpeg is smaller for photos
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(buff, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality})
+		processed.thumbExt = ".jpg"
+		processed.thumbType = "image/jpeg"
+	} else {
+		err = png.Encode(buff, thumb)
+		processed.thumbExt = ".png"
+		processed.thumbType = "image/png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	processed.thumbnail = buff.Bytes()
+
+	return processed, nil
+}
+
+// thumbnail scales the image down to fit into maxPixel x maxPixel,
+// averaging the source pixels covered by each thumbnail pixel
+func thumbnail(src image.Image, maxPixel int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	thumbWidth, thumbHeight := width, height
+	if width > maxPixel || height > maxPixel {
+		if width >= height {
+			thumbWidth = maxPixel
+			thumbHeight = height * maxPixel / width
+		} else {
+			thumbHeight = maxPixel
+			thumbWidth = width * maxPixel / height
+		}
+	}
+	if thumbWidth < 1 {
+		thumbWidth = 1
+	}
+	if thumbHeight < 1 {
+		thumbHeight = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0 := bounds.Min.Y + y*height/thumbHeight
+		y1 := bounds.Min.Y + (y+1)*height/thumbHeight
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < thumbWidth; x++ {
+			x0 := bounds.Min.X + x*width/thumbWidth
+			x1 := bounds.Min.X + (x+1)*width/thumbWidth
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
+
+func randomName() (string, error) {
+	buff := make([]byte, 16)
+	_, err := rand.Read(buff)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", buff), nil
+}
+
+func (s *Service) withImageURLs(images []*ProductImage) []*ProductImage {
+	for _, img := range images {
+		img.URL = s.blobStore.URL(img.Key)
+		img.ThumbnailURL = s.blobStore.URL(img.ThumbnailKey)
+	}
+	return images
+}
+
+// ListProductImages is listing the images of a product ordered by position
+func (s *Service) ListProductImages(ctx context.Context, productID int) ([]*ProductImage, *types.Error) {
+	images, err := s.imageStorage.FindAllProductImage(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->ListProductImages()" + err.Path
+		return nil, err
+	}
+
+	return s.withImageURLs(images), nil
+}
+
+// UploadProductImages validates the uploaded images, stores them and their thumbnails
+// in the blob store and appends them to the product images.
+// The first image of a product without any image becomes its primary image.
+func (s *Service) UploadProductImages(ctx context.Context, productID int, uploads []*ImageUpload) ([]*ProductImage, *types.Error) {
+	if len(uploads) < 1 {
+		return nil, &types.Error{
+			Path:    ".ProductService->UploadProductImages()",
+			Message: ErrImageRequired.Error(),
+			Error:   ErrImageRequired,
+			Type:    "validation-error",
+		}
+	}
+	if len(uploads) > MaxImagesPerUpload {
+		return nil, &types.Error{
+			Path:    ".ProductService->UploadProductImages()",
+			Message: ErrImageTooMany.Error(),
+			Error:   ErrImageTooMany,
+			Type:    "validation-error",
+		}
+	}
+
+	_, errType := s.GetProduct(ctx, productID)
+	if errType != nil {
+		errType.Path = ".ProductService->UploadProductImages()" + errType.Path
+		return nil, errType
+	}
+
+	processed := []*processedImage{}
+	for _, upload := range uploads {
+		p, err := processImage(upload)
+		if err != nil {
+			errorType := "validation-error"
+			if err != ErrImageTooLarge && err != ErrImageInvalidType && err != ErrImageCorrupted {
+				errorType = "golang-error"
+			}
+			return nil, &types.Error{
+				Path:    ".ProductService->UploadProductImages()",
+				Message: fmt.Sprintf("%s: %s", upload.Filename, err.Error()),
+				Error:   err,
+				Type:    errorType,
+			}
+		}
+		processed = append(processed, p)
+	}
+
+	existing, errType := s.imageStorage.FindAllProductImage(ctx, productID)
+	if errType != nil {
+		errType.Path = ".ProductService->UploadProductImages()" + errType.Path
+		return nil, errType
+	}
+	position := 0
+	hasPrimary := false
+	for _, img := range existing {
+		if img.Position >= position {
+			position = img.Position + 1
+		}
+		if img.IsPrimary {
+			hasPrimary = true
+		}
+	}
+
+	// the blobs are stored before the rows referencing them and removed again when the rows are not kept
+	storedKeys := []string{}
+	cleanup := func() {
+		for _, key := range storedKeys {
+			s.blobStore.Delete(ctx, key)
+		}
+	}
+
+	pending := []*ProductImage{}
+	for i, p := range processed {
+		name, err := randomName()
+		if err != nil {
+			cleanup()
+			return nil, &types.Error{
+				Path:    ".ProductService->UploadProductImages()",
+				Message: err.Error(),
+				Error:   err,
+				Type:    "golang-error",
+			}
+		}
+
+		key := fmt.Sprintf("products/%d/%s%s", productID, name, p.ext)
+		thumbnailKey := fmt.Sprintf("products/%d/%s_thumb%s", productID, name, p.thumbExt)
+
+		err = s.blobStore.Put(ctx, key, bytes.NewReader(p.data), p.contentType)
+		if err == nil {
+			storedKeys = append(storedKeys, key)
+			err = s.blobStore.Put(ctx, thumbnailKey, bytes.NewReader(p.thumbnail), p.thumbType)
+		}
+		if err != nil {
+			cleanup()
+			return nil, &types.Error{
+				Path:    ".ProductService->UploadProductImages()",
+				Message: err.Error(),
+				Error:   err,
+				Type:    "blob-error",
+			}
+		}
+		storedKeys = append(storedKeys, thumbnailKey)
+
+		now := time.Now()
+		pending = append(pending, &ProductImage{
+			ProductID:    productID,
+			Key:          key,
+			ThumbnailKey: thumbnailKey,
+			ContentType:  p.contentType,
+			Size:         len(p.data),
+			Position:     position + i,
+			IsPrimary:    !hasPrimary && i == 0,
+			CreatedAt:    now,
+			UpdatedAt:    &now,
+		})
+	}
+
+	images := []*ProductImage{}
+	for _, img := range pending {
+		img, errType := s.imageStorage.InsertProductImage(ctx, img)
+		if errType != nil {
+			cleanup()
+			errType.Path = ".ProductService->UploadProductImages()" + errType.Path
+			return nil, errType
+		}
+		images = append(images, img)
+	}
+	data.AfterRollback(ctx, cleanup)
+
+	return s.withImageURLs(images), nil
+}
+
+// ReorderProductImages sets the position of the product images
+// following the order of the image ids
+func (s *Service) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) ([]*ProductImage, *types.Error) {
+	images, errType := s.imageStorage.FindAllProductImage(ctx, productID)
+	if errType != nil {
+		errType.Path = ".ProductService->ReorderProductImages()" + errType.Path
+		return nil, errType
+	}
+
+	byID := map[int]*ProductImage{}
+	for _, img := range images {
+		byID[img.ID] = img
+	}
+	if len(imageIDs) != len(images) {
+		return nil, &types.Error{
+			Path:    ".ProductService->ReorderProductImages()",
+			Message: ErrImageOrderMismatch.Error(),
+			Error:   ErrImageOrderMismatch,
+			Type:    "validation-error",
+		}
+	}
+
+	reordered := []*ProductImage{}
+	for position, imageID := range imageIDs {
+		img, ok := byID[imageID]
+		if !ok {
+			return nil, &types.Error{
+				Path:    ".ProductService->ReorderProductImages()",
+				Message: ErrImageOrderMismatch.Error(),
+				Error:   ErrImageOrderMismatch,
+				Type:    "validation-error",
+			}
+		}
+		delete(byID, imageID)
+
+		if img.Position != position {
+			now := time.Now()
+			img.Position = position
+			img.UpdatedAt = &now
+			img, errType = s.imageStorage.UpdateProductImage(ctx, img)
+			if errType != nil {
+				errType.Path = ".ProductService->ReorderProductImages()" + errType.Path
+				return nil, errType
+			}
+		}
+		reordered = append(reordered, img)
+	}
+
+	return s.withImageURLs(reordered), nil
+}
+
+// SetPrimaryProductImage makes the image the only primary image of the product
+func (s *Service) SetPrimaryProductImage(ctx context.Context, productID int, imageID int) ([]*ProductImage, *types.Error) {
+	images, errType := s.imageStorage.FindAllProductImage(ctx, productID)
+	if errType != nil {
+		errType.Path = ".ProductService->SetPrimaryProductImage()" + errType.Path
+		return nil, errType
+	}
+
+	found := false
+	for _, img := range images {
+		if img.ID == imageID {
+			found = true
+		}
+	}
+	if !found {
+		return nil, &types.Error{
+			Path:    ".ProductService->SetPrimaryProductImage()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "validation-error",
+		}
+	}
+
+	for i, img := range images {
+		isPrimary := img.ID == imageID
+		if img.IsPrimary == isPrimary {
+			continue
+		}
+
+		now := time.Now()
+		img.IsPrimary = isPrimary
+		img.UpdatedAt = &now
+		images[i], errType = s.imageStorage.UpdateProductImage(ctx, img)
+		if errType != nil {
+			errType.Path = ".ProductService->SetPrimaryProductImage()" + errType.Path
+			return nil, errType
+		}
+	}
+
+	return s.withImageURLs(images), nil
+}
+
+// DeleteProductImage removes the image and, once the removal is committed, its blobs.
+// The next image takes over when the primary image is deleted.
+func (s *Service) DeleteProductImage(ctx context.Context, productID int, imageID int) *types.Error {
+	images, errType := s.imageStorage.FindAllProductImage(ctx, productID)
+	if errType != nil {
+		errType.Path = ".ProductService->DeleteProductImage()" + errType.Path
+		return errType
+	}
+
+	var deleted *ProductImage
+	remaining := []*ProductImage{}
+	for _, img := range images {
+		if img.ID == imageID {
+			deleted = img
+			continue
+		}
+		remaining = append(remaining, img)
+	}
+	if deleted == nil {
+		return &types.Error{
+			Path:    ".ProductService->DeleteProductImage()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "validation-error",
+		}
+	}
+
+	errType = s.imageStorage.DeleteProductImage(ctx, imageID)
+	if errType != nil {
+		errType.Path = ".ProductService->DeleteProductImage()" + errType.Path
+		return errType
+	}
+
+	if deleted.IsPrimary && len(remaining) > 0 {
+		now := time.Now()
+		remaining[0].IsPrimary = true
+		remaining[0].UpdatedAt = &now
+		_, errType = s.imageStorage.UpdateProductImage(ctx, remaining[0])
+		if errType != nil {
+			errType.Path = ".ProductService->DeleteProductImage()" + errType.Path
+			return errType
+		}
+	}
+
+	s.deleteBlobsAfterCommit(ctx, ".ProductService->DeleteProductImage()", deleted.Key, deleted.ThumbnailKey)
+
+	return nil
+}
+
+// deleteBlobsAfterCommit deletes the blobs once the transaction removing the rows referencing them is committed,
+// they are kept for the rows when it is rolled back. A failed delete leaves an unreferenced blob behind, it is logged.
+func (s *Service) deleteBlobsAfterCommit(ctx context.Context, path string, keys ...string) {
+	data.AfterCommit(ctx, func() {
+		for _, key := range keys {
+			err := s.blobStore.Delete(ctx, key)
+			if err != nil {
+				log.Printf("DETAIL [%s - %s]: %s: %s\n", path, "blob-error", key, err.Error())
+			}
+		}
+	})
+}
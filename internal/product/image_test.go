@@ -0,0 +1,136 @@
+package product
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	buff := &bytes.Buffer{}
+	if err := png.Encode(buff, img); err != nil {
+		t.Fatal(err)
+	}
+	return buff.Bytes()
+}
+
+func solid(width, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withDimensions rewrites the IHDR chunk of the png so it claims the dimensions
+// without the pixel data being there
+func withDimensions(data []byte, width, height uint32) []byte {
+	out := append([]byte{}, data...)
+	// signature (8) + chunk length (4) + chunk type (4)
+	binary.BigEndian.PutUint32(out[16:], width)
+	binary.BigEndian.PutUint32(out[20:], height)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func TestProcessImage(t *testing.T) {
+	jpegBuff := &bytes.Buffer{}
+	if err := jpeg.Encode(jpegBuff, solid(400, 100, color.White), nil); err != nil {
+		t.Fatal(err)
+	}
+	gifBuff := &bytes.Buffer{}
+	if err := gif.Encode(gifBuff, solid(10, 10, color.Black), nil); err != nil {
+		t.Fatal(err)
+	}
+	pngData := encodePNG(t, solid(100, 300, color.Black))
+
+	tests := []struct {
+		name          string
+		data          []byte
+		wantErr       error
+		wantType      string
+		wantThumbType string
+		wantThumbSize image.Point
+	}{
+		{"jpeg", jpegBuff.Bytes(), nil, "image/jpeg", "image/jpeg", image.Pt(200, 50)},
+		{"png", pngData, nil, "image/png", "image/png", image.Pt(66, 200)},
+		{"gif smaller than the thumbnail", gifBuff.Bytes(), nil, "image/gif", "image/png", image.Pt(10, 10)},
+		{"text", []byte("name,price\nlaptop,10\n"), ErrImageInvalidType, "", "", image.Point{}},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrImageInvalidType, "", "", image.Point{}},
+		{"too many bytes", append(encodePNG(t, solid(1, 1, color.Black)), make([]byte, MaxImageSize)...), ErrImageTooLarge, "", "", image.Point{}},
+		{"too many pixels", withDimensions(pngData, 10000, 10000), ErrImageTooLarge, "", "", image.Point{}},
+		{"corrupted", pngData[:len(pngData)/2], ErrImageCorrupted, "", "", image.Point{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := processImage(&ImageUpload{Filename: tt.name, Data: tt.data})
+			if err != tt.wantErr {
+				t.Fatalf("processImage() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if p.contentType != tt.wantType {
+				t.Errorf("contentType = %s, want %s", p.contentType, tt.wantType)
+			}
+			if p.thumbType != tt.wantThumbType {
+				t.Errorf("thumbType = %s, want %s", p.thumbType, tt.wantThumbType)
+			}
+			thumb, format, err := image.Decode(bytes.NewReader(p.thumbnail))
+			if err != nil {
+				t.Fatalf("thumbnail can not be decoded: %v", err)
+			}
+			if "image/"+format != tt.wantThumbType {
+				t.Errorf("thumbnail format = %s, want %s", format, tt.wantThumbType)
+			}
+			if got := thumb.Bounds().Size(); got != tt.wantThumbSize {
+				t.Errorf("thumbnail size = %v, want %v", got, tt.wantThumbSize)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	// left half black, right half white
+	src := solid(400, 200, color.White)
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			src.Set(x, y, color.Black)
+		}
+	}
+
+	thumb := thumbnail(src, 100)
+	if got := thumb.Bounds().Size(); got != image.Pt(100, 50) {
+		t.Fatalf("size = %v, want (100,50)", got)
+	}
+	if got := color.GrayModel.Convert(thumb.At(10, 25)).(color.Gray).Y; got != 0 {
+		t.Errorf("left pixel = %d, want 0", got)
+	}
+	if got := color.GrayModel.Convert(thumb.At(90, 25)).(color.Gray).Y; got != 255 {
+		t.Errorf("right pixel = %d, want 255", got)
+	}
+
+	// the pixels straddling both halves are averaged
+	src3 := solid(2, 1, color.White)
+	src3.Set(0, 0, color.Black)
+	thumb = thumbnail(src3, 1)
+	if got := color.GrayModel.Convert(thumb.At(0, 0)).(color.Gray).Y; got < 126 || got > 128 {
+		t.Errorf("averaged pixel = %d, want about 127", got)
+	}
+
+	// a very thin image keeps at least one pixel
+	thumb = thumbnail(solid(1000, 1, color.Black), 100)
+	if got := thumb.Bounds().Size(); got != image.Pt(100, 1) {
+		t.Errorf("size = %v, want (100,1)", got)
+	}
+}
@@ -0,0 +1,346 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// Import job statuses
+const (
+	ImportJobPending    = "pending"
+	ImportJobProcessing = "processing"
+	ImportJobDone       = "done"
+	ImportJobFailed     = "failed"
+)
+
+// ImportAsyncThreshold is the number of rows above which
+// the import is processed as a background job
+const ImportAsyncThreshold = 500
+
+// ImportColumns are the columns of the import template, in order
+var ImportColumns = []string{"name", "qty", "price"}
+
+// formulaPrefixes are the first characters that make a spreadsheet cell a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// Errors
+var (
+	ErrImportEmpty       = errors.New("File has no product rows")
+	ErrImportHeader      = fmt.Errorf("First row should be the header: %s", strings.Join(ImportColumns, ", "))
+	ErrImportInvalidRows = errors.New("Some rows are invalid, nothing was imported")
+)
+
+// ImportRowError represents the validation error of a single imported row
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportResult represents the result of a products import
+type ImportResult struct {
+	TotalRows int               `json:"totalRows"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Errors    []*ImportRowError `json:"errors"`
+}
+
+// ImportJob represents a products import processed in the background
+type ImportJob struct {
+	ID         int             `json:"id" db:"id"`
+	Filename   string          `json:"filename" db:"filename"`
+	Status     string          `json:"status" db:"status"`
+	TotalRows  int             `json:"totalRows" db:"total_rows"`
+	Created    int             `json:"created" db:"created_rows"`
+	Updated    int             `json:"updated" db:"updated_rows"`
+	Errors     json.RawMessage `json:"errors" db:"errors"`
+	Message    *string         `json:"message" db:"message"`
+	FinishedAt *time.Time      `json:"finishedAt" db:"finished_at"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	CreatedBy  *string         `json:"createdBy" db:"created_by"`
+	UpdatedAt  *time.Time      `json:"updatedAt" db:"updated_at"`
+	UpdatedBy  *string         `json:"updatedBy" db:"updated_by"`
+}
+
+// ImportTemplate returns the rows of the import template
+func (s *Service) ImportTemplate() [][]string {
+	return [][]string{
+		ImportColumns,
+		{"Laptop", "5", "2000000"},
+	}
+}
+
+// ExportProducts returns the current catalog as spreadsheet rows,
+// the columns match the import template so the file can be imported back
+func (s *Service) ExportProducts(ctx context.Context) ([][]string, *types.Error) {
+	products, err := s.productStorage.FindAll(ctx, &FindAllProductsParams{})
+	if err != nil {
+		err.Path = ".ProductService->ExportProducts()" + err.Path
+		return nil, err
+	}
+
+	rows := [][]string{ImportColumns}
+	for _, p := range products {
+		rows = append(rows, []string{escapeCell(p.Name), strconv.Itoa(p.Qty), strconv.Itoa(p.Price)})
+	}
+
+	return rows, nil
+}
+
+// escapeCell prefixes the cells spreadsheet applications would evaluate as a formula with a quote
+func escapeCell(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeCell removes the quote escapeCell prefixed, so an export can be imported back as is
+func unescapeCell(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// parseImportRows validates the header and every row of the import
+func parseImportRows(rows [][]string) ([]*TransactionProductParams, []*ImportRowError, error) {
+	if len(rows) < 1 {
+		return nil, nil, ErrImportEmpty
+	}
+
+	header := rows[0]
+	if len(header) < len(ImportColumns) {
+		return nil, nil, ErrImportHeader
+	}
+	for i, column := range ImportColumns {
+		if strings.ToLower(strings.TrimSpace(header[i])) != column {
+			return nil, nil, ErrImportHeader
+		}
+	}
+	if len(rows) < 2 {
+		return nil, nil, ErrImportEmpty
+	}
+
+	params := []*TransactionProductParams{}
+	rowErrors := []*ImportRowError{}
+	names := map[string]int{}
+	for i, row := range rows[1:] {
+		// row numbers follow the spreadsheet, the header is row 1
+		rowNumber := i + 2
+		cells := make([]string, len(ImportColumns))
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.TrimSpace(row[j])
+			}
+		}
+
+		name := unescapeCell(cells[0])
+		if name == "" {
+			rowErrors = append(rowErrors, &ImportRowError{Row: rowNumber, Field: "name", Message: "is required"})
+		} else if len(name) > 80 {
+			rowErrors = append(rowErrors, &ImportRowError{Row: rowNumber, Field: "name", Message: "should not be longer than 80 characters"})
+		} else if firstRow, ok := names[name]; ok {
+			rowErrors = append(rowErrors, &ImportRowError{Row: rowNumber, Field: "name", Message: fmt.Sprintf("is duplicated with row %d", firstRow)})
+		} else {
+			names[name] = rowNumber
+		}
+
+		qty, err := strconv.Atoi(cells[1])
+		if err != nil || qty < 0 {
+			rowErrors = append(rowErrors, &ImportRowError{Row: rowNumber, Field: "qty", Message: "should be a non negative integer"})
+		}
+
+		price, err := strconv.Atoi(cells[2])
+		if err != nil || price < 0 {
+			rowErrors = append(rowErrors, &ImportRowError{Row: rowNumber, Field: "price", Message: "should be a non negative integer"})
+		}
+
+		params = append(params, &TransactionProductParams{
+			Name:  name,
+			Qty:   qty,
+			Price: price,
+		})
+	}
+
+	return params, rowErrors, nil
+}
+
+// ImportProducts validates the rows and upserts the products by name, matched exactly as on product creation.
+// Nothing is written when any row is invalid, the result then lists every row error.
+// It should run inside a transaction so the import is all or nothing.
+func (s *Service) ImportProducts(ctx context.Context, rows [][]string) (*ImportResult, *types.Error) {
+	params, rowErrors, err := parseImportRows(rows)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductService->ImportProducts()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "validation-error",
+		}
+	}
+
+	result := &ImportResult{
+		TotalRows: len(params),
+		Errors:    rowErrors,
+	}
+	if len(rowErrors) > 0 {
+		return result, &types.Error{
+			Path:    ".ProductService->ImportProducts()",
+			Message: ErrImportInvalidRows.Error(),
+			Error:   ErrImportInvalidRows,
+			Type:    "validation-error",
+		}
+	}
+
+	for _, param := range params {
+		existing, errType := s.productStorage.FindAll(ctx, &FindAllProductsParams{
+			Name: param.Name,
+		})
+		if errType != nil {
+			errType.Path = ".ProductService->ImportProducts()" + errType.Path
+			return nil, errType
+		}
+
+		now := time.Now()
+		if len(existing) > 0 {
+			product := existing[0]
+			priceChanged := product.Price != param.Price
+			product.Qty = param.Qty
+			product.Price = param.Price
+			product.UpdatedAt = &now
+			_, errType = s.productStorage.Update(ctx, product)
+			if errType != nil {
+				errType.Path = ".ProductService->ImportProducts()" + errType.Path
+				return nil, errType
+			}
+			if priceChanged {
+				errType = s.recordPrice(ctx, product.ID, product.Price)
+				if errType != nil {
+					errType.Path = ".ProductService->ImportProducts()" + errType.Path
+					return nil, errType
+				}
+			}
+			result.Updated++
+			continue
+		}
+
+		productSlug, errType := s.uniqueSlug(ctx, param.Name, 0)
+		if errType != nil {
+			errType.Path = ".ProductService->ImportProducts()" + errType.Path
+			return nil, errType
+		}
+
+		product, errType := s.productStorage.Insert(ctx, &Product{
+			Name:      param.Name,
+			Slug:      productSlug,
+			Qty:       param.Qty,
+			Price:     param.Price,
+			CreatedAt: now,
+			UpdatedAt: &now,
+		})
+		if errType != nil {
+			errType.Path = ".ProductService->ImportProducts()" + errType.Path
+			return nil, errType
+		}
+		errType = s.recordPrice(ctx, product.ID, product.Price)
+		if errType != nil {
+			errType.Path = ".ProductService->ImportProducts()" + errType.Path
+			return nil, errType
+		}
+		result.Created++
+	}
+
+	return result, nil
+}
+
+// CreateImportJob registers a pending background import of the file
+func (s *Service) CreateImportJob(ctx context.Context, filename string, totalRows int) (*ImportJob, *types.Error) {
+	now := time.Now()
+	job, err := s.importJobStorage.InsertImportJob(ctx, &ImportJob{
+		Filename:  filename,
+		Status:    ImportJobPending,
+		TotalRows: totalRows,
+		Errors:    json.RawMessage("[]"),
+		CreatedAt: now,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		err.Path = ".ProductService->CreateImportJob()" + err.Path
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetImportJob is get import job
+func (s *Service) GetImportJob(ctx context.Context, jobID int) (*ImportJob, *types.Error) {
+	job, err := s.importJobStorage.FindImportJobByID(ctx, jobID)
+	if err != nil {
+		err.Path = ".ProductService->GetImportJob()" + err.Path
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// StartImportJob marks the import job as being processed
+func (s *Service) StartImportJob(ctx context.Context, job *ImportJob) (*ImportJob, *types.Error) {
+	now := time.Now()
+	job.Status = ImportJobProcessing
+	job.UpdatedAt = &now
+
+	job, err := s.importJobStorage.UpdateImportJob(ctx, job)
+	if err != nil {
+		err.Path = ".ProductService->StartImportJob()" + err.Path
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// FinishImportJob records the result of the import job
+func (s *Service) FinishImportJob(ctx context.Context, job *ImportJob, result *ImportResult, importErr *types.Error) (*ImportJob, *types.Error) {
+	now := time.Now()
+	job.Status = ImportJobDone
+	job.FinishedAt = &now
+	job.UpdatedAt = &now
+
+	if result != nil {
+		job.TotalRows = result.TotalRows
+		job.Created = result.Created
+		job.Updated = result.Updated
+		if len(result.Errors) > 0 {
+			rowErrors, err := json.Marshal(result.Errors)
+			if err == nil {
+				job.Errors = rowErrors
+			}
+		}
+	}
+	if importErr != nil {
+		job.Status = ImportJobFailed
+		job.Created = 0
+		job.Updated = 0
+		message := importErr.Message
+		if importErr.Type != "validation-error" {
+			// internal errors are only logged, not exposed to the client
+			message = "Import failed"
+		}
+		job.Message = &message
+	}
+
+	job, err := s.importJobStorage.UpdateImportJob(ctx, job)
+	if err != nil {
+		err.Path = ".ProductService->FinishImportJob()" + err.Path
+		return nil, err
+	}
+
+	return job, nil
+}
@@ -0,0 +1,90 @@
+package product
+
+import (
+	"testing"
+)
+
+func TestEscapeCell(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{"Laptop", "Laptop"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"'quoted", "'quoted"},
+	}
+	for _, tt := range tests {
+		got := escapeCell(tt.cell)
+		if got != tt.want {
+			t.Errorf("escapeCell(%q) = %q, want %q", tt.cell, got, tt.want)
+		}
+		if back := unescapeCell(got); back != tt.cell {
+			t.Errorf("unescapeCell(%q) = %q, want %q", got, back, tt.cell)
+		}
+	}
+}
+
+func TestParseImportRows(t *testing.T) {
+	rows := [][]string{
+		{"Name", "Qty", "Price"},
+		{"Laptop", "5", "2000000"},
+		{"laptop", "1", "10"},
+		{"'=1+1", "2", "20"},
+		{"Laptop", "3", "30"},
+		{"", "x", "-1"},
+	}
+
+	params, rowErrors, err := parseImportRows(rows)
+	if err != nil {
+		t.Fatalf("parseImportRows() error = %v", err)
+	}
+	if len(params) != 5 {
+		t.Fatalf("got %d params, want 5", len(params))
+	}
+	if params[1].Name != "laptop" {
+		t.Errorf("name of row 3 = %q, want %q", params[1].Name, "laptop")
+	}
+	if params[2].Name != "=1+1" {
+		t.Errorf("name of row 4 = %q, want the exported quote removed", params[2].Name)
+	}
+
+	want := []ImportRowError{
+		{Row: 5, Field: "name", Message: "is duplicated with row 2"},
+		{Row: 6, Field: "name", Message: "is required"},
+		{Row: 6, Field: "qty", Message: "should be a non negative integer"},
+		{Row: 6, Field: "price", Message: "should be a non negative integer"},
+	}
+	if len(rowErrors) != len(want) {
+		t.Fatalf("got %d row errors, want %d", len(rowErrors), len(want))
+	}
+	for i, e := range rowErrors {
+		if *e != want[i] {
+			t.Errorf("row error %d = %+v, want %+v", i, *e, want[i])
+		}
+	}
+}
+
+func TestParseImportRowsHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		want error
+	}{
+		{"no rows", [][]string{}, ErrImportEmpty},
+		{"header only", [][]string{{"name", "qty", "price"}}, ErrImportEmpty},
+		{"missing column", [][]string{{"name", "qty"}, {"Laptop", "1"}}, ErrImportHeader},
+		{"wrong order", [][]string{{"qty", "name", "price"}, {"1", "Laptop", "1"}}, ErrImportHeader},
+	}
+	for _, tt := range tests {
+		_, _, err := parseImportRows(tt.rows)
+		if err != tt.want {
+			t.Errorf("%s: parseImportRows() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/riskiramdan/evermos/internal/data"
 	"github.com/riskiramdan/evermos/internal/product"
@@ -18,30 +19,15 @@ type PostgresStorage struct {
 func (s *PostgresStorage) FindAll(ctx context.Context, params *product.FindAllProductsParams) ([]*product.Product, *types.Error) {
 
 	products := []*product.Product{}
-	where := `"deleted_at" IS NULL`
+	where, args := productFilter(params)
 
-	if params.ProductID != 0 {
-		where += ` AND "id" = :productId`
-	}
-	if params.Name != "" {
-		where += ` AND "name" = :name`
-	}
-	if params.Search != "" {
-		where += ` AND "name" ILIKE :search`
-	}
 	if params.Page != 0 && params.Limit != 0 {
 		where = fmt.Sprintf(`%s ORDER BY "id" DESC LIMIT :limit OFFSET :offset`, where)
 	} else {
 		where = fmt.Sprintf(`%s ORDER BY "id" DESC`, where)
 	}
 
-	err := s.Storage.Where(ctx, &products, where, map[string]interface{}{
-		"productId": params.ProductID,
-		"name":      params.Name,
-		"limit":     params.Limit,
-		"search":    "%" + params.Search + "%",
-		"offset":    ((params.Page - 1) * params.Limit),
-	})
+	err := s.Storage.Where(ctx, &products, where, args)
 	if err != nil {
 		return nil, &types.Error{
 			Path:    ".ProductPostgresStorage->FindAll()",
@@ -54,6 +40,85 @@ func (s *PostgresStorage) FindAll(ctx context.Context, params *product.FindAllPr
 	return products, nil
 }
 
+// FindAllByCursor find all products paginated by cursor
+func (s *PostgresStorage) FindAllByCursor(ctx context.Context, params *product.FindAllProductsParams) ([]*product.Product, *data.CursorPage, *types.Error) {
+
+	products := []*product.Product{}
+	where, args := productFilter(params)
+
+	cursors, err := s.Storage.WhereKeyset(ctx, &products, where, args, &data.KeysetParams{
+		SortField: "created_at",
+		Cursor:    params.Cursor,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		return nil, nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindAllByCursor()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return products, cursors, nil
+}
+
+// Count count products
+func (s *PostgresStorage) Count(ctx context.Context, params *product.FindAllProductsParams) (int, *types.Error) {
+	where, args := productFilter(params)
+
+	var count int
+	var err error
+	if params.EstimateCount {
+		count, err = s.Storage.CountEstimate(ctx, where, args)
+	} else {
+		count, err = s.Storage.Count(ctx, where, args)
+	}
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".ProductPostgresStorage->Count()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return count, nil
+}
+
+func productFilter(params *product.FindAllProductsParams) (string, map[string]interface{}) {
+	where := `"deleted_at" IS NULL`
+	if params.Deleted {
+		where = `"deleted_at" IS NOT NULL`
+	}
+	if params.DeletedBefore != nil {
+		where += ` AND "deleted_at" < :deletedBefore`
+	}
+
+	if params.ProductID != 0 {
+		where += ` AND "id" = :productId`
+	}
+	if params.Name != "" {
+		where += ` AND "name" = :name`
+	}
+	if params.Slug != "" {
+		where += ` AND "slug" = :slug`
+	}
+	if params.Search != "" {
+		where += ` AND "name" ILIKE :search`
+	}
+
+	return where, map[string]interface{}{
+		"productId":     params.ProductID,
+		"name":          params.Name,
+		"slug":          params.Slug,
+		"limit":         params.Limit,
+		"search":        "%" + params.Search + "%",
+		"offset":        ((params.Page - 1) * params.Limit),
+		"deletedBefore": params.DeletedBefore,
+	}
+}
+
 // FindByID find product by its id
 func (s *PostgresStorage) FindByID(ctx context.Context, productID int) (*product.Product, *types.Error) {
 	products, err := s.FindAll(ctx, &product.FindAllProductsParams{
@@ -76,6 +141,46 @@ func (s *PostgresStorage) FindByID(ctx context.Context, productID int) (*product
 	return products[0], nil
 }
 
+// FindBySlug find product by its current slug
+func (s *PostgresStorage) FindBySlug(ctx context.Context, slug string) (*product.Product, *types.Error) {
+	products, err := s.FindAll(ctx, &product.FindAllProductsParams{
+		Slug: slug,
+	})
+	if err != nil {
+		err.Path = ".ProductPostgresStorage->FindBySlug()" + err.Path
+		return nil, err
+	}
+
+	if len(products) < 1 {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindBySlug()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return products[0], nil
+}
+
+// CountBySlug count products using the slug other than the given one, deleted products included
+func (s *PostgresStorage) CountBySlug(ctx context.Context, slug string, exceptProductID int) (int, *types.Error) {
+	count, err := s.Storage.Count(ctx, `"slug" = :slug AND "id" != :productId`, map[string]interface{}{
+		"slug":      slug,
+		"productId": exceptProductID,
+	})
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".ProductPostgresStorage->CountBySlug()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return count, nil
+}
+
 // Insert insert product
 func (s *PostgresStorage) Insert(ctx context.Context, product *product.Product) (*product.Product, *types.Error) {
 	err := s.Storage.Insert(ctx, product)
@@ -106,6 +211,39 @@ func (s *PostgresStorage) Update(ctx context.Context, product *product.Product)
 	return product, nil
 }
 
+// UpdatePrice sets the price of the product, in the trash or not, unless the product changed after the time.
+// The product is checked and updated by a single statement.
+func (s *PostgresStorage) UpdatePrice(ctx context.Context, productID int, price int, unchangedSince time.Time) (*product.Product, *types.Error) {
+	products := []*product.Product{}
+
+	err := s.Storage.UpdateWhere(ctx, &products, `"price" = :price`,
+		`"id" = :id AND ("updated_at" IS NULL OR "updated_at" <= :unchangedSince)`,
+		map[string]interface{}{
+			"id":             productID,
+			"price":          price,
+			"unchangedSince": unchangedSince,
+		})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->UpdatePrice()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	if len(products) < 1 {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->UpdatePrice()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return products[0], nil
+}
+
 // Delete delete a product
 func (s *PostgresStorage) Delete(ctx context.Context, productID int) *types.Error {
 	err := s.Storage.Delete(ctx, productID)
@@ -121,6 +259,59 @@ func (s *PostgresStorage) Delete(ctx context.Context, productID int) *types.Erro
 	return nil
 }
 
+// FindDeletedByID find soft deleted product by its id
+func (s *PostgresStorage) FindDeletedByID(ctx context.Context, productID int) (*product.Product, *types.Error) {
+	products, err := s.FindAll(ctx, &product.FindAllProductsParams{
+		ProductID: productID,
+		Deleted:   true,
+	})
+	if err != nil {
+		err.Path = ".ProductPostgresStorage->FindDeletedByID()" + err.Path
+		return nil, err
+	}
+
+	if len(products) < 1 || products[0].ID != productID {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindDeletedByID()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return products[0], nil
+}
+
+// Restore restore a soft deleted product
+func (s *PostgresStorage) Restore(ctx context.Context, productID int) *types.Error {
+	err := s.Storage.Restore(ctx, productID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".ProductPostgresStorage->Restore()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
+// DeleteHard permanently delete a product
+func (s *PostgresStorage) DeleteHard(ctx context.Context, productID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, productID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".ProductPostgresStorage->DeleteHard()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
 // NewPostgresStorage creates new product repository service
 func NewPostgresStorage(
 	storage data.GenericStorage,
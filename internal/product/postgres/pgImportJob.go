@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// FindImportJobByID find import job by its id
+func (s *PostgresStorage) FindImportJobByID(ctx context.Context, importJobID int) (*product.ImportJob, *types.Error) {
+	importJob := &product.ImportJob{}
+	err := s.Storage.Single(ctx, importJob, `"deleted_at" IS NULL AND "id" = :id`, map[string]interface{}{
+		"id": importJobID,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindImportJobByID()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return importJob, nil
+}
+
+// InsertImportJob insert import job
+func (s *PostgresStorage) InsertImportJob(ctx context.Context, importJob *product.ImportJob) (*product.ImportJob, *types.Error) {
+	err := s.Storage.Insert(ctx, importJob)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->InsertImportJob()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return importJob, nil
+}
+
+// UpdateImportJob update import job
+func (s *PostgresStorage) UpdateImportJob(ctx context.Context, importJob *product.ImportJob) (*product.ImportJob, *types.Error) {
+	err := s.Storage.Update(ctx, importJob)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->UpdateImportJob()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return importJob, nil
+}
@@ -13,23 +13,15 @@ import (
 func (s *PostgresStorage) FindAllOrderHistory(ctx context.Context, params *product.FindAllOrderHistorysParams) ([]*product.OrderHistory, *types.Error) {
 
 	orderHistory := []*product.OrderHistory{}
-	where := `"deleted_at" IS NULL`
+	where, args := orderHistoryFilter(params)
 
-	if params.ID != 0 {
-		where += ` AND "id" = :id`
-	}
 	if params.Page != 0 && params.Limit != 0 {
 		where = fmt.Sprintf(`%s ORDER BY "id" DESC LIMIT :limit OFFSET :offset`, where)
 	} else {
 		where = fmt.Sprintf(`%s ORDER BY "id" DESC`, where)
 	}
 
-	err := s.Storage.Where(ctx, &orderHistory, where, map[string]interface{}{
-		"id":     params.ID,
-		"limit":  params.Limit,
-		"search": "%" + params.Search + "%",
-		"offset": ((params.Page - 1) * params.Limit),
-	})
+	err := s.Storage.Where(ctx, &orderHistory, where, args)
 	if err != nil {
 		return nil, &types.Error{
 			Path:    ".ProductPostgresStorage->FindAll()",
@@ -42,6 +34,71 @@ func (s *PostgresStorage) FindAllOrderHistory(ctx context.Context, params *produ
 	return orderHistory, nil
 }
 
+// FindAllOrderHistoryByCursor find all Order History paginated by cursor
+func (s *PostgresStorage) FindAllOrderHistoryByCursor(ctx context.Context, params *product.FindAllOrderHistorysParams) ([]*product.OrderHistory, *data.CursorPage, *types.Error) {
+
+	orderHistory := []*product.OrderHistory{}
+	where, args := orderHistoryFilter(params)
+
+	cursors, err := s.Storage.WhereKeyset(ctx, &orderHistory, where, args, &data.KeysetParams{
+		SortField: "created_at",
+		Cursor:    params.Cursor,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		return nil, nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindAllOrderHistoryByCursor()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return orderHistory, cursors, nil
+}
+
+// CountOrderHistory count Order History
+func (s *PostgresStorage) CountOrderHistory(ctx context.Context, params *product.FindAllOrderHistorysParams) (int, *types.Error) {
+	where, args := orderHistoryFilter(params)
+
+	var count int
+	var err error
+	if params.EstimateCount {
+		count, err = s.Storage.CountEstimate(ctx, where, args)
+	} else {
+		count, err = s.Storage.Count(ctx, where, args)
+	}
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".ProductPostgresStorage->CountOrderHistory()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return count, nil
+}
+
+func orderHistoryFilter(params *product.FindAllOrderHistorysParams) (string, map[string]interface{}) {
+	where := `"deleted_at" IS NULL`
+
+	if params.ID != 0 {
+		where += ` AND "id" = :id`
+	}
+	if params.UserID != 0 {
+		where += ` AND "user_id" = :userId`
+	}
+
+	return where, map[string]interface{}{
+		"id":     params.ID,
+		"userId": params.UserID,
+		"limit":  params.Limit,
+		"search": "%" + params.Search + "%",
+		"offset": ((params.Page - 1) * params.Limit),
+	}
+}
+
 // FindOrderHistoryByID find order history by its id
 func (s *PostgresStorage) FindOrderHistoryByID(ctx context.Context, orderHistoryID int) (*product.OrderHistory, *types.Error) {
 	products, err := s.FindAllOrderHistory(ctx, &product.FindAllOrderHistorysParams{
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// FindAllProductImage find all images of a product ordered by position
+func (s *PostgresStorage) FindAllProductImage(ctx context.Context, productID int) ([]*product.ProductImage, *types.Error) {
+
+	productImages := []*product.ProductImage{}
+	where := `"deleted_at" IS NULL AND "product_id" = :productId ORDER BY "position" ASC, "id" ASC`
+
+	err := s.Storage.Where(ctx, &productImages, where, map[string]interface{}{
+		"productId": productID,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindAllProductImage()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return productImages, nil
+}
+
+// InsertProductImage insert product image
+func (s *PostgresStorage) InsertProductImage(ctx context.Context, productImage *product.ProductImage) (*product.ProductImage, *types.Error) {
+	err := s.Storage.Insert(ctx, productImage)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->InsertProductImage()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return productImage, nil
+}
+
+// UpdateProductImage update product image
+func (s *PostgresStorage) UpdateProductImage(ctx context.Context, productImage *product.ProductImage) (*product.ProductImage, *types.Error) {
+	err := s.Storage.Update(ctx, productImage)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->UpdateProductImage()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return productImage, nil
+}
+
+// DeleteProductImage delete a product image permanently, its blobs are removed by the service
+func (s *PostgresStorage) DeleteProductImage(ctx context.Context, productImageID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, productImageID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".ProductPostgresStorage->DeleteProductImage()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// FindAllProductPrice find all prices, latest effective first
+func (s *PostgresStorage) FindAllProductPrice(ctx context.Context, params *product.FindAllProductPricesParams) ([]*product.ProductPrice, *types.Error) {
+	productPrices := []*product.ProductPrice{}
+
+	where := `"deleted_at" IS NULL`
+	if params.ID != 0 {
+		where += ` AND "id" = :id`
+	}
+	if params.ProductID != 0 {
+		where += ` AND "product_id" = :productId`
+	}
+	if params.Scheduled {
+		where += ` AND "applied_at" IS NULL`
+	}
+	if params.EffectiveBefore != nil {
+		where += ` AND "effective_from" <= :effectiveBefore`
+	}
+
+	err := s.Storage.Where(ctx, &productPrices, where+` ORDER BY "effective_from" DESC, "id" DESC`, map[string]interface{}{
+		"id":              params.ID,
+		"productId":       params.ProductID,
+		"effectiveBefore": params.EffectiveBefore,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindAllProductPrice()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return productPrices, nil
+}
+
+// InsertProductPrice insert product price
+func (s *PostgresStorage) InsertProductPrice(ctx context.Context, productPrice *product.ProductPrice) (*product.ProductPrice, *types.Error) {
+	err := s.Storage.Insert(ctx, productPrice)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->InsertProductPrice()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return productPrice, nil
+}
+
+// UpdateProductPrice update product price
+func (s *PostgresStorage) UpdateProductPrice(ctx context.Context, productPrice *product.ProductPrice) (*product.ProductPrice, *types.Error) {
+	err := s.Storage.Update(ctx, productPrice)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->UpdateProductPrice()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return productPrice, nil
+}
+
+// ApplyProductPrice marks the scheduled price as applied at the time once it is effective.
+// The price is checked and updated by a single statement, of concurrent runs only one finds it.
+func (s *PostgresStorage) ApplyProductPrice(ctx context.Context, productPriceID int, appliedAt time.Time) (*product.ProductPrice, *types.Error) {
+	productPrices := []*product.ProductPrice{}
+
+	err := s.Storage.UpdateWhere(ctx, &productPrices, `"applied_at" = :appliedAt`,
+		`"id" = :id AND "applied_at" IS NULL AND "effective_from" <= :appliedAt AND "deleted_at" IS NULL`,
+		map[string]interface{}{
+			"id":        productPriceID,
+			"appliedAt": appliedAt,
+		})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->ApplyProductPrice()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	if len(productPrices) < 1 {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->ApplyProductPrice()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return productPrices[0], nil
+}
+
+// DeleteProductPrice delete a scheduled product price
+func (s *PostgresStorage) DeleteProductPrice(ctx context.Context, productPriceID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, productPriceID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".ProductPostgresStorage->DeleteProductPrice()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
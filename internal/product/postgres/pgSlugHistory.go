@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/product"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// FindSlugHistoryBySlug find slug history by the previous slug
+func (s *PostgresStorage) FindSlugHistoryBySlug(ctx context.Context, slug string) (*product.SlugHistory, *types.Error) {
+	slugHistory := &product.SlugHistory{}
+	err := s.Storage.Single(ctx, slugHistory, `"deleted_at" IS NULL AND "slug" = :slug`, map[string]interface{}{
+		"slug": slug,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->FindSlugHistoryBySlug()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return slugHistory, nil
+}
+
+// InsertSlugHistory insert slug history
+func (s *PostgresStorage) InsertSlugHistory(ctx context.Context, slugHistory *product.SlugHistory) (*product.SlugHistory, *types.Error) {
+	err := s.Storage.Insert(ctx, slugHistory)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".ProductPostgresStorage->InsertSlugHistory()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return slugHistory, nil
+}
+
+// DeleteSlugHistory delete slug history, the row is removed so the slug can be recorded again
+func (s *PostgresStorage) DeleteSlugHistory(ctx context.Context, slugHistoryID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, slugHistoryID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".ProductPostgresStorage->DeleteSlugHistory()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// Price statuses
+const (
+	PriceApplied   = "applied"
+	PriceScheduled = "scheduled"
+)
+
+// Errors
+var (
+	ErrPriceInvalid        = errors.New("Price should not be negative")
+	ErrPriceNotInFuture    = errors.New("Effective from should be in the future")
+	ErrPriceAlreadyApplied = errors.New("Price change was already applied")
+)
+
+// ProductPrice represents a price of a product and since when it is effective.
+// Scheduled prices are not applied yet.
+type ProductPrice struct {
+	ID            int        `json:"id" db:"id"`
+	ProductID     int        `json:"productId" db:"product_id"`
+	Price         int        `json:"price" db:"price"`
+	EffectiveFrom time.Time  `json:"effectiveFrom" db:"effective_from"`
+	AppliedAt     *time.Time `json:"appliedAt" db:"applied_at"`
+	Status        string     `json:"status" db:"-"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy     *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt     *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy     *string    `json:"updatedBy" db:"updated_by"`
+}
+
+//FindAllProductPricesParams params for find all
+type FindAllProductPricesParams struct {
+	ID              int        `json:"id"`
+	ProductID       int        `json:"productId"`
+	Scheduled       bool       `json:"scheduled"`
+	EffectiveBefore *time.Time `json:"effectiveBefore"`
+}
+
+// SchedulePriceParams represent the http request data for scheduling a price change
+type SchedulePriceParams struct {
+	Price         int       `json:"price" validate:"gte=0"`
+	EffectiveFrom time.Time `json:"effectiveFrom" validate:"required"`
+}
+
+func setPriceStatus(prices []*ProductPrice) {
+	for _, price := range prices {
+		price.Status = PriceScheduled
+		if price.AppliedAt != nil {
+			price.Status = PriceApplied
+		}
+	}
+}
+
+// recordPrice stores the new price of the product as effective now
+func (s *Service) recordPrice(ctx context.Context, productID int, price int) *types.Error {
+	now := time.Now()
+	_, err := s.priceStorage.InsertProductPrice(ctx, &ProductPrice{
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: now,
+		AppliedAt:     &now,
+		CreatedAt:     now,
+		UpdatedAt:     &now,
+	})
+	if err != nil {
+		err.Path = ".ProductService->recordPrice()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// effectivePrice returns the price of the product at the time,
+// taking the scheduled prices not yet applied by the scheduler into account
+func (s *Service) effectivePrice(ctx context.Context, product *Product, at time.Time) (int, *types.Error) {
+	prices, err := s.priceStorage.FindAllProductPrice(ctx, &FindAllProductPricesParams{
+		ProductID:       product.ID,
+		Scheduled:       true,
+		EffectiveBefore: &at,
+	})
+	if err != nil {
+		err.Path = ".ProductService->effectivePrice()" + err.Path
+		return 0, err
+	}
+
+	// prices are sorted by effective from, latest first
+	if len(prices) > 0 {
+		return prices[0].Price, nil
+	}
+	return product.Price, nil
+}
+
+// ListProductPrices is listing the price history and the scheduled prices of a product
+func (s *Service) ListProductPrices(ctx context.Context, productID int) ([]*ProductPrice, *types.Error) {
+	_, err := s.GetProduct(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->ListProductPrices()" + err.Path
+		return nil, err
+	}
+
+	prices, err := s.priceStorage.FindAllProductPrice(ctx, &FindAllProductPricesParams{
+		ProductID: productID,
+	})
+	if err != nil {
+		err.Path = ".ProductService->ListProductPrices()" + err.Path
+		return nil, err
+	}
+	setPriceStatus(prices)
+
+	return prices, nil
+}
+
+// SchedulePrice schedules a price change of the product, applied once the effective time is reached
+func (s *Service) SchedulePrice(ctx context.Context, productID int, params *SchedulePriceParams) (*ProductPrice, *types.Error) {
+	_, err := s.GetProduct(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->SchedulePrice()" + err.Path
+		return nil, err
+	}
+
+	if params.Price < 0 {
+		return nil, &types.Error{
+			Path:    ".ProductService->SchedulePrice()",
+			Message: ErrPriceInvalid.Error(),
+			Error:   ErrPriceInvalid,
+			Type:    "validation-error",
+		}
+	}
+	now := time.Now()
+	if !params.EffectiveFrom.After(now) {
+		return nil, &types.Error{
+			Path:    ".ProductService->SchedulePrice()",
+			Message: ErrPriceNotInFuture.Error(),
+			Error:   ErrPriceNotInFuture,
+			Type:    "validation-error",
+		}
+	}
+
+	price, err := s.priceStorage.InsertProductPrice(ctx, &ProductPrice{
+		ProductID:     productID,
+		Price:         params.Price,
+		EffectiveFrom: params.EffectiveFrom,
+		CreatedAt:     now,
+		UpdatedAt:     &now,
+	})
+	if err != nil {
+		err.Path = ".ProductService->SchedulePrice()" + err.Path
+		return nil, err
+	}
+	setPriceStatus([]*ProductPrice{price})
+
+	return price, nil
+}
+
+// CancelScheduledPrice cancels a price change which is not applied yet
+func (s *Service) CancelScheduledPrice(ctx context.Context, productID int, priceID int) *types.Error {
+	prices, err := s.priceStorage.FindAllProductPrice(ctx, &FindAllProductPricesParams{
+		ID:        priceID,
+		ProductID: productID,
+	})
+	if err != nil {
+		err.Path = ".ProductService->CancelScheduledPrice()" + err.Path
+		return err
+	}
+	if len(prices) < 1 {
+		return &types.Error{
+			Path:    ".ProductService->CancelScheduledPrice()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "validation-error",
+		}
+	}
+	if prices[0].AppliedAt != nil {
+		return &types.Error{
+			Path:    ".ProductService->CancelScheduledPrice()",
+			Message: ErrPriceAlreadyApplied.Error(),
+			Error:   ErrPriceAlreadyApplied,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.priceStorage.DeleteProductPrice(ctx, priceID)
+	if err != nil {
+		err.Path = ".ProductService->CancelScheduledPrice()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// ListDuePrices returns the scheduled prices which became effective, latest first
+func (s *Service) ListDuePrices(ctx context.Context, now time.Time) ([]*ProductPrice, *types.Error) {
+	prices, err := s.priceStorage.FindAllProductPrice(ctx, &FindAllProductPricesParams{
+		Scheduled:       true,
+		EffectiveBefore: &now,
+	})
+	if err != nil {
+		err.Path = ".ProductService->ListDuePrices()" + err.Path
+		return nil, err
+	}
+	setPriceStatus(prices)
+
+	return prices, nil
+}
+
+// ApplyScheduledPrice applies the scheduled price which became effective and returns whether the product took it.
+// The price leaves the schedule either way. The product keeps its price when it changed after the price became
+// effective, so neither a later price nor an edit made meanwhile is overwritten, and a product in the trash
+// gets the price for when it is restored. A price applied by a concurrent run or cancelled is skipped.
+func (s *Service) ApplyScheduledPrice(ctx context.Context, priceID int, now time.Time) (bool, *types.Error) {
+	price, err := s.priceStorage.ApplyProductPrice(ctx, priceID, now)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return false, nil
+		}
+		err.Path = ".ProductService->ApplyScheduledPrice()" + err.Path
+		return false, err
+	}
+
+	_, err = s.productStorage.UpdatePrice(ctx, price.ProductID, price.Price, price.EffectiveFrom)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return false, nil
+		}
+		err.Path = ".ProductService->ApplyScheduledPrice()" + err.Path
+		return false, err
+	}
+
+	return true, nil
+}
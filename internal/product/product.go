@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/blob"
 	"github.com/riskiramdan/evermos/internal/data"
 	"github.com/riskiramdan/evermos/internal/types"
 )
@@ -27,10 +28,15 @@ var (
 type Product struct {
 	ID        int        `json:"id" db:"id"`
 	Name      string     `json:"name" db:"name"`
+	Slug      string     `json:"slug" db:"slug"`
 	Qty       int        `json:"qty" db:"qty"`
 	Price     int        `json:"price" db:"price"`
 	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
 	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	DeletedBy *string    `json:"deletedBy,omitempty" db:"deleted_by"`
 }
 
 // OrderHistory OrderHistory
@@ -41,72 +47,152 @@ type OrderHistory struct {
 	Qty       int        `json:"qty" db:"qty"`
 	Price     int        `json:"price" db:"price"`
 	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
 	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
 }
 
 //FindAllProductsParams params for find all
 type FindAllProductsParams struct {
-	Page      int    `json:"page"`
-	Search    string `json:"search"`
-	Limit     int    `json:"limit"`
-	ProductID int    `json:"productId"`
-	Name      string `json:"name"`
+	Page          int        `json:"page"`
+	Search        string     `json:"search"`
+	Limit         int        `json:"limit"`
+	ProductID     int        `json:"productId"`
+	Name          string     `json:"name"`
+	Slug          string     `json:"slug"`
+	Cursor        string     `json:"cursor"`
+	EstimateCount bool       `json:"estimateCount"`
+	Deleted       bool       `json:"deleted"`
+	DeletedBefore *time.Time `json:"deletedBefore"`
 }
 
 //FindAllOrderHistorysParams params for find all
 type FindAllOrderHistorysParams struct {
-	Page   int    `json:"page"`
-	Search string `json:"search"`
-	Limit  int    `json:"limit"`
-	ID     int    `json:"id"`
+	Page          int    `json:"page"`
+	Search        string `json:"search"`
+	Limit         int    `json:"limit"`
+	ID            int    `json:"id"`
+	UserID        int    `json:"userId"`
+	Cursor        string `json:"cursor"`
+	EstimateCount bool   `json:"estimateCount"`
 }
 
 // TransactionProductParams represent the http request data for create product
 type TransactionProductParams struct {
-	Name  string `json:"name"`
-	Qty   int    `json:"qty"`
-	Price int    `json:"price"`
+	Name  string `json:"name" validate:"required,max=80"`
+	Qty   int    `json:"qty" validate:"gte=0"`
+	Price int    `json:"price" validate:"gte=0"`
 }
 
-// TransactionOrderHistorytParams represent the http request data for create order history
+// TransactionOrderHistorytParams represent the http request data for create order history.
+// The price is not sent, the order takes the effective price of the product.
 type TransactionOrderHistorytParams struct {
-	ProductID int `json:"productId"`
-	Qty       int `json:"qty"`
-	Price     int `json:"price"`
+	ProductID int `json:"productId" validate:"required,gt=0"`
+	Qty       int `json:"qty" validate:"required,gt=0"`
 }
 
 // Storage represents the product storage interface
 type Storage interface {
 	FindAll(ctx context.Context, params *FindAllProductsParams) ([]*Product, *types.Error)
+	FindAllByCursor(ctx context.Context, params *FindAllProductsParams) ([]*Product, *data.CursorPage, *types.Error)
+	Count(ctx context.Context, params *FindAllProductsParams) (int, *types.Error)
 	FindByID(ctx context.Context, productID int) (*Product, *types.Error)
+	FindBySlug(ctx context.Context, slug string) (*Product, *types.Error)
+	FindDeletedByID(ctx context.Context, productID int) (*Product, *types.Error)
+	CountBySlug(ctx context.Context, slug string, exceptProductID int) (int, *types.Error)
 	Insert(ctx context.Context, product *Product) (*Product, *types.Error)
 	Update(ctx context.Context, product *Product) (*Product, *types.Error)
+	UpdatePrice(ctx context.Context, productID int, price int, unchangedSince time.Time) (*Product, *types.Error)
 	Delete(ctx context.Context, productID int) *types.Error
+	Restore(ctx context.Context, productID int) *types.Error
+	DeleteHard(ctx context.Context, productID int) *types.Error
 }
 
 // StorageOrderHistory represents the order History storage interface
 type StorageOrderHistory interface {
 	FindAllOrderHistory(ctx context.Context, params *FindAllOrderHistorysParams) ([]*OrderHistory, *types.Error)
+	FindAllOrderHistoryByCursor(ctx context.Context, params *FindAllOrderHistorysParams) ([]*OrderHistory, *data.CursorPage, *types.Error)
+	CountOrderHistory(ctx context.Context, params *FindAllOrderHistorysParams) (int, *types.Error)
 	FindOrderHistoryByID(ctx context.Context, orderHistoryID int) (*OrderHistory, *types.Error)
 	InsertOrderHistory(ctx context.Context, OrderHistory *OrderHistory) (*OrderHistory, *types.Error)
 	UpdateOrderHistory(ctx context.Context, OrderHistory *OrderHistory) (*OrderHistory, *types.Error)
 	DeleteOrderHistory(ctx context.Context, orderHistoryID int) *types.Error
 }
 
+// StorageProductImage represents the product image storage interface
+type StorageProductImage interface {
+	FindAllProductImage(ctx context.Context, productID int) ([]*ProductImage, *types.Error)
+	InsertProductImage(ctx context.Context, productImage *ProductImage) (*ProductImage, *types.Error)
+	UpdateProductImage(ctx context.Context, productImage *ProductImage) (*ProductImage, *types.Error)
+	DeleteProductImage(ctx context.Context, productImageID int) *types.Error
+}
+
+// StorageImportJob represents the product import job storage interface
+type StorageImportJob interface {
+	FindImportJobByID(ctx context.Context, importJobID int) (*ImportJob, *types.Error)
+	InsertImportJob(ctx context.Context, importJob *ImportJob) (*ImportJob, *types.Error)
+	UpdateImportJob(ctx context.Context, importJob *ImportJob) (*ImportJob, *types.Error)
+}
+
+// StorageSlugHistory represents the product slug history storage interface
+type StorageSlugHistory interface {
+	FindSlugHistoryBySlug(ctx context.Context, slug string) (*SlugHistory, *types.Error)
+	InsertSlugHistory(ctx context.Context, slugHistory *SlugHistory) (*SlugHistory, *types.Error)
+	DeleteSlugHistory(ctx context.Context, slugHistoryID int) *types.Error
+}
+
+// StorageProductPrice represents the product price storage interface
+type StorageProductPrice interface {
+	FindAllProductPrice(ctx context.Context, params *FindAllProductPricesParams) ([]*ProductPrice, *types.Error)
+	InsertProductPrice(ctx context.Context, productPrice *ProductPrice) (*ProductPrice, *types.Error)
+	UpdateProductPrice(ctx context.Context, productPrice *ProductPrice) (*ProductPrice, *types.Error)
+	ApplyProductPrice(ctx context.Context, productPriceID int, appliedAt time.Time) (*ProductPrice, *types.Error)
+	DeleteProductPrice(ctx context.Context, productPriceID int) *types.Error
+}
+
 // ServiceInterface represents the product service interface
 type ServiceInterface interface {
 	ListProducts(ctx context.Context, params *FindAllProductsParams) ([]*Product, int, *types.Error)
+	ListProductsByCursor(ctx context.Context, params *FindAllProductsParams) ([]*Product, *data.CursorPage, *types.Error)
 	GetProduct(ctx context.Context, productID int) (*Product, *types.Error)
+	GetProductBySlug(ctx context.Context, slug string) (*Product, *types.Error)
 	CreateProduct(ctx context.Context, params *TransactionProductParams) (*Product, *types.Error)
 	UpdateProduct(ctx context.Context, productID int, params *TransactionProductParams) (*Product, *types.Error)
 	DeleteProduct(ctx context.Context, productID int) *types.Error
+	ListDeletedProducts(ctx context.Context, params *FindAllProductsParams) ([]*Product, int, *types.Error)
+	RestoreProduct(ctx context.Context, productID int) (*Product, *types.Error)
+	PurgeProduct(ctx context.Context, productID int) *types.Error
 	CreateOrder(ctx context.Context, params *TransactionOrderHistorytParams) (*OrderHistory, *types.Error)
+	ListOrders(ctx context.Context, params *FindAllOrderHistorysParams) ([]*OrderHistory, int, *types.Error)
+	ListOrdersByCursor(ctx context.Context, params *FindAllOrderHistorysParams) ([]*OrderHistory, *data.CursorPage, *types.Error)
+	ListProductPrices(ctx context.Context, productID int) ([]*ProductPrice, *types.Error)
+	SchedulePrice(ctx context.Context, productID int, params *SchedulePriceParams) (*ProductPrice, *types.Error)
+	CancelScheduledPrice(ctx context.Context, productID int, priceID int) *types.Error
+	ListDuePrices(ctx context.Context, now time.Time) ([]*ProductPrice, *types.Error)
+	ApplyScheduledPrice(ctx context.Context, priceID int, now time.Time) (bool, *types.Error)
+	ListProductImages(ctx context.Context, productID int) ([]*ProductImage, *types.Error)
+	UploadProductImages(ctx context.Context, productID int, uploads []*ImageUpload) ([]*ProductImage, *types.Error)
+	ReorderProductImages(ctx context.Context, productID int, imageIDs []int) ([]*ProductImage, *types.Error)
+	SetPrimaryProductImage(ctx context.Context, productID int, imageID int) ([]*ProductImage, *types.Error)
+	DeleteProductImage(ctx context.Context, productID int, imageID int) *types.Error
+	ImportTemplate() [][]string
+	ExportProducts(ctx context.Context) ([][]string, *types.Error)
+	ImportProducts(ctx context.Context, rows [][]string) (*ImportResult, *types.Error)
+	CreateImportJob(ctx context.Context, filename string, totalRows int) (*ImportJob, *types.Error)
+	GetImportJob(ctx context.Context, jobID int) (*ImportJob, *types.Error)
+	StartImportJob(ctx context.Context, job *ImportJob) (*ImportJob, *types.Error)
+	FinishImportJob(ctx context.Context, job *ImportJob, result *ImportResult, importErr *types.Error) (*ImportJob, *types.Error)
 }
 
 // Service is the domain logic implementation of product Service interface
 type Service struct {
-	productStorage Storage
-	orderStorage   StorageOrderHistory
+	productStorage   Storage
+	orderStorage     StorageOrderHistory
+	imageStorage     StorageProductImage
+	importJobStorage StorageImportJob
+	slugStorage      StorageSlugHistory
+	priceStorage     StorageProductPrice
+	blobStore        blob.Store
 }
 
 // ListProducts is listing products
@@ -116,15 +202,24 @@ func (s *Service) ListProducts(ctx context.Context, params *FindAllProductsParam
 		err.Path = ".ProductService->ListProducts()" + err.Path
 		return nil, 0, err
 	}
-	params.Page = 0
-	params.Limit = 0
-	allProducts, err := s.productStorage.FindAll(ctx, params)
+	count, err := s.productStorage.Count(ctx, params)
 	if err != nil {
 		err.Path = ".ProductService->ListProducts()" + err.Path
 		return nil, 0, err
 	}
 
-	return products, len(allProducts), nil
+	return products, count, nil
+}
+
+// ListProductsByCursor is listing products paginated by cursor
+func (s *Service) ListProductsByCursor(ctx context.Context, params *FindAllProductsParams) ([]*Product, *data.CursorPage, *types.Error) {
+	products, cursors, err := s.productStorage.FindAllByCursor(ctx, params)
+	if err != nil {
+		err.Path = ".ProductService->ListProductsByCursor()" + err.Path
+		return nil, nil, err
+	}
+
+	return products, cursors, nil
 }
 
 // GetProduct is get product
@@ -156,10 +251,17 @@ func (s *Service) CreateProduct(ctx context.Context, params *TransactionProductP
 		}
 	}
 
+	productSlug, errType := s.uniqueSlug(ctx, params.Name, 0)
+	if errType != nil {
+		errType.Path = ".ProductService->CreateProduct()" + errType.Path
+		return nil, errType
+	}
+
 	now := time.Now()
 
 	product := &Product{
 		Name:      params.Name,
+		Slug:      productSlug,
 		Qty:       params.Qty,
 		Price:     params.Price,
 		CreatedAt: now,
@@ -172,6 +274,12 @@ func (s *Service) CreateProduct(ctx context.Context, params *TransactionProductP
 		return nil, errType
 	}
 
+	errType = s.recordPrice(ctx, product.ID, product.Price)
+	if errType != nil {
+		errType.Path = ".ProductService->CreateProduct()" + errType.Path
+		return nil, errType
+	}
+
 	return product, nil
 }
 
@@ -200,10 +308,21 @@ func (s *Service) UpdateProduct(ctx context.Context, productID int, params *Tran
 			}
 		}
 	}
-	if params.Name != "" {
+	if params.Name != "" && params.Name != product.Name {
+		productSlug, err := s.uniqueSlug(ctx, params.Name, product.ID)
+		if err != nil {
+			err.Path = ".ProductService->UpdateProduct()" + err.Path
+			return nil, err
+		}
+		err = s.changeSlug(ctx, product, productSlug)
+		if err != nil {
+			err.Path = ".ProductService->UpdateProduct()" + err.Path
+			return nil, err
+		}
 		product.Name = params.Name
 	}
 
+	priceChanged := product.Price != params.Price
 	product.Qty = params.Qty
 	product.Price = params.Price
 
@@ -213,6 +332,14 @@ func (s *Service) UpdateProduct(ctx context.Context, productID int, params *Tran
 		return nil, err
 	}
 
+	if priceChanged {
+		err = s.recordPrice(ctx, product.ID, product.Price)
+		if err != nil {
+			err.Path = ".ProductService->UpdateProduct()" + err.Path
+			return nil, err
+		}
+	}
+
 	return product, nil
 }
 
@@ -243,8 +370,18 @@ func (s *Service) CreateOrder(ctx context.Context, params *TransactionOrderHisto
 		}
 	}
 
+	now := time.Now()
+
+	// the order keeps the price effective at purchase time, later price changes do not affect it
+	price, errType := s.effectivePrice(ctx, product, now)
+	if errType != nil {
+		errType.Path = ".ProductService->CreateOrder()" + errType.Path
+		return nil, errType
+	}
+
 	product, errType = s.UpdateProduct(ctx, params.ProductID, &TransactionProductParams{
-		Qty: product.Qty - params.Qty,
+		Qty:   product.Qty - params.Qty,
+		Price: product.Price,
 	})
 	if errType != nil {
 		errType.Path = ".ProductService->CreateOrder()" + errType.Path
@@ -257,13 +394,11 @@ func (s *Service) CreateOrder(ctx context.Context, params *TransactionOrderHisto
 
 	*/
 
-	now := time.Now()
-
 	orderHistory, errType := s.orderStorage.InsertOrderHistory(ctx, &OrderHistory{
 		ProductID: params.ProductID,
 		UserID:    appcontext.UserID(ctx),
 		Qty:       params.Qty,
-		Price:     params.Price,
+		Price:     price,
 		CreatedAt: now,
 		UpdatedAt: &now,
 	})
@@ -276,13 +411,50 @@ func (s *Service) CreateOrder(ctx context.Context, params *TransactionOrderHisto
 	return orderHistory, nil
 }
 
+// ListOrders is listing orders
+func (s *Service) ListOrders(ctx context.Context, params *FindAllOrderHistorysParams) ([]*OrderHistory, int, *types.Error) {
+	orders, err := s.orderStorage.FindAllOrderHistory(ctx, params)
+	if err != nil {
+		err.Path = ".ProductService->ListOrders()" + err.Path
+		return nil, 0, err
+	}
+	count, err := s.orderStorage.CountOrderHistory(ctx, params)
+	if err != nil {
+		err.Path = ".ProductService->ListOrders()" + err.Path
+		return nil, 0, err
+	}
+
+	return orders, count, nil
+}
+
+// ListOrdersByCursor is listing orders paginated by cursor
+func (s *Service) ListOrdersByCursor(ctx context.Context, params *FindAllOrderHistorysParams) ([]*OrderHistory, *data.CursorPage, *types.Error) {
+	orders, cursors, err := s.orderStorage.FindAllOrderHistoryByCursor(ctx, params)
+	if err != nil {
+		err.Path = ".ProductService->ListOrdersByCursor()" + err.Path
+		return nil, nil, err
+	}
+
+	return orders, cursors, nil
+}
+
 // NewService creates a new product AppService
 func NewService(
 	productStorage Storage,
 	orderStorage StorageOrderHistory,
+	imageStorage StorageProductImage,
+	importJobStorage StorageImportJob,
+	slugStorage StorageSlugHistory,
+	priceStorage StorageProductPrice,
+	blobStore blob.Store,
 ) *Service {
 	return &Service{
-		productStorage: productStorage,
-		orderStorage:   orderStorage,
+		productStorage:   productStorage,
+		orderStorage:     orderStorage,
+		imageStorage:     imageStorage,
+		importJobStorage: importJobStorage,
+		slugStorage:      slugStorage,
+		priceStorage:     priceStorage,
+		blobStore:        blobStore,
 	}
 }
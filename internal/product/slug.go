@@ -0,0 +1,131 @@
+package product
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// maxSlugLength matches the size of the slug column, leaving room for the collision suffix
+const maxSlugLength = 90
+
+// SlugHistory represents a previous slug of a product, kept so old links keep working
+type SlugHistory struct {
+	ID        int        `json:"id" db:"id"`
+	ProductID int        `json:"productId" db:"product_id"`
+	Slug      string     `json:"slug" db:"slug"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// baseSlug builds the slug of the product name.
+// Numeric slugs are prefixed so they can not be mistaken for a product id.
+func baseSlug(name string) string {
+	base := slug.Make(name)
+	if len(base) > maxSlugLength {
+		base = base[:maxSlugLength]
+	}
+	if base == "" {
+		return "product"
+	}
+	if _, err := strconv.Atoi(base); err == nil {
+		return "product-" + base
+	}
+	return base
+}
+
+// uniqueSlug returns the first free slug for the product name, suffixing "-2", "-3", ... on collision.
+// A slug is taken when another product currently uses it or used it before.
+func (s *Service) uniqueSlug(ctx context.Context, name string, productID int) (string, *types.Error) {
+	base := baseSlug(name)
+	candidate := base
+	for i := 2; ; i++ {
+		count, err := s.productStorage.CountBySlug(ctx, candidate, productID)
+		if err != nil {
+			err.Path = ".ProductService->uniqueSlug()" + err.Path
+			return "", err
+		}
+
+		if count == 0 {
+			history, err := s.slugStorage.FindSlugHistoryBySlug(ctx, candidate)
+			if err != nil && err.Error != data.ErrNotFound {
+				err.Path = ".ProductService->uniqueSlug()" + err.Path
+				return "", err
+			}
+			if history == nil || history.ProductID == productID {
+				return candidate, nil
+			}
+		}
+
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// changeSlug gives the product a new slug and keeps the current one in its history
+func (s *Service) changeSlug(ctx context.Context, product *Product, newSlug string) *types.Error {
+	if newSlug == product.Slug {
+		return nil
+	}
+
+	// the product takes back one of its previous slugs
+	history, err := s.slugStorage.FindSlugHistoryBySlug(ctx, newSlug)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".ProductService->changeSlug()" + err.Path
+		return err
+	}
+	if history != nil {
+		err = s.slugStorage.DeleteSlugHistory(ctx, history.ID)
+		if err != nil {
+			err.Path = ".ProductService->changeSlug()" + err.Path
+			return err
+		}
+	}
+
+	now := time.Now()
+	_, err = s.slugStorage.InsertSlugHistory(ctx, &SlugHistory{
+		ProductID: product.ID,
+		Slug:      product.Slug,
+		CreatedAt: now,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		err.Path = ".ProductService->changeSlug()" + err.Path
+		return err
+	}
+
+	product.Slug = newSlug
+	return nil
+}
+
+// GetProductBySlug is get product by its current or a previous slug.
+// The returned product slug differs from the requested one when it was found by a previous slug.
+func (s *Service) GetProductBySlug(ctx context.Context, productSlug string) (*Product, *types.Error) {
+	product, err := s.productStorage.FindBySlug(ctx, productSlug)
+	if err == nil {
+		return product, nil
+	}
+	if err.Error != data.ErrNotFound {
+		err.Path = ".ProductService->GetProductBySlug()" + err.Path
+		return nil, err
+	}
+
+	history, err := s.slugStorage.FindSlugHistoryBySlug(ctx, productSlug)
+	if err != nil {
+		err.Path = ".ProductService->GetProductBySlug()" + err.Path
+		return nil, err
+	}
+
+	product, err = s.productStorage.FindByID(ctx, history.ProductID)
+	if err != nil {
+		err.Path = ".ProductService->GetProductBySlug()" + err.Path
+		return nil, err
+	}
+
+	return product, nil
+}
@@ -0,0 +1,90 @@
+package product
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// ListDeletedProducts is listing soft deleted products
+func (s *Service) ListDeletedProducts(ctx context.Context, params *FindAllProductsParams) ([]*Product, int, *types.Error) {
+	params.Deleted = true
+
+	products, err := s.productStorage.FindAll(ctx, params)
+	if err != nil {
+		err.Path = ".ProductService->ListDeletedProducts()" + err.Path
+		return nil, 0, err
+	}
+	count, err := s.productStorage.Count(ctx, params)
+	if err != nil {
+		err.Path = ".ProductService->ListDeletedProducts()" + err.Path
+		return nil, 0, err
+	}
+
+	return products, count, nil
+}
+
+// RestoreProduct restores a soft deleted product.
+// It fails when a live product took the name in the meantime.
+func (s *Service) RestoreProduct(ctx context.Context, productID int) (*Product, *types.Error) {
+	product, err := s.productStorage.FindDeletedByID(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->RestoreProduct()" + err.Path
+		return nil, err
+	}
+
+	products, _, err := s.ListProducts(ctx, &FindAllProductsParams{
+		Name: product.Name,
+	})
+	if err != nil {
+		err.Path = ".ProductService->RestoreProduct()" + err.Path
+		return nil, err
+	}
+	if len(products) > 0 {
+		return nil, &types.Error{
+			Path:    ".ProductService->RestoreProduct()",
+			Message: ErrProductAlreadyExists.Error(),
+			Error:   ErrProductAlreadyExists,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.productStorage.Restore(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->RestoreProduct()" + err.Path
+		return nil, err
+	}
+
+	return s.GetProduct(ctx, productID)
+}
+
+// PurgeProduct permanently deletes a soft deleted product with its images,
+// the image blobs are deleted once the purge is committed. Products which were ordered can not be purged.
+func (s *Service) PurgeProduct(ctx context.Context, productID int) *types.Error {
+	_, err := s.productStorage.FindDeletedByID(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->PurgeProduct()" + err.Path
+		return err
+	}
+
+	images, err := s.imageStorage.FindAllProductImage(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->PurgeProduct()" + err.Path
+		return err
+	}
+
+	// the images and previous slugs rows are removed by the database cascade
+	err = s.productStorage.DeleteHard(ctx, productID)
+	if err != nil {
+		err.Path = ".ProductService->PurgeProduct()" + err.Path
+		return err
+	}
+
+	keys := []string{}
+	for _, image := range images {
+		keys = append(keys, image.Key, image.ThumbnailKey)
+	}
+	s.deleteBlobsAfterCommit(ctx, ".ProductService->PurgeProduct()", keys...)
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job represents a task run periodically in the background
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs the jobs periodically until it is stopped
+type Scheduler struct {
+	jobs   []*Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Add registers the job, it should be called before Start
+func (s *Scheduler) Add(job *Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start runs every job once and then on each of its interval
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job *Job) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				s.run(ctx, job)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job *Job) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("scheduler: job %s panicked: %v\n", job.Name, rec)
+		}
+	}()
+
+	err := job.Run(ctx)
+	if err != nil {
+		log.Printf("scheduler: job %s failed: %s\n", job.Name, err)
+	}
+}
+
+// Stop stops the jobs and waits for the running ones to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
@@ -0,0 +1,104 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Formats
+const (
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+)
+
+// Content types
+const (
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypeCSV  = "text/csv"
+)
+
+// maxXMLPartSize guards against zip bombs inside xlsx files
+const maxXMLPartSize = 64 << 20
+
+// Errors
+var (
+	ErrInvalidFile       = errors.New("file is not a valid spreadsheet")
+	ErrUnsupportedFormat = errors.New("spreadsheet format should be xlsx or csv")
+)
+
+// FormatFromFilename returns the spreadsheet format of the file by its extension
+func FormatFromFilename(filename string) (string, error) {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".xlsx"):
+		return FormatXLSX, nil
+	case strings.HasSuffix(lower, ".csv"):
+		return FormatCSV, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Read reads the rows of the spreadsheet in the format
+func Read(format string, data []byte) ([][]string, error) {
+	switch format {
+	case FormatXLSX:
+		return ReadXLSX(data)
+	case FormatCSV:
+		return ReadCSV(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Write writes the rows as a spreadsheet in the format
+func Write(w io.Writer, format string, sheetName string, rows [][]string) error {
+	switch format {
+	case FormatXLSX:
+		return WriteXLSX(w, sheetName, rows)
+	case FormatCSV:
+		return WriteCSV(w, rows)
+	}
+	return ErrUnsupportedFormat
+}
+
+// ReadCSV reads the rows of a csv file, rows may have different number of columns
+func ReadCSV(data []byte) ([][]string, error) {
+	// skip the utf-8 byte order mark written by spreadsheet applications
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	for len(rows) > 0 && isEmptyRow(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+
+	return rows, nil
+}
+
+// WriteCSV writes the rows as csv
+func WriteCSV(w io.Writer, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	err := writer.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
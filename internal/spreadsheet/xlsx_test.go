@@ -0,0 +1,170 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestXLSXRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"name", "qty", "price"},
+		{"Laptop", "5", "2000000"},
+		{"<Tom & Jerry's \"mug\">", "0", "-15"},
+		{"  padded  ", "007", "1.5"},
+		{"", "", "last"},
+		{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "aa"},
+	}
+
+	buff := &bytes.Buffer{}
+	err := WriteXLSX(buff, "Products & Co", rows)
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	got, err := ReadXLSX(buff.Bytes())
+	if err != nil {
+		t.Fatalf("ReadXLSX() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Errorf("ReadXLSX() = %q, want %q", got, rows)
+	}
+}
+
+func TestWriteXLSXNumbers(t *testing.T) {
+	buff := &bytes.Buffer{}
+	err := WriteXLSX(buff, "Sheet1", [][]string{{"12", "1.5", "abc"}})
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	sheet := &xlsxSheet{}
+	err = decodeZipXML(files, "xl/worksheets/sheet1.xml", sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cells := sheet.Rows[0].Cells
+	if cells[0].Type != "" || cells[0].Value != "12" {
+		t.Errorf("integer cell = %+v, want a number", cells[0])
+	}
+	if cells[1].Type != "inlineStr" || cells[2].Type != "inlineStr" {
+		t.Errorf("other cells = %+v, want inline strings", cells[1:])
+	}
+}
+
+// workbook builds an xlsx file the way spreadsheet applications write it,
+// with a shared strings table and the sheet outside the default path
+func workbook(t *testing.T, sheet string, sharedStrings string) []byte {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId3"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/data.xml"/></Relationships>`},
+		{"xl/worksheets/data.xml", sheet},
+	}
+	if sharedStrings != "" {
+		files = append(files, struct {
+			name    string
+			content string
+		}{"xl/sharedStrings.xml", sharedStrings})
+	}
+
+	buff := &bytes.Buffer{}
+	zw := zip.NewWriter(buff)
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fw.Write([]byte(file.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buff.Bytes()
+}
+
+func TestReadXLSXSharedStringsAndSparseCells(t *testing.T) {
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="4" uniqueCount="4"><si><t>name</t></si><si><t>qty</t></si><si><t>price</t></si><si><r><t>Lap</t></r><r><rPr><b/></rPr><t>top</t></r></si></sst>`
+	sheet := `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>` +
+		`<row r="2"><c r="A2" t="s"><v>3</v></c><c r="C2"><v>2000000</v></c></row>` +
+		`<row r="3"><c r="B3"><v>4</v></c><c r="AB3" t="inlineStr"><is><t>far</t></is></c></row>` +
+		`<row r="4"><c><v>1</v></c><c><v>2</v></c></row>` +
+		`<row r="5"><c r="A5"/></row>` +
+		`</sheetData></worksheet>`
+
+	got, err := ReadXLSX(workbook(t, sheet, sharedStrings))
+	if err != nil {
+		t.Fatalf("ReadXLSX() error = %v", err)
+	}
+
+	far := make([]string, 28)
+	far[1] = "4"
+	far[27] = "far"
+	want := [][]string{
+		{"name", "qty", "price"},
+		{"Laptop", "", "2000000"},
+		far,
+		{"1", "2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadXLSX() = %q, want %q", got, want)
+	}
+}
+
+func TestReadXLSXInvalid(t *testing.T) {
+	sheet := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="1"><c r="A1" t="s"><v>5</v></c></row></sheetData></worksheet>`
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a zip", []byte("name,qty,price\n")},
+		{"shared string out of range", workbook(t, sheet, `<sst><si><t>name</t></si></sst>`)},
+		{"no shared strings", workbook(t, sheet, "")},
+		{"malformed sheet", workbook(t, "<worksheet><sheetData><row>", "")},
+	}
+	for _, tt := range tests {
+		_, err := ReadXLSX(tt.data)
+		if err != ErrInvalidFile {
+			t.Errorf("%s: ReadXLSX() error = %v, want %v", tt.name, err, ErrInvalidFile)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		index int
+		name  string
+	}{
+		{0, "A"}, {25, "Z"}, {26, "AA"}, {27, "AB"}, {701, "ZZ"}, {702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.name {
+			t.Errorf("columnName(%d) = %s, want %s", tt.index, got, tt.name)
+		}
+		if got := columnIndex(tt.name + "12"); got != tt.index {
+			t.Errorf("columnIndex(%s12) = %d, want %d", tt.name, got, tt.index)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Errors
+var (
+	ErrNoActiveKey    = errors.New("active signing key not found")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// minSecretLength is the minimum size of a HS256 secret
+const minSecretLength = 32
+
+// key represents a signing key with the algorithm it signs with
+type key struct {
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// KeySet holds the keys tokens are verified with, addressed by their key id ("kid").
+// Tokens are signed with the active key only, so keys are rotated by adding a new key,
+// making it active and removing the previous one once its tokens expired.
+type KeySet struct {
+	keys      map[string]*key
+	activeKID string
+}
+
+// LoadKeySet loads the keys of the directory, the key id is the file name without its extension.
+// "<kid>.secret" files hold a HS256 secret, "<kid>.pem" files hold a RSA (RS256) or Ed25519 (EdDSA) private key.
+func LoadKeySet(dir string, activeKID string) (*KeySet, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &KeySet{
+		keys:      map[string]*key{},
+		activeKID: activeKID,
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(file.Name())
+		kid := strings.TrimSuffix(file.Name(), ext)
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var k *key
+		switch ext {
+		case ".secret":
+			k, err = secretKey(content)
+		case ".pem":
+			k, err = privateKey(content)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %s", kid, err)
+		}
+		keySet.keys[kid] = k
+	}
+
+	if _, ok := keySet.keys[activeKID]; !ok {
+		return nil, ErrNoActiveKey
+	}
+	return keySet, nil
+}
+
+func secretKey(content []byte) (*key, error) {
+	secret := []byte(strings.TrimSpace(string(content)))
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("secret should be at least %d bytes", minSecretLength)
+	}
+	return &key{method: jwt.SigningMethodHS256, sign: secret, verify: secret}, nil
+}
+
+func privateKey(content []byte) (*key, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed crypto.PrivateKey
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &key{method: jwt.SigningMethodRS256, sign: k, verify: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &key{method: jwt.SigningMethodEdDSA, sign: k, verify: k.Public()}, nil
+	}
+	return nil, ErrUnsupportedKey
+}
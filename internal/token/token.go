@@ -0,0 +1,96 @@
+package token
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidToken is returned when the access token is malformed, badly signed or expired
+var ErrInvalidToken = errors.New("invalid access token")
+
+// Claims represents the claims of an access token
+type Claims struct {
+	jwt.RegisteredClaims
+	Role      string `json:"role"`
+	SessionID int    `json:"sid"`
+}
+
+// UserID returns the id of the user the token was issued to
+func (c *Claims) UserID() int {
+	userID, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+// Manager issues and verifies the signed access tokens.
+// Access tokens are verified without any database lookup, so they are kept short-lived.
+type Manager struct {
+	keys   *KeySet
+	issuer string
+	ttl    time.Duration
+}
+
+// Issue signs a new access token for the user session, it returns the token and its expiry
+func (m *Manager) Issue(userID int, role string, sessionID int) (string, time.Time, error) {
+	now := time.Now()
+	expiredAt := now.Add(m.ttl)
+
+	signing := m.keys.keys[m.keys.activeKID]
+	t := jwt.NewWithClaims(signing.method, &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiredAt),
+		},
+		Role:      role,
+		SessionID: sessionID,
+	})
+	t.Header["kid"] = m.keys.activeKID
+
+	signed, err := t.SignedString(signing.sign)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiredAt, nil
+}
+
+// Verify checks the signature, the expiry and the issuer of the access token and returns its claims
+func (m *Manager) Verify(accessToken string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, ok := m.keys.keys[kid]
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		// the algorithm is fixed by the key, never by the token header
+		if t.Method.Alg() != k.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return k.verify, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(m.issuer, true) || claims.UserID() == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// NewManager creates a new access token manager
+func NewManager(keys *KeySet, issuer string, ttl time.Duration) *Manager {
+	return &Manager{
+		keys:   keys,
+		issuer: issuer,
+		ttl:    ttl,
+	}
+}
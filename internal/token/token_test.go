@@ -0,0 +1,201 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writeKeys writes a HS256 secret "hs", a RSA key "rs" and an Ed25519 key "ed" to a temporary directory
+func writeKeys(t *testing.T) (string, *rsa.PrivateKey) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"hs.secret": []byte(testSecret + "\n"),
+		"rs.pem":    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		"ed.pem":    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}),
+		"README":    []byte("not a key"),
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), content, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, rsaKey
+}
+
+func newManager(t *testing.T, dir string, activeKID string) *Manager {
+	keys, err := LoadKeySet(dir, activeKID)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+	return NewManager(keys, "evermos", 15*time.Minute)
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	dir, _ := writeKeys(t)
+
+	for _, kid := range []string{"hs", "rs", "ed"} {
+		t.Run(kid, func(t *testing.T) {
+			m := newManager(t, dir, kid)
+
+			accessToken, expiredAt, err := m.Issue(7, "staff", 3)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if d := time.Until(expiredAt); d <= 14*time.Minute || d > 15*time.Minute {
+				t.Errorf("expiredAt in %v, want the ttl", d)
+			}
+
+			claims, err := m.Verify(accessToken)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.UserID() != 7 || claims.Role != "staff" || claims.SessionID != 3 || claims.Issuer != "evermos" {
+				t.Errorf("Verify() = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	dir, _ := writeKeys(t)
+
+	_, err := LoadKeySet(dir, "missing")
+	if err != ErrNoActiveKey {
+		t.Errorf("LoadKeySet() with an unknown active key error = %v, want %v", err, ErrNoActiveKey)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "short.secret"), []byte("short"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadKeySet(dir, "hs")
+	if err == nil {
+		t.Error("LoadKeySet() with a short secret error = nil")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	dir, rsaKey := writeKeys(t)
+	m := newManager(t, dir, "rs")
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, change func(claims *Claims)) string {
+		now := time.Now()
+		claims := &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "evermos",
+				Subject:   "7",
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+			Role:      "admin",
+			SessionID: 3,
+		}
+		if change != nil {
+			change(claims)
+		}
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 with the RSA public key", sign(jwt.SigningMethodHS256, "rs", publicPEM, nil)},
+		{"HS256 under the RSA kid", sign(jwt.SigningMethodHS256, "rs", []byte(testSecret), nil)},
+		{"RS512 under the RS256 kid", sign(jwt.SigningMethodRS512, "rs", rsaKey, nil)},
+		{"alg none", sign(jwt.SigningMethodNone, "rs", jwt.UnsafeAllowNoneSignatureType, nil)},
+		{"no kid", sign(jwt.SigningMethodRS256, "", rsaKey, nil)},
+		{"unknown kid", sign(jwt.SigningMethodRS256, "other", rsaKey, nil)},
+		{"wrong issuer", sign(jwt.SigningMethodRS256, "rs", rsaKey, func(c *Claims) { c.Issuer = "other" })},
+		{"no issuer", sign(jwt.SigningMethodRS256, "rs", rsaKey, func(c *Claims) { c.Issuer = "" })},
+		{"expired", sign(jwt.SigningMethodRS256, "rs", rsaKey, func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Second)) })},
+		{"no user", sign(jwt.SigningMethodRS256, "rs", rsaKey, func(c *Claims) { c.Subject = "" })},
+		{"malformed", "not.a.token"},
+	}
+	for _, tt := range tests {
+		claims, err := m.Verify(tt.token)
+		if err != ErrInvalidToken {
+			t.Errorf("%s: Verify() = %+v, %v, want %v", tt.name, claims, err, ErrInvalidToken)
+		}
+	}
+
+	// the valid token passes, so the cases above are refused for their own reason
+	_, err = m.Verify(sign(jwt.SigningMethodRS256, "rs", rsaKey, nil))
+	if err != nil {
+		t.Errorf("Verify() of the valid token error = %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir, _ := writeKeys(t)
+
+	before := newManager(t, dir, "hs")
+	oldToken, _, err := before.Issue(7, "staff", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key is made active, the previous one is kept until its tokens expired
+	after := newManager(t, dir, "ed")
+	newToken, _, err := after.Issue(7, "staff", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := after.Verify(token); err != nil {
+			t.Errorf("Verify() of the %s token error = %v", name, err)
+		}
+	}
+
+	// once the previous key is removed its tokens are refused
+	if err := os.Remove(filepath.Join(dir, "hs.secret")); err != nil {
+		t.Fatal(err)
+	}
+	rotated := newManager(t, dir, "ed")
+	if _, err := rotated.Verify(oldToken); err != ErrInvalidToken {
+		t.Errorf("Verify() of the token of the removed key error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := rotated.Verify(newToken); err != nil {
+		t.Errorf("Verify() of the new token error = %v", err)
+	}
+}
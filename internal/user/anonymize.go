@@ -0,0 +1,171 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+const (
+	// anonymizedName replaces the name of an anonymized user
+	anonymizedName = "Deleted user"
+
+	// anonymizedPassword is no bcrypt hash, so no password matches it
+	anonymizedPassword = "!"
+)
+
+// Export represents everything kept about a user by the user service
+// swagger:model
+type Export struct {
+	User       *User        `json:"user"`
+	Sessions   []*Session   `json:"sessions"`
+	Tokens     []*UserToken `json:"tokens"`
+	Identities []*Identity  `json:"identities"`
+}
+
+// findUser returns the user whether it is soft deleted or not
+func (s *Service) findUser(ctx context.Context, userID int) (*User, *types.Error) {
+	user, err := s.userStorage.FindByID(ctx, userID)
+	if err == nil {
+		return user, nil
+	}
+	if err.Error != data.ErrNotFound {
+		err.Path = ".UserService->findUser()" + err.Path
+		return nil, err
+	}
+
+	user, err = s.userStorage.FindDeletedByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->findUser()" + err.Path
+		return nil, err
+	}
+	return user, nil
+}
+
+// AnonymizeUser scrubs the personal data of the user and soft deletes it.
+// The row is kept so the orders of the user still refer to it.
+func (s *Service) AnonymizeUser(ctx context.Context, userID int) *types.Error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->AnonymizeUser()" + err.Path
+		return err
+	}
+	if user.AnonymizedAt != nil {
+		return nil
+	}
+
+	if s.loginGuard != nil {
+		errGuard := s.loginGuard.Unlock(ctx, user.Email)
+		if errGuard != nil {
+			return &types.Error{
+				Path:    ".UserService->AnonymizeUser()",
+				Message: errGuard.Error(),
+				Error:   errGuard,
+				Type:    "golang-error",
+			}
+		}
+	}
+
+	err = s.LogoutAll(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->AnonymizeUser()" + err.Path
+		return err
+	}
+
+	// the tokens hold the emails they were sent to
+	userTokens, err := s.userTokenStorage.FindAllUserTokens(ctx, &FindAllUserTokensParams{
+		UserID: user.ID,
+	})
+	if err != nil {
+		err.Path = ".UserService->AnonymizeUser()" + err.Path
+		return err
+	}
+	for _, userToken := range userTokens {
+		err = s.userTokenStorage.DeleteUserToken(ctx, userToken.ID)
+		if err != nil {
+			err.Path = ".UserService->AnonymizeUser()" + err.Path
+			return err
+		}
+	}
+
+	err = s.removeTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->AnonymizeUser()" + err.Path
+		return err
+	}
+
+	identities, err := s.listIdentities(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->AnonymizeUser()" + err.Path
+		return err
+	}
+	for _, identity := range identities {
+		err = s.identityStorage.DeleteIdentity(ctx, identity.ID)
+		if err != nil {
+			err.Path = ".UserService->AnonymizeUser()" + err.Path
+			return err
+		}
+	}
+
+	now := time.Now()
+	user.Name = anonymizedName
+	user.Email = fmt.Sprintf("deleted-%d@anonymized.invalid", user.ID)
+	user.Password = anonymizedPassword
+	user.VerifiedAt = nil
+	user.AnonymizedAt = &now
+	user.UpdatedAt = &now
+	_, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->AnonymizeUser()" + err.Path
+		return err
+	}
+
+	if user.DeletedAt == nil {
+		err = s.userStorage.Delete(ctx, user.ID)
+		if err != nil {
+			err.Path = ".UserService->AnonymizeUser()" + err.Path
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportUser returns the user with its sessions, the emailed tokens without their hashes and the linked identities
+func (s *Service) ExportUser(ctx context.Context, userID int) (*Export, *types.Error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->ExportUser()" + err.Path
+		return nil, err
+	}
+
+	sessions, err := s.ListSessions(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->ExportUser()" + err.Path
+		return nil, err
+	}
+
+	userTokens, err := s.userTokenStorage.FindAllUserTokens(ctx, &FindAllUserTokensParams{
+		UserID: user.ID,
+	})
+	if err != nil {
+		err.Path = ".UserService->ExportUser()" + err.Path
+		return nil, err
+	}
+
+	identities, err := s.listIdentities(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->ExportUser()" + err.Path
+		return nil, err
+	}
+
+	return &Export{
+		User:       user,
+		Sessions:   sessions,
+		Tokens:     userTokens,
+		Identities: identities,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// DeleteExpiredTokens deletes the expired sessions, emailed tokens, identity provider logins
+// and the failed logins which no longer count, and returns how many were deleted. The emailed tokens are kept for an hour after they expired
+// so the hourly limit of the tokens sent still counts them.
+func (s *Service) DeleteExpiredTokens(ctx context.Context, now time.Time) (int, *types.Error) {
+	sessions, err := s.sessionStorage.DeleteExpiredSessions(ctx, now)
+	if err != nil {
+		err.Path = ".UserService->DeleteExpiredTokens()" + err.Path
+		return 0, err
+	}
+
+	userTokens, err := s.userTokenStorage.DeleteExpiredUserTokens(ctx, now.Add(-time.Hour))
+	if err != nil {
+		err.Path = ".UserService->DeleteExpiredTokens()" + err.Path
+		return 0, err
+	}
+
+	oidcStates := 0
+	if s.oidcStateStorage != nil {
+		oidcStates, err = s.oidcStateStorage.DeleteExpiredOIDCStates(ctx, now)
+		if err != nil {
+			err.Path = ".UserService->DeleteExpiredTokens()" + err.Path
+			return 0, err
+		}
+	}
+
+	loginAttempts := 0
+	if s.loginGuard != nil {
+		var errGuard error
+		loginAttempts, errGuard = s.loginGuard.DeleteExpired(ctx, now)
+		if errGuard != nil {
+			return 0, &types.Error{
+				Path:    ".UserService->DeleteExpiredTokens()",
+				Message: errGuard.Error(),
+				Error:   errGuard,
+				Type:    "golang-error",
+			}
+		}
+	}
+
+	return sessions + userTokens + oidcStates + loginAttempts, nil
+}
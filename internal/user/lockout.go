@@ -0,0 +1,140 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/loginguard"
+	"github.com/riskiramdan/evermos/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash is compared against when the email is unknown so the response takes as long as a wrong password
+const dummyPasswordHash = "$2a$10$CCWkaZ1UedUQeGtABKwEqepsNTBR1Rp.b4UlFCuvtkbGmfX3rk4SC"
+
+// Errors
+var (
+	ErrInvalidCredentials = errors.New("Email / password is wrong")
+	ErrLoginLocked        = loginguard.ErrLocked
+)
+
+// WithLoginGuard enables the tracking of the failed logins per account and per ip
+func (s *Service) WithLoginGuard(guard *loginguard.Guard) *Service {
+	s.loginGuard = guard
+	return s
+}
+
+// checkLoginGuard refuses the login while the account or the ip is locked out,
+// unknown emails are locked out alike so the lockout does not tell which emails are registered
+func (s *Service) checkLoginGuard(ctx context.Context, email string, client *ClientInfo) *types.Error {
+	if s.loginGuard == nil {
+		return nil
+	}
+
+	lockedUntil, errGuard := s.loginGuard.Check(ctx, email, client.IP)
+	if errGuard == loginguard.ErrLocked {
+		return &types.Error{
+			Path:    ".UserService->checkLoginGuard()",
+			Message: fmt.Sprintf("%s, locked until %s", ErrLoginLocked.Error(), lockedUntil.Format(time.RFC3339)),
+			Error:   ErrLoginLocked,
+			Type:    "validation-error",
+		}
+	}
+	if errGuard != nil {
+		return &types.Error{
+			Path:    ".UserService->checkLoginGuard()",
+			Message: errGuard.Error(),
+			Error:   errGuard,
+			Type:    "golang-error",
+		}
+	}
+
+	return nil
+}
+
+// loginFailed counts the failed login, delays the response progressively and returns the failure,
+// ErrInvalidCredentials whether the email or the password was wrong
+func (s *Service) loginFailed(ctx context.Context, email string, client *ClientInfo, failure error) *types.Error {
+	if s.loginGuard != nil {
+		delay, errGuard := s.loginGuard.Fail(ctx, email, client.IP)
+		if errGuard != nil {
+			return &types.Error{
+				Path:    ".UserService->loginFailed()",
+				Message: errGuard.Error(),
+				Error:   errGuard,
+				Type:    "golang-error",
+			}
+		}
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+	}
+
+	return &types.Error{
+		Path:    ".UserService->loginFailed()",
+		Message: failure.Error(),
+		Error:   failure,
+		Type:    "validation-error",
+	}
+}
+
+// loginSucceeded forgets the failed logins of the account
+func (s *Service) loginSucceeded(ctx context.Context, email string) *types.Error {
+	if s.loginGuard == nil {
+		return nil
+	}
+
+	errGuard := s.loginGuard.Succeed(ctx, email)
+	if errGuard != nil {
+		return &types.Error{
+			Path:    ".UserService->loginSucceeded()",
+			Message: errGuard.Error(),
+			Error:   errGuard,
+			Type:    "golang-error",
+		}
+	}
+
+	return nil
+}
+
+// UnlockUser lifts the lockout of the account of the user
+func (s *Service) UnlockUser(ctx context.Context, userID int) *types.Error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->UnlockUser()" + err.Path
+		return err
+	}
+
+	if s.loginGuard == nil {
+		return nil
+	}
+
+	errGuard := s.loginGuard.Unlock(ctx, user.Email)
+	if errGuard != nil {
+		return &types.Error{
+			Path:    ".UserService->UnlockUser()",
+			Message: errGuard.Error(),
+			Error:   errGuard,
+			Type:    "golang-error",
+		}
+	}
+
+	return nil
+}
+
+// comparePassword compares the password with the dummy hash when the user is unknown
+func comparePassword(user *User, password string) error {
+	if user == nil {
+		bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return ErrInvalidCredentials
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+}
@@ -0,0 +1,372 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/oidc"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+const (
+	// oidcStateLifetime is how long the user has to sign in at the provider
+	oidcStateLifetime = 10 * time.Minute
+
+	// noPassword is no bcrypt hash, the users created by the provider log in without a password
+	// until they set one with the reset password link
+	noPassword = "!"
+
+	// maxNameLength is the length of the name column
+	maxNameLength = 80
+)
+
+// Errors
+var (
+	ErrOIDCDisabled         = errors.New("Login with the identity provider is not enabled")
+	ErrOIDCStateInvalid     = errors.New("Login with the identity provider expired, please try again")
+	ErrOIDCFailed           = errors.New("Login with the identity provider failed")
+	ErrOIDCEmailNotVerified = errors.New("The email is not verified by the identity provider")
+)
+
+// Identity links a user to its account at an OpenID Connect provider, the account is identified by the issuer and the subject
+type Identity struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"userId" db:"user_id"`
+	Issuer    string     `json:"issuer" db:"issuer"`
+	Subject   string     `json:"subject" db:"subject"`
+	Email     string     `json:"email" db:"email" audit:"-"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// OIDCState is a login started at the provider, only the hash of the state is stored
+type OIDCState struct {
+	ID           int        `json:"id" db:"id"`
+	StateHash    string     `json:"-" db:"state_hash"`
+	Nonce        string     `json:"-" db:"nonce"`
+	CodeVerifier string     `json:"-" db:"code_verifier"`
+	ExpiredAt    time.Time  `json:"expiredAt" db:"expired_at"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy    *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt    *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy    *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// OIDCLogin is a login started at the provider. The state is kept by the browser as well,
+// so the provider can only redirect back to the browser which started the login.
+type OIDCLogin struct {
+	AuthURL   string    `json:"authUrl"`
+	State     string    `json:"-"`
+	ExpiredAt time.Time `json:"expiredAt"`
+}
+
+// OIDCCallbackParams are the query parameters the provider redirects back with,
+// along with the state kept by the browser when the login started
+type OIDCCallbackParams struct {
+	Code         string `json:"code" validate:"required"`
+	State        string `json:"state" validate:"required"`
+	BrowserState string `json:"-"`
+}
+
+// IdentityStorage represents the identity storage interface
+type IdentityStorage interface {
+	FindIdentity(ctx context.Context, issuer string, subject string) (*Identity, *types.Error)
+	FindAllIdentities(ctx context.Context, userID int) ([]*Identity, *types.Error)
+	InsertIdentity(ctx context.Context, identity *Identity) (*Identity, *types.Error)
+	DeleteIdentity(ctx context.Context, identityID int) *types.Error
+}
+
+// OIDCStateStorage represents the oidc state storage interface
+type OIDCStateStorage interface {
+	FindOIDCStateByHash(ctx context.Context, stateHash string) (*OIDCState, *types.Error)
+	InsertOIDCState(ctx context.Context, state *OIDCState) (*OIDCState, *types.Error)
+	DeleteOIDCState(ctx context.Context, stateID int) *types.Error
+	DeleteExpiredOIDCStates(ctx context.Context, before time.Time) (int, *types.Error)
+}
+
+// WithOIDC enables the login with the OpenID Connect provider
+func (s *Service) WithOIDC(provider *oidc.Provider, identityStorage IdentityStorage, oidcStateStorage OIDCStateStorage) *Service {
+	s.oidcProvider = provider
+	s.identityStorage = identityStorage
+	s.oidcStateStorage = oidcStateStorage
+	return s
+}
+
+// StartOIDCLogin returns the url of the provider the user signs in at and the state the browser has to keep.
+// The state, the nonce and the PKCE code verifier of the login are kept until the provider redirects back.
+func (s *Service) StartOIDCLogin(ctx context.Context) (*OIDCLogin, *types.Error) {
+	if s.oidcProvider == nil {
+		return nil, &types.Error{
+			Path:    ".UserService->StartOIDCLogin()",
+			Message: ErrOIDCDisabled.Error(),
+			Error:   ErrOIDCDisabled,
+			Type:    "validation-error",
+		}
+	}
+
+	values := make([]string, 3)
+	for i := range values {
+		value, errValue := oidc.GenerateVerifier()
+		if errValue != nil {
+			return nil, &types.Error{
+				Path:    ".UserService->StartOIDCLogin()",
+				Message: errValue.Error(),
+				Error:   errValue,
+				Type:    "golang-error",
+			}
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, errURL := s.oidcProvider.AuthCodeURL(ctx, state, nonce, verifier)
+	if errURL != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->StartOIDCLogin()",
+			Message: errURL.Error(),
+			Error:   ErrOIDCFailed,
+			Type:    "golang-error",
+		}
+	}
+
+	now := time.Now()
+	expiredAt := now.Add(oidcStateLifetime)
+	_, err := s.oidcStateStorage.InsertOIDCState(ctx, &OIDCState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiredAt:    expiredAt,
+		CreatedAt:    now,
+		UpdatedAt:    &now,
+	})
+	if err != nil {
+		err.Path = ".UserService->StartOIDCLogin()" + err.Path
+		return nil, err
+	}
+
+	return &OIDCLogin{
+		AuthURL:   authURL,
+		State:     state,
+		ExpiredAt: expiredAt,
+	}, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the id token and opens the session of its user
+// the way Login does. The user is linked by the verified email or created on the first login.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, params *OIDCCallbackParams, client *ClientInfo) (*LoginResponse, *types.Error) {
+	if s.oidcProvider == nil {
+		return nil, &types.Error{
+			Path:    ".UserService->CompleteOIDCLogin()",
+			Message: ErrOIDCDisabled.Error(),
+			Error:   ErrOIDCDisabled,
+			Type:    "validation-error",
+		}
+	}
+	if client == nil {
+		client = &ClientInfo{}
+	}
+
+	// a callback the browser did not start the login for is a login forced on the user
+	if params.BrowserState == "" || subtle.ConstantTimeCompare([]byte(params.BrowserState), []byte(params.State)) != 1 {
+		return nil, &types.Error{
+			Path:    ".UserService->CompleteOIDCLogin()",
+			Message: ErrOIDCStateInvalid.Error(),
+			Error:   ErrOIDCStateInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	state, err := s.useOIDCState(ctx, params.State)
+	if err != nil {
+		err.Path = ".UserService->CompleteOIDCLogin()" + err.Path
+		return nil, err
+	}
+
+	rawIDToken, errExchange := s.oidcProvider.Exchange(ctx, params.Code, state.CodeVerifier)
+	if errExchange != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->CompleteOIDCLogin()",
+			Message: errExchange.Error(),
+			Error:   ErrOIDCFailed,
+			Type:    "golang-error",
+		}
+	}
+
+	claims, errVerify := s.oidcProvider.Verify(ctx, rawIDToken, state.Nonce)
+	if errVerify != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->CompleteOIDCLogin()",
+			Message: errVerify.Error(),
+			Error:   ErrOIDCFailed,
+			Type:    "validation-error",
+		}
+	}
+
+	user, err := s.identityUser(ctx, claims)
+	if err != nil {
+		err.Path = ".UserService->CompleteOIDCLogin()" + err.Path
+		return nil, err
+	}
+
+	response, err := s.openSession(ctx, user, client)
+	if err != nil {
+		err.Path = ".UserService->CompleteOIDCLogin()" + err.Path
+		return nil, err
+	}
+	return response, nil
+}
+
+// useOIDCState deletes the state of the login and returns it, unknown and expired states are refused alike
+func (s *Service) useOIDCState(ctx context.Context, state string) (*OIDCState, *types.Error) {
+	oidcState, err := s.oidcStateStorage.FindOIDCStateByHash(ctx, hashToken(state))
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".UserService->useOIDCState()" + err.Path
+		return nil, err
+	}
+	if err != nil || oidcState.ExpiredAt.Before(time.Now()) {
+		return nil, &types.Error{
+			Path:    ".UserService->useOIDCState()",
+			Message: ErrOIDCStateInvalid.Error(),
+			Error:   ErrOIDCStateInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.oidcStateStorage.DeleteOIDCState(ctx, oidcState.ID)
+	if err != nil {
+		err.Path = ".UserService->useOIDCState()" + err.Path
+		return nil, err
+	}
+	return oidcState, nil
+}
+
+// identityUser returns the user linked to the account at the provider.
+// An unlinked account is linked to the user of its email when the provider verified the email,
+// or to a new user when no user has the email.
+func (s *Service) identityUser(ctx context.Context, claims *oidc.Claims) (*User, *types.Error) {
+	identity, err := s.identityStorage.FindIdentity(ctx, s.oidcProvider.Issuer(), claims.Subject)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".UserService->identityUser()" + err.Path
+		return nil, err
+	}
+	if err == nil {
+		user, err := s.GetUser(ctx, identity.UserID)
+		if err != nil {
+			err.Path = ".UserService->identityUser()" + err.Path
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, &types.Error{
+			Path:    ".UserService->identityUser()",
+			Message: ErrOIDCEmailNotVerified.Error(),
+			Error:   ErrOIDCEmailNotVerified,
+			Type:    "validation-error",
+		}
+	}
+
+	user, err := s.userStorage.FindByEmail(ctx, claims.Email)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".UserService->identityUser()" + err.Path
+		return nil, err
+	}
+	if err != nil {
+		user, err = s.createIdentityUser(ctx, claims)
+	} else {
+		user, err = s.linkIdentityUser(ctx, user)
+	}
+	if err != nil {
+		err.Path = ".UserService->identityUser()" + err.Path
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = s.identityStorage.InsertIdentity(ctx, &Identity{
+		UserID:    user.ID,
+		Issuer:    s.oidcProvider.Issuer(),
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: now,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		err.Path = ".UserService->identityUser()" + err.Path
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createIdentityUser creates a verified customer without password for the account at the provider
+func (s *Service) createIdentityUser(ctx context.Context, claims *oidc.Claims) (*User, *types.Error) {
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+
+	now := time.Now()
+	user, err := s.userStorage.Insert(ctx, &User{
+		Name:       name,
+		Email:      claims.Email,
+		Role:       RoleCustomer,
+		Password:   noPassword,
+		VerifiedAt: &now,
+		CreatedAt:  now,
+		UpdatedAt:  &now,
+	})
+	if err != nil {
+		err.Path = ".UserService->createIdentityUser()" + err.Path
+		return nil, err
+	}
+	return user, nil
+}
+
+// linkIdentityUser prepares the user of the email to be linked. A user who never verified the email
+// may have been registered by someone else, the password of such a user is removed and the email is verified.
+func (s *Service) linkIdentityUser(ctx context.Context, user *User) (*User, *types.Error) {
+	if user.VerifiedAt != nil {
+		return user, nil
+	}
+
+	now := time.Now()
+	user.Password = noPassword
+	user.VerifiedAt = &now
+	user.UpdatedAt = &now
+	user, err := s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->linkIdentityUser()" + err.Path
+		return nil, err
+	}
+
+	err = s.LogoutAll(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->linkIdentityUser()" + err.Path
+		return nil, err
+	}
+	return user, nil
+}
+
+// listIdentities returns the accounts at the provider linked to the user
+func (s *Service) listIdentities(ctx context.Context, userID int) ([]*Identity, *types.Error) {
+	if s.identityStorage == nil {
+		return []*Identity{}, nil
+	}
+
+	identities, err := s.identityStorage.FindAllIdentities(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->listIdentities()" + err.Path
+		return nil, err
+	}
+	return identities, nil
+}
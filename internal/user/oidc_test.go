@@ -0,0 +1,239 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/oidc"
+	"github.com/riskiramdan/evermos/internal/oidc/oidctest"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+type memIdentityStorage struct {
+	IdentityStorage
+	identities []*Identity
+}
+
+func (s *memIdentityStorage) FindIdentity(ctx context.Context, issuer string, subject string) (*Identity, *types.Error) {
+	for _, identity := range s.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, notFound(".memIdentityStorage->FindIdentity()")
+}
+
+func (s *memIdentityStorage) InsertIdentity(ctx context.Context, identity *Identity) (*Identity, *types.Error) {
+	identity.ID = len(s.identities) + 1
+	s.identities = append(s.identities, identity)
+	return identity, nil
+}
+
+type memOIDCStateStorage struct {
+	OIDCStateStorage
+	states []*OIDCState
+}
+
+func (s *memOIDCStateStorage) FindOIDCStateByHash(ctx context.Context, stateHash string) (*OIDCState, *types.Error) {
+	for _, state := range s.states {
+		if state.StateHash == stateHash {
+			return state, nil
+		}
+	}
+	return nil, notFound(".memOIDCStateStorage->FindOIDCStateByHash()")
+}
+
+func (s *memOIDCStateStorage) InsertOIDCState(ctx context.Context, state *OIDCState) (*OIDCState, *types.Error) {
+	state.ID = len(s.states) + 1
+	s.states = append(s.states, state)
+	return state, nil
+}
+
+func (s *memOIDCStateStorage) DeleteOIDCState(ctx context.Context, stateID int) *types.Error {
+	for i, state := range s.states {
+		if state.ID == stateID {
+			s.states = append(s.states[:i], s.states[i+1:]...)
+			return nil
+		}
+	}
+	return notFound(".memOIDCStateStorage->DeleteOIDCState()")
+}
+
+type oidcTest struct {
+	srv        *oidctest.Server
+	service    *Service
+	users      *memUserStorage
+	sessions   *memSessionStorage
+	identities *memIdentityStorage
+}
+
+func newOIDCTest(t *testing.T, users ...*User) *oidcTest {
+	srv := oidctest.NewServer(t, "evermos")
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      srv.URL,
+		ClientID:    "evermos",
+		RedirectURL: "https://shop.example.com/v1/oidc/callback",
+	}, srv.Client())
+
+	env := &oidcTest{
+		srv:        srv,
+		users:      &memUserStorage{users: users},
+		sessions:   &memSessionStorage{},
+		identities: &memIdentityStorage{},
+	}
+	env.service = NewService(env.users, env.sessions, nil, nil, &memTOTPStorage{}, nil, nil, "https://shop.example.com").
+		WithOIDC(provider, env.identities, &memOIDCStateStorage{})
+	return env
+}
+
+// login starts a login, signs the user in at the provider with the claims and completes the login
+func (env *oidcTest) login(t *testing.T, subject string, email string, emailVerified bool) (*LoginResponse, *types.Error) {
+	ctx := context.Background()
+
+	login, err := env.service.StartOIDCLogin(ctx)
+	if err != nil {
+		t.Fatalf("StartOIDCLogin() error = %v", err.Error)
+	}
+	authURL, errURL := url.Parse(login.AuthURL)
+	if errURL != nil {
+		t.Fatal(errURL)
+	}
+	query := authURL.Query()
+
+	claims := env.srv.Claims(subject, query.Get("nonce"))
+	claims["email"] = email
+	claims["email_verified"] = emailVerified
+	code := env.srv.IssueCode(query.Get("code_challenge"), env.srv.Sign(t, "key-1", claims))
+
+	return env.service.CompleteOIDCLogin(ctx, &OIDCCallbackParams{
+		Code:         code,
+		State:        query.Get("state"),
+		BrowserState: login.State,
+	}, &ClientInfo{UserAgent: "test", IP: "127.0.0.1"})
+}
+
+func TestCompleteOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	verifiedAt := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name       string
+		verifiedAt *time.Time
+	}{
+		{"verified user", &verifiedAt},
+		{"unverified user", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jane := &User{ID: 7, Name: "Jane", Email: "jane@example.com", Role: RoleCustomer, Password: "$2a$10$hash", VerifiedAt: tt.verifiedAt}
+			env := newOIDCTest(t, jane)
+			env.sessions.sessions = []*Session{{ID: 100, UserID: 7}}
+
+			response, err := env.login(t, "jane-at-provider", "jane@example.com", true)
+			if err != nil {
+				t.Fatalf("CompleteOIDCLogin() error = %v", err.Error)
+			}
+			if response.User.ID != 7 || response.SessionID == "" {
+				t.Errorf("CompleteOIDCLogin() = %+v, want a session of the user 7", response)
+			}
+			if len(env.users.users) != 1 {
+				t.Errorf("got %d users, want the user linked instead of a new one", len(env.users.users))
+			}
+			if len(env.identities.identities) != 1 || env.identities.identities[0].UserID != 7 ||
+				env.identities.identities[0].Issuer != env.srv.URL || env.identities.identities[0].Subject != "jane-at-provider" {
+				t.Errorf("identities = %+v, want the account linked to the user 7", env.identities.identities)
+			}
+
+			// an unverified registration may be someone else's, its password and sessions are dropped
+			if tt.verifiedAt == nil {
+				if jane.Password != noPassword || jane.VerifiedAt == nil {
+					t.Errorf("user = %+v, want the password removed and the email verified", jane)
+				}
+				if len(env.sessions.sessions) != 1 || env.sessions.sessions[0].ID == 100 {
+					t.Errorf("sessions = %+v, want only the new session", env.sessions.sessions)
+				}
+			} else if jane.Password == noPassword || len(env.sessions.sessions) != 2 {
+				t.Errorf("user = %+v, sessions = %d, want the verified user kept", jane, len(env.sessions.sessions))
+			}
+
+			// the next login finds the linked account
+			response, err = env.login(t, "jane-at-provider", "jane@example.com", false)
+			if err != nil || response.User.ID != 7 {
+				t.Errorf("CompleteOIDCLogin() of the linked account = %+v, %v", response, err)
+			}
+		})
+	}
+}
+
+func TestCompleteOIDCLoginRefusesUnverifiedEmail(t *testing.T) {
+	jane := &User{ID: 7, Name: "Jane", Email: "jane@example.com", Role: RoleCustomer, Password: "$2a$10$hash"}
+	env := newOIDCTest(t, jane)
+
+	for _, email := range []string{"jane@example.com", "new@example.com"} {
+		_, err := env.login(t, "someone", email, false)
+		if err == nil || err.Error != ErrOIDCEmailNotVerified {
+			t.Errorf("CompleteOIDCLogin() with %s error = %v, want %v", email, err, ErrOIDCEmailNotVerified)
+		}
+	}
+	if len(env.users.users) != 1 || len(env.identities.identities) != 0 || jane.Password == noPassword {
+		t.Errorf("users = %d, identities = %d, want nothing created or linked", len(env.users.users), len(env.identities.identities))
+	}
+}
+
+func TestCompleteOIDCLoginCreatesUser(t *testing.T) {
+	env := newOIDCTest(t)
+
+	response, err := env.login(t, "new-at-provider", "new@example.com", true)
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err.Error)
+	}
+	created := response.User
+	if created.Email != "new@example.com" || created.Name != "new" || created.Role != RoleCustomer ||
+		created.Password != noPassword || created.VerifiedAt == nil {
+		t.Errorf("created user = %+v", created)
+	}
+}
+
+func TestCompleteOIDCLoginChecksState(t *testing.T) {
+	env := newOIDCTest(t)
+	ctx := context.Background()
+
+	login, err := env.service.StartOIDCLogin(ctx)
+	if err != nil {
+		t.Fatalf("StartOIDCLogin() error = %v", err.Error)
+	}
+	other, err := env.service.StartOIDCLogin(ctx)
+	if err != nil {
+		t.Fatalf("StartOIDCLogin() error = %v", err.Error)
+	}
+
+	tests := []struct {
+		name         string
+		state        string
+		browserState string
+	}{
+		{"no browser state", login.State, ""},
+		{"state of another browser", login.State, other.State},
+		{"unknown state", "unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		_, err := env.service.CompleteOIDCLogin(ctx, &OIDCCallbackParams{
+			Code:         "code",
+			State:        tt.state,
+			BrowserState: tt.browserState,
+		}, nil)
+		if err == nil || err.Error != ErrOIDCStateInvalid {
+			t.Errorf("%s: CompleteOIDCLogin() error = %v, want %v", tt.name, err, ErrOIDCStateInvalid)
+		}
+	}
+
+	// the refused callbacks did not use the state, the callback of the browser which started the login gets to the provider
+	_, err = env.service.CompleteOIDCLogin(ctx, &OIDCCallbackParams{
+		Code:         "unknown-code",
+		State:        login.State,
+		BrowserState: login.State,
+	}, nil)
+	if err == nil || err.Error != ErrOIDCFailed {
+		t.Errorf("CompleteOIDCLogin() with an unknown code error = %v, want %v", err, ErrOIDCFailed)
+	}
+}
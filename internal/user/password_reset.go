@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/mailer"
+	"github.com/riskiramdan/evermos/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetPasswordLifetime is how long a reset password link stays valid
+const resetPasswordLifetime = time.Hour
+
+// RequestResetPasswordParams represent the http request data for requesting a reset password link
+// swagger:model
+type RequestResetPasswordParams struct {
+	Email string `json:"email" validate:"required,max=80"`
+}
+
+// ResetPasswordParams represent the http request data for resetting the password
+// swagger:model
+type ResetPasswordParams struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+// WithResetPasswordURL sets the page the reset password links open, the token is added to its query
+func (s *Service) WithResetPasswordURL(resetPasswordURL string) *Service {
+	s.resetPasswordURL = resetPasswordURL
+	return s
+}
+
+// resetPasswordLink returns the link of the reset password page with the token
+func (s *Service) resetPasswordLink(token string) string {
+	separator := "?"
+	if strings.Contains(s.resetPasswordURL, "?") {
+		separator = "&"
+	}
+	return s.resetPasswordURL + separator + "token=" + url.QueryEscape(token)
+}
+
+// RequestResetPassword emails a reset password link.
+// Unknown emails and requests over the limit are ignored so the response does not tell which emails are registered.
+func (s *Service) RequestResetPassword(ctx context.Context, email string) *types.Error {
+	user, err := s.userStorage.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".UserService->RequestResetPassword()" + err.Path
+		return err
+	}
+
+	err = s.limitUserTokens(ctx, user.ID, TokenResetPassword)
+	if err != nil {
+		if err.Error == ErrTooManyRequests {
+			return nil
+		}
+		err.Path = ".UserService->RequestResetPassword()" + err.Path
+		return err
+	}
+
+	token, err := s.issueUserToken(ctx, user, TokenResetPassword, user.Email, resetPasswordLifetime)
+	if err != nil {
+		err.Path = ".UserService->RequestResetPassword()" + err.Path
+		return err
+	}
+
+	errSend := s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password, it is valid for %d minutes.\nIgnore this email if you did not ask for it.\n\n%s\n",
+			user.Name, int(resetPasswordLifetime.Minutes()), s.resetPasswordLink(token)),
+	})
+	if errSend != nil {
+		return &types.Error{
+			Path:    ".UserService->RequestResetPassword()",
+			Message: errSend.Error(),
+			Error:   errSend,
+			Type:    "golang-error",
+		}
+	}
+
+	return nil
+}
+
+// ResetPassword sets the password of the user of the token and logs the user out of all of its sessions
+func (s *Service) ResetPassword(ctx context.Context, params *ResetPasswordParams) *types.Error {
+	if params.Token == "" || params.Password == "" {
+		return &types.Error{
+			Path:    ".UserService->ResetPassword()",
+			Message: ErrNoInput.Error(),
+			Error:   ErrNoInput,
+			Type:    "validation-error",
+		}
+	}
+
+	userToken, err := s.useUserToken(ctx, params.Token, TokenResetPassword)
+	if err != nil {
+		err.Path = ".UserService->ResetPassword()" + err.Path
+		return err
+	}
+
+	user, err := s.tokenUser(ctx, userToken)
+	if err != nil {
+		err.Path = ".UserService->ResetPassword()" + err.Path
+		return err
+	}
+	if user.Email != userToken.Email {
+		return &types.Error{
+			Path:    ".UserService->ResetPassword()",
+			Message: ErrTokenInvalid.Error(),
+			Error:   ErrTokenInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	bcryptHash, errBcrypt := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+	if errBcrypt != nil {
+		return &types.Error{
+			Path:    ".UserService->ResetPassword()",
+			Message: errBcrypt.Error(),
+			Error:   errBcrypt,
+			Type:    "golang-error",
+		}
+	}
+
+	now := time.Now()
+	user.Password = string(bcryptHash)
+	// the link was opened from the inbox, so the email is verified as well
+	if user.VerifiedAt == nil {
+		user.VerifiedAt = &now
+	}
+	user.UpdatedAt = &now
+	_, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->ResetPassword()" + err.Path
+		return err
+	}
+
+	err = s.LogoutAll(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->ResetPassword()" + err.Path
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+type resetTest struct {
+	service    *Service
+	jane       *User
+	sessions   *memSessionStorage
+	userTokens *memUserTokenStorage
+	mails      *memMailer
+}
+
+func newResetTest(t *testing.T) *resetTest {
+	env := &resetTest{
+		jane:       &User{ID: 7, Name: "Jane", Email: "jane@example.com", Role: RoleCustomer, Password: "$2a$10$hash"},
+		sessions:   &memSessionStorage{},
+		userTokens: &memUserTokenStorage{},
+		mails:      &memMailer{},
+	}
+	future := time.Now().Add(time.Hour)
+	env.sessions.sessions = []*Session{
+		{ID: 1, UserID: 7, ExpiredAt: future},
+		{ID: 2, UserID: 7, ExpiredAt: future},
+		{ID: 3, UserID: 8, ExpiredAt: future},
+	}
+	users := &memUserStorage{users: []*User{env.jane, {ID: 8, Email: "john@example.com"}}}
+	env.service = NewService(users, env.sessions, nil, env.userTokens, &memTOTPStorage{}, nil, env.mails, "https://api.example.com").
+		WithResetPasswordURL("https://shop.example.com/reset?from=email")
+	return env
+}
+
+// requestToken requests a reset password link for jane and returns the token of the link
+func (env *resetTest) requestToken(t *testing.T) string {
+	err := env.service.RequestResetPassword(context.Background(), "jane@example.com")
+	if err != nil {
+		t.Fatalf("RequestResetPassword() error = %v", err.Error)
+	}
+	if len(env.mails.messages) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(env.mails.messages))
+	}
+
+	link, errURL := url.Parse(linkPattern.FindString(env.mails.messages[0].Body))
+	if errURL != nil {
+		t.Fatal(errURL)
+	}
+	if link.Host != "shop.example.com" || link.Path != "/reset" || link.Query().Get("from") != "email" {
+		t.Errorf("link = %s, want the configured reset password page", link)
+	}
+	return link.Query().Get("token")
+}
+
+func TestResetPassword(t *testing.T) {
+	env := newResetTest(t)
+	ctx := context.Background()
+	token := env.requestToken(t)
+
+	err := env.service.ResetPassword(ctx, &ResetPasswordParams{Token: token, Password: "N3w-Passw0rd!"})
+	if err != nil {
+		t.Fatalf("ResetPassword() error = %v", err.Error)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(env.jane.Password), []byte("N3w-Passw0rd!")) != nil {
+		t.Error("the password was not set")
+	}
+	if env.jane.VerifiedAt == nil {
+		t.Error("the email was not verified by opening the link")
+	}
+	if len(env.sessions.sessions) != 1 || env.sessions.sessions[0].UserID != 8 {
+		t.Errorf("sessions = %+v, want every session of the user logged out", env.sessions.sessions)
+	}
+
+	// the token is single use
+	password := env.jane.Password
+	err = env.service.ResetPassword(ctx, &ResetPasswordParams{Token: token, Password: "An0ther-Passw0rd!"})
+	if err == nil || err.Error != ErrTokenInvalid {
+		t.Errorf("ResetPassword() with the used token error = %v, want %v", err, ErrTokenInvalid)
+	}
+	if env.jane.Password != password {
+		t.Error("the used token changed the password")
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	env := newResetTest(t)
+	token := env.requestToken(t)
+	env.userTokens.userTokens[0].ExpiredAt = time.Now().Add(-time.Second)
+
+	err := env.service.ResetPassword(context.Background(), &ResetPasswordParams{Token: token, Password: "N3w-Passw0rd!"})
+	if err == nil || err.Error != ErrTokenInvalid {
+		t.Errorf("ResetPassword() with the expired token error = %v, want %v", err, ErrTokenInvalid)
+	}
+	if env.jane.Password != "$2a$10$hash" || len(env.sessions.sessions) != 3 {
+		t.Error("the expired token changed the user")
+	}
+}
+
+func TestResetPasswordOtherTokens(t *testing.T) {
+	env := newResetTest(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"unknown token", "unknown"},
+		{"token of another purpose", func() string {
+			token, err := env.service.issueUserToken(ctx, env.jane, TokenVerifyEmail, env.jane.Email, time.Hour)
+			if err != nil {
+				t.Fatal(err.Error)
+			}
+			return token
+		}()},
+	}
+	for _, tt := range tests {
+		err := env.service.ResetPassword(ctx, &ResetPasswordParams{Token: tt.token, Password: "N3w-Passw0rd!"})
+		if err == nil || err.Error != ErrTokenInvalid {
+			t.Errorf("%s: ResetPassword() error = %v, want %v", tt.name, err, ErrTokenInvalid)
+		}
+	}
+}
+
+func TestRequestResetPasswordUnknownEmail(t *testing.T) {
+	env := newResetTest(t)
+
+	err := env.service.RequestResetPassword(context.Background(), "nobody@example.com")
+	if err != nil {
+		t.Fatalf("RequestResetPassword() error = %v", err.Error)
+	}
+	if len(env.mails.messages) != 0 {
+		t.Errorf("sent %d emails, want none", len(env.mails.messages))
+	}
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/riskiramdan/evermos/internal/data"
 	"github.com/riskiramdan/evermos/internal/types"
@@ -18,23 +19,7 @@ type PostgresStorage struct {
 func (s *PostgresStorage) FindAll(ctx context.Context, params *user.FindAllUsersParams) ([]*user.User, *types.Error) {
 
 	users := []*user.User{}
-	where := `"deleted_at" IS NULL`
-
-	if params.UserID != 0 {
-		where += ` AND "id" = :userId`
-	}
-	if params.Email != "" {
-		where += ` AND "email" ILIKE :email`
-	}
-	if params.Name != "" {
-		where += ` AND "name" ILIKE :name`
-	}
-	if params.Search != "" {
-		where += ` AND "name" ILIKE :search`
-	}
-	if params.Token != "" {
-		where += ` AND "token" ILIKE :token`
-	}
+	where, args := userFilter(params)
 
 	if params.Page != 0 && params.Limit != 0 {
 		where = fmt.Sprintf(`%s ORDER BY "id" DESC LIMIT :limit OFFSET :offset`, where)
@@ -42,15 +27,7 @@ func (s *PostgresStorage) FindAll(ctx context.Context, params *user.FindAllUsers
 		where = fmt.Sprintf(`%s ORDER BY "id" DESC`, where)
 	}
 
-	err := s.Storage.Where(ctx, &users, where, map[string]interface{}{
-		"userId": params.UserID,
-		"limit":  params.Limit,
-		"email":  params.Email,
-		"name":   params.Name,
-		"search": "%" + params.Search + "%",
-		"offset": ((params.Page - 1) * params.Limit),
-		"token":  params.Token,
-	})
+	err := s.Storage.Where(ctx, &users, where, args)
 	if err != nil {
 		return nil, &types.Error{
 			Path:    ".UserPostgresStorage->FindAll()",
@@ -63,6 +40,85 @@ func (s *PostgresStorage) FindAll(ctx context.Context, params *user.FindAllUsers
 	return users, nil
 }
 
+// FindAllByCursor find all users paginated by cursor
+func (s *PostgresStorage) FindAllByCursor(ctx context.Context, params *user.FindAllUsersParams) ([]*user.User, *data.CursorPage, *types.Error) {
+
+	users := []*user.User{}
+	where, args := userFilter(params)
+
+	cursors, err := s.Storage.WhereKeyset(ctx, &users, where, args, &data.KeysetParams{
+		SortField: "created_at",
+		Cursor:    params.Cursor,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		return nil, nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindAllByCursor()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return users, cursors, nil
+}
+
+// Count count users
+func (s *PostgresStorage) Count(ctx context.Context, params *user.FindAllUsersParams) (int, *types.Error) {
+	where, args := userFilter(params)
+
+	var count int
+	var err error
+	if params.EstimateCount {
+		count, err = s.Storage.CountEstimate(ctx, where, args)
+	} else {
+		count, err = s.Storage.Count(ctx, where, args)
+	}
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".UserPostgresStorage->Count()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return count, nil
+}
+
+func userFilter(params *user.FindAllUsersParams) (string, map[string]interface{}) {
+	where := `"deleted_at" IS NULL`
+	if params.Deleted {
+		where = `"deleted_at" IS NOT NULL`
+	}
+	if params.DeletedBefore != nil {
+		where += ` AND "deleted_at" < :deletedBefore`
+	}
+
+	if params.UserID != 0 {
+		where += ` AND "id" = :userId`
+	}
+	if params.Email != "" {
+		where += ` AND lower("email") = lower(:email)`
+	}
+	if params.Name != "" {
+		where += ` AND "name" ILIKE :name`
+	}
+	if params.Search != "" {
+		where += ` AND "name" ILIKE :search`
+	}
+
+	return where, map[string]interface{}{
+		"userId":        params.UserID,
+		"limit":         params.Limit,
+		"email":         params.Email,
+		"name":          params.Name,
+		"search":        "%" + params.Search + "%",
+		"offset":        ((params.Page - 1) * params.Limit),
+		"deletedBefore": params.DeletedBefore,
+	}
+}
+
 // FindByID find user by its id
 func (s *PostgresStorage) FindByID(ctx context.Context, userID int) (*user.User, *types.Error) {
 	users, err := s.FindAll(ctx, &user.FindAllUsersParams{
@@ -95,7 +151,7 @@ func (s *PostgresStorage) FindByEmail(ctx context.Context, email string) (*user.
 		return nil, err
 	}
 
-	if len(users) < 1 || users[0].Email != email {
+	if len(users) < 1 || !strings.EqualFold(users[0].Email, email) {
 		return nil, &types.Error{
 			Path:    ".UserPostgresStorage->FindByEmail()",
 			Message: data.ErrNotFound.Error(),
@@ -107,28 +163,6 @@ func (s *PostgresStorage) FindByEmail(ctx context.Context, email string) (*user.
 	return users[0], nil
 }
 
-// FindByToken find user by its token
-func (s *PostgresStorage) FindByToken(ctx context.Context, token string) (*user.User, *types.Error) {
-	users, err := s.FindAll(ctx, &user.FindAllUsersParams{
-		Token: token,
-	})
-	if err != nil {
-		err.Path = ".UserPostgresStorage->FindByToken()" + err.Path
-		return nil, err
-	}
-
-	if len(users) < 1 || (users[0].Token != nil && *users[0].Token != token) {
-		return nil, &types.Error{
-			Path:    ".UserPostgresStorage->FindByToken()",
-			Message: data.ErrNotFound.Error(),
-			Error:   data.ErrNotFound,
-			Type:    "pq-error",
-		}
-	}
-
-	return users[0], nil
-}
-
 // Insert insert user
 func (s *PostgresStorage) Insert(ctx context.Context, user *user.User) (*user.User, *types.Error) {
 	err := s.Storage.Insert(ctx, user)
@@ -174,6 +208,59 @@ func (s *PostgresStorage) Delete(ctx context.Context, userID int) *types.Error {
 	return nil
 }
 
+// FindDeletedByID find soft deleted user by its id
+func (s *PostgresStorage) FindDeletedByID(ctx context.Context, userID int) (*user.User, *types.Error) {
+	users, err := s.FindAll(ctx, &user.FindAllUsersParams{
+		UserID:  userID,
+		Deleted: true,
+	})
+	if err != nil {
+		err.Path = ".UserPostgresStorage->FindDeletedByID()" + err.Path
+		return nil, err
+	}
+
+	if len(users) < 1 || users[0].ID != userID {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindDeletedByID()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return users[0], nil
+}
+
+// Restore restore a soft deleted user
+func (s *PostgresStorage) Restore(ctx context.Context, userID int) *types.Error {
+	err := s.Storage.Restore(ctx, userID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->Restore()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
+// DeleteHard permanently delete a user
+func (s *PostgresStorage) DeleteHard(ctx context.Context, userID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, userID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteHard()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
 // NewPostgresStorage creates new user repository service
 func NewPostgresStorage(
 	storage data.GenericStorage,
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindIdentity find the identity of the account at the issuer
+func (s *PostgresStorage) FindIdentity(ctx context.Context, issuer string, subject string) (*user.Identity, *types.Error) {
+	identity := &user.Identity{}
+	err := s.Storage.Single(ctx, identity, `"deleted_at" IS NULL AND "issuer" = :issuer AND "subject" = :subject`, map[string]interface{}{
+		"issuer":  issuer,
+		"subject": subject,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindIdentity()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return identity, nil
+}
+
+// FindAllIdentities find all identities of the user
+func (s *PostgresStorage) FindAllIdentities(ctx context.Context, userID int) ([]*user.Identity, *types.Error) {
+	identities := []*user.Identity{}
+	err := s.Storage.Where(ctx, &identities, `"deleted_at" IS NULL AND "user_id" = :userId ORDER BY "id"`, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindAllIdentities()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return identities, nil
+}
+
+// InsertIdentity insert identity
+func (s *PostgresStorage) InsertIdentity(ctx context.Context, identity *user.Identity) (*user.Identity, *types.Error) {
+	err := s.Storage.Insert(ctx, identity)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertIdentity()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return identity, nil
+}
+
+// DeleteIdentity delete identity
+func (s *PostgresStorage) DeleteIdentity(ctx context.Context, identityID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, identityID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteIdentity()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
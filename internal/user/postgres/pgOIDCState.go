@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindOIDCStateByHash find oidc state by the hash of its state
+func (s *PostgresStorage) FindOIDCStateByHash(ctx context.Context, stateHash string) (*user.OIDCState, *types.Error) {
+	state := &user.OIDCState{}
+	err := s.Storage.Single(ctx, state, `"deleted_at" IS NULL AND "state_hash" = :stateHash`, map[string]interface{}{
+		"stateHash": stateHash,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindOIDCStateByHash()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return state, nil
+}
+
+// InsertOIDCState insert oidc state
+func (s *PostgresStorage) InsertOIDCState(ctx context.Context, state *user.OIDCState) (*user.OIDCState, *types.Error) {
+	err := s.Storage.Insert(ctx, state)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertOIDCState()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return state, nil
+}
+
+// DeleteOIDCState delete oidc state
+func (s *PostgresStorage) DeleteOIDCState(ctx context.Context, stateID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, stateID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteOIDCState()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredOIDCStates delete the oidc states expired before the time
+func (s *PostgresStorage) DeleteExpiredOIDCStates(ctx context.Context, before time.Time) (int, *types.Error) {
+	deleted, err := s.Storage.DeleteHardWhere(ctx, `"expired_at" < :before`, map[string]interface{}{
+		"before": before,
+	})
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".UserPostgresStorage->DeleteExpiredOIDCStates()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return deleted, nil
+}
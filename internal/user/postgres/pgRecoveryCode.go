@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindAllRecoveryCodes find all recovery codes of the user
+func (s *PostgresStorage) FindAllRecoveryCodes(ctx context.Context, userID int) ([]*user.RecoveryCode, *types.Error) {
+	recoveryCodes := []*user.RecoveryCode{}
+	err := s.Storage.Where(ctx, &recoveryCodes, `"deleted_at" IS NULL AND "user_id" = :userId ORDER BY "id"`, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindAllRecoveryCodes()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return recoveryCodes, nil
+}
+
+// InsertRecoveryCode insert recovery code
+func (s *PostgresStorage) InsertRecoveryCode(ctx context.Context, recoveryCode *user.RecoveryCode) (*user.RecoveryCode, *types.Error) {
+	err := s.Storage.Insert(ctx, recoveryCode)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertRecoveryCode()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return recoveryCode, nil
+}
+
+// UpdateRecoveryCode update recovery code
+func (s *PostgresStorage) UpdateRecoveryCode(ctx context.Context, recoveryCode *user.RecoveryCode) (*user.RecoveryCode, *types.Error) {
+	err := s.Storage.Update(ctx, recoveryCode)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UpdateRecoveryCode()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return recoveryCode, nil
+}
+
+// UseRecoveryCode marks the unused recovery code of the user and the hash as used at the time.
+// The code is checked and updated by a single statement, of concurrent uses only one finds it.
+func (s *PostgresStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (*user.RecoveryCode, *types.Error) {
+	recoveryCodes := []*user.RecoveryCode{}
+
+	err := s.Storage.UpdateWhere(ctx, &recoveryCodes, `"used_at" = :usedAt`,
+		`"user_id" = :userId AND "code_hash" = :codeHash AND "used_at" IS NULL AND "deleted_at" IS NULL`,
+		map[string]interface{}{
+			"userId":   userID,
+			"codeHash": codeHash,
+			"usedAt":   usedAt,
+		})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UseRecoveryCode()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	if len(recoveryCodes) < 1 {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UseRecoveryCode()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return recoveryCodes[0], nil
+}
+
+// DeleteRecoveryCode delete recovery code
+func (s *PostgresStorage) DeleteRecoveryCode(ctx context.Context, recoveryCodeID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, recoveryCodeID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteRecoveryCode()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindRefreshTokenHistoryByHash find rotated refresh token by its hash
+func (s *PostgresStorage) FindRefreshTokenHistoryByHash(ctx context.Context, tokenHash string) (*user.RefreshTokenHistory, *types.Error) {
+	history := &user.RefreshTokenHistory{}
+	err := s.Storage.Single(ctx, history, `"deleted_at" IS NULL AND "token_hash" = :tokenHash`, map[string]interface{}{
+		"tokenHash": tokenHash,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindRefreshTokenHistoryByHash()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return history, nil
+}
+
+// InsertRefreshTokenHistory insert rotated refresh token
+func (s *PostgresStorage) InsertRefreshTokenHistory(ctx context.Context, history *user.RefreshTokenHistory) (*user.RefreshTokenHistory, *types.Error) {
+	err := s.Storage.Insert(ctx, history)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertRefreshTokenHistory()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return history, nil
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindAllSessions find all sessions, latest first
+func (s *PostgresStorage) FindAllSessions(ctx context.Context, params *user.FindAllSessionsParams) ([]*user.Session, *types.Error) {
+	sessions := []*user.Session{}
+
+	where := `"deleted_at" IS NULL`
+	if params.ID != 0 {
+		where += ` AND "id" = :id`
+	}
+	if params.UserID != 0 {
+		where += ` AND "user_id" = :userId`
+	}
+	if params.TokenHash != "" {
+		where += ` AND "token_hash" = :tokenHash`
+	}
+	where += ` ORDER BY "last_seen_at" DESC, "id" DESC`
+
+	err := s.Storage.Where(ctx, &sessions, where, map[string]interface{}{
+		"id":        params.ID,
+		"userId":    params.UserID,
+		"tokenHash": params.TokenHash,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindAllSessions()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return sessions, nil
+}
+
+// FindSessionByTokenHash find session by the hash of its token
+func (s *PostgresStorage) FindSessionByTokenHash(ctx context.Context, tokenHash string) (*user.Session, *types.Error) {
+	session := &user.Session{}
+	err := s.Storage.Single(ctx, session, `"deleted_at" IS NULL AND "token_hash" = :tokenHash`, map[string]interface{}{
+		"tokenHash": tokenHash,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindSessionByTokenHash()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return session, nil
+}
+
+// InsertSession insert session
+func (s *PostgresStorage) InsertSession(ctx context.Context, session *user.Session) (*user.Session, *types.Error) {
+	err := s.Storage.Insert(ctx, session)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertSession()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return session, nil
+}
+
+// UpdateSession update session
+func (s *PostgresStorage) UpdateSession(ctx context.Context, session *user.Session) (*user.Session, *types.Error) {
+	err := s.Storage.Update(ctx, session)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UpdateSession()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return session, nil
+}
+
+// RotateSessionToken replaces the token hash of the unexpired session holding the token hash.
+// The token is checked and replaced by a single statement, of concurrent rotations only one finds it.
+func (s *PostgresStorage) RotateSessionToken(ctx context.Context, tokenHash string, newTokenHash string, rotatedAt time.Time) (*user.Session, *types.Error) {
+	sessions := []*user.Session{}
+
+	err := s.Storage.UpdateWhere(ctx, &sessions, `"token_hash" = :newTokenHash, "last_seen_at" = :rotatedAt`,
+		`"token_hash" = :tokenHash AND "expired_at" > :rotatedAt AND "deleted_at" IS NULL`,
+		map[string]interface{}{
+			"tokenHash":    tokenHash,
+			"newTokenHash": newTokenHash,
+			"rotatedAt":    rotatedAt,
+		})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->RotateSessionToken()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	if len(sessions) < 1 {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->RotateSessionToken()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return sessions[0], nil
+}
+
+// DeleteSession delete session, the row is removed so the token can not be used again
+func (s *PostgresStorage) DeleteSession(ctx context.Context, sessionID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, sessionID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteSession()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions delete the sessions expired before the time
+func (s *PostgresStorage) DeleteExpiredSessions(ctx context.Context, before time.Time) (int, *types.Error) {
+	deleted, err := s.Storage.DeleteHardWhere(ctx, `"expired_at" < :before`, map[string]interface{}{
+		"before": before,
+	})
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".UserPostgresStorage->DeleteExpiredSessions()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return deleted, nil
+}
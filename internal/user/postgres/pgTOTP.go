@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindTOTPByUserID find the totp of the user
+func (s *PostgresStorage) FindTOTPByUserID(ctx context.Context, userID int) (*user.UserTOTP, *types.Error) {
+	userTOTP := &user.UserTOTP{}
+	err := s.Storage.Single(ctx, userTOTP, `"deleted_at" IS NULL AND "user_id" = :userId`, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindTOTPByUserID()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return userTOTP, nil
+}
+
+// InsertTOTP insert totp
+func (s *PostgresStorage) InsertTOTP(ctx context.Context, userTOTP *user.UserTOTP) (*user.UserTOTP, *types.Error) {
+	err := s.Storage.Insert(ctx, userTOTP)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertTOTP()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return userTOTP, nil
+}
+
+// UpdateTOTP update totp
+func (s *PostgresStorage) UpdateTOTP(ctx context.Context, userTOTP *user.UserTOTP) (*user.UserTOTP, *types.Error) {
+	err := s.Storage.Update(ctx, userTOTP)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UpdateTOTP()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return userTOTP, nil
+}
+
+// DeleteTOTP delete totp
+func (s *PostgresStorage) DeleteTOTP(ctx context.Context, userTOTPID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, userTOTPID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteTOTP()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+	"github.com/riskiramdan/evermos/internal/user"
+)
+
+// FindAllUserTokens find all user tokens, latest first
+func (s *PostgresStorage) FindAllUserTokens(ctx context.Context, params *user.FindAllUserTokensParams) ([]*user.UserToken, *types.Error) {
+	userTokens := []*user.UserToken{}
+
+	where := `"deleted_at" IS NULL`
+	if params.UserID != 0 {
+		where += ` AND "user_id" = :userId`
+	}
+	if params.Purpose != "" {
+		where += ` AND "purpose" = :purpose`
+	}
+	if params.TokenHash != "" {
+		where += ` AND "token_hash" = :tokenHash`
+	}
+	if params.CreatedAfter != nil {
+		where += ` AND "created_at" > :createdAfter`
+	}
+	where += ` ORDER BY "id" DESC`
+
+	err := s.Storage.Where(ctx, &userTokens, where, map[string]interface{}{
+		"userId":       params.UserID,
+		"purpose":      params.Purpose,
+		"tokenHash":    params.TokenHash,
+		"createdAfter": params.CreatedAfter,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->FindAllUserTokens()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return userTokens, nil
+}
+
+// InsertUserToken insert user token
+func (s *PostgresStorage) InsertUserToken(ctx context.Context, userToken *user.UserToken) (*user.UserToken, *types.Error) {
+	err := s.Storage.Insert(ctx, userToken)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->InsertUserToken()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return userToken, nil
+}
+
+// UpdateUserToken update user token
+func (s *PostgresStorage) UpdateUserToken(ctx context.Context, userToken *user.UserToken) (*user.UserToken, *types.Error) {
+	err := s.Storage.Update(ctx, userToken)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UpdateUserToken()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return userToken, nil
+}
+
+// UseUserToken marks the unused and unexpired token of the hash and one of the purposes as used at the time.
+// The token is checked and updated by a single statement, of concurrent uses only one finds it.
+func (s *PostgresStorage) UseUserToken(ctx context.Context, tokenHash string, purposes []string, usedAt time.Time) (*user.UserToken, *types.Error) {
+	userTokens := []*user.UserToken{}
+
+	err := s.Storage.UpdateWhere(ctx, &userTokens, `"used_at" = :usedAt`,
+		`"token_hash" = :tokenHash AND "purpose" IN (:purposes) AND "used_at" IS NULL AND "expired_at" > :usedAt AND "deleted_at" IS NULL`,
+		map[string]interface{}{
+			"tokenHash": tokenHash,
+			"purposes":  purposes,
+			"usedAt":    usedAt,
+		})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UseUserToken()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	if len(userTokens) < 1 {
+		return nil, &types.Error{
+			Path:    ".UserPostgresStorage->UseUserToken()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "pq-error",
+		}
+	}
+
+	return userTokens[0], nil
+}
+
+// DeleteUserToken delete user token
+func (s *PostgresStorage) DeleteUserToken(ctx context.Context, userTokenID int) *types.Error {
+	err := s.Storage.DeleteHard(ctx, userTokenID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".UserPostgresStorage->DeleteUserToken()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredUserTokens delete the user tokens expired before the time
+func (s *PostgresStorage) DeleteExpiredUserTokens(ctx context.Context, before time.Time) (int, *types.Error) {
+	deleted, err := s.Storage.DeleteHardWhere(ctx, `"expired_at" < :before`, map[string]interface{}{
+		"before": before,
+	})
+	if err != nil {
+		return 0, &types.Error{
+			Path:    ".UserPostgresStorage->DeleteExpiredUserTokens()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return deleted, nil
+}
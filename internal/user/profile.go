@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/mailer"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// UpdateProfileParams represent the http request data for updating the own profile.
+// A new email only replaces the current one once it is verified.
+// swagger:model
+type UpdateProfileParams struct {
+	Name  string `json:"name" validate:"required,max=80"`
+	Email string `json:"email" validate:"required,useremail"`
+}
+
+// checkEmailAvailable returns ErrEmailAlreadyExists when the email belongs to another user
+func (s *Service) checkEmailAvailable(ctx context.Context, email string, userID int) *types.Error {
+	owner, err := s.userStorage.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".UserService->checkEmailAvailable()" + err.Path
+		return err
+	}
+	if owner.ID != userID {
+		return &types.Error{
+			Path:    ".UserService->checkEmailAvailable()",
+			Message: ErrEmailAlreadyExists.Error(),
+			Error:   ErrEmailAlreadyExists,
+			Type:    "validation-error",
+		}
+	}
+
+	return nil
+}
+
+// UpdateProfile updates the name of the user and emails a verification link to a new email,
+// the user keeps logging in with the current email until the link is opened
+func (s *Service) UpdateProfile(ctx context.Context, userID int, params *UpdateProfileParams) (*User, *types.Error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->UpdateProfile()" + err.Path
+		return nil, err
+	}
+
+	err = s.checkEmailAvailable(ctx, params.Email, user.ID)
+	if err != nil {
+		err.Path = ".UserService->UpdateProfile()" + err.Path
+		return nil, err
+	}
+
+	if params.Email != user.Email {
+		err = s.requestEmailChange(ctx, user, params.Email)
+		if err != nil {
+			err.Path = ".UserService->UpdateProfile()" + err.Path
+			return nil, err
+		}
+	}
+
+	if params.Name != user.Name {
+		now := time.Now()
+		user.Name = params.Name
+		user.UpdatedAt = &now
+		user, err = s.userStorage.Update(ctx, user)
+		if err != nil {
+			err.Path = ".UserService->UpdateProfile()" + err.Path
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// requestEmailChange emails a verification link to the new email, replacing the pending email change
+func (s *Service) requestEmailChange(ctx context.Context, user *User, email string) *types.Error {
+	err := s.limitUserTokens(ctx, user.ID, TokenChangeEmail)
+	if err != nil {
+		err.Path = ".UserService->requestEmailChange()" + err.Path
+		return err
+	}
+
+	err = s.revokeUserTokens(ctx, user.ID, TokenChangeEmail)
+	if err != nil {
+		err.Path = ".UserService->requestEmailChange()" + err.Path
+		return err
+	}
+
+	token, err := s.issueUserToken(ctx, user, TokenChangeEmail, email, verificationLifetime)
+	if err != nil {
+		err.Path = ".UserService->requestEmailChange()" + err.Path
+		return err
+	}
+
+	errSend := s.mailer.Send(ctx, &mailer.Message{
+		To:      email,
+		Subject: "Verify your new email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to use this email for your account, it is valid for %d hours.\n\n%s/v1/verifyEmail?token=%s\n",
+			user.Name, int(verificationLifetime.Hours()), s.appURL, url.QueryEscape(token)),
+	})
+	if errSend != nil {
+		return &types.Error{
+			Path:    ".UserService->requestEmailChange()",
+			Message: errSend.Error(),
+			Error:   errSend,
+			Type:    "golang-error",
+		}
+	}
+
+	return nil
+}
+
+// changeEmail switches the user to the verified new email and lets the previous email know
+func (s *Service) changeEmail(ctx context.Context, user *User, email string) (*User, *types.Error) {
+	err := s.checkEmailAvailable(ctx, email, user.ID)
+	if err != nil {
+		err.Path = ".UserService->changeEmail()" + err.Path
+		return nil, err
+	}
+
+	previousEmail := user.Email
+	now := time.Now()
+	user.Email = email
+	user.VerifiedAt = &now
+	user.UpdatedAt = &now
+	user, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->changeEmail()" + err.Path
+		return nil, err
+	}
+
+	errSend := s.mailer.Send(ctx, &mailer.Message{
+		To:      previousEmail,
+		Subject: "Your email was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nThe email of your account was changed to %s.\n", user.Name, email),
+	})
+	if errSend != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->changeEmail()",
+			Message: errSend.Error(),
+			Error:   errSend,
+			Type:    "golang-error",
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// Roles
+const (
+	RoleAdmin    = "admin"
+	RoleStaff    = "staff"
+	RoleCustomer = "customer"
+	RoleReseller = "reseller"
+)
+
+// Permissions checked per route
+const (
+	PermissionUserRead      = "user:read"
+	PermissionUserWrite     = "user:write"
+	PermissionProductRead   = "product:read"
+	PermissionProductWrite  = "product:write"
+	PermissionProductExport = "product:export"
+	PermissionOrderCreate   = "order:create"
+	PermissionOrderRead     = "order:read"
+	PermissionTrashManage   = "trash:manage"
+	PermissionAuditRead     = "audit:read"
+	PermissionAPIKeyManage  = "apikey:manage"
+)
+
+// ErrInvalidRole is returned when the role is not one of the known roles
+var ErrInvalidRole = errors.New("Role should be one of admin, staff, customer or reseller")
+
+// rolePermissions lists the permissions granted to each role, admin is granted every permission
+var rolePermissions = map[string][]string{
+	RoleStaff: {
+		PermissionUserRead,
+		PermissionProductRead,
+		PermissionProductWrite,
+		PermissionProductExport,
+		PermissionOrderCreate,
+		PermissionOrderRead,
+	},
+	RoleCustomer: {
+		PermissionProductRead,
+		PermissionOrderCreate,
+		PermissionOrderRead,
+	},
+	RoleReseller: {
+		PermissionProductRead,
+		PermissionProductExport,
+		PermissionOrderCreate,
+		PermissionOrderRead,
+	},
+}
+
+// clientPermissions lists the permissions an api key can be granted.
+// Orders and self-service routes belong to a user, so they are left out.
+var clientPermissions = []string{
+	PermissionUserRead,
+	PermissionProductRead,
+	PermissionProductWrite,
+	PermissionProductExport,
+	PermissionAuditRead,
+}
+
+// ClientPermission tells whether the permission can be granted to an api key
+func ClientPermission(permission string) bool {
+	for _, granted := range clientPermissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRole tells whether the role is one of the known roles
+func ValidRole(role string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission tells whether the role is granted the permission
+func HasPermission(role string, permission string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRole(path string, role string) *types.Error {
+	if ValidRole(role) {
+		return nil
+	}
+	return &types.Error{
+		Path:    path,
+		Message: ErrInvalidRole.Error(),
+		Error:   ErrInvalidRole,
+		Type:    "validation-error",
+	}
+}
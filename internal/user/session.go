@@ -0,0 +1,412 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/appcontext"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+const (
+	// defaultSessionLifetime is how long a session stays valid after login unless configured otherwise
+	defaultSessionLifetime = 72 * time.Hour
+
+	// sessionTouchInterval limits how often the last seen time of a session is written
+	sessionTouchInterval = 5 * time.Minute
+
+	// maxUserAgentLength matches the size of the user agent column
+	maxUserAgentLength = 255
+)
+
+// Errors
+var (
+	ErrSessionExpired       = errors.New("session expired")
+	ErrRefreshTokenInvalid  = errors.New("invalid refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token was already used, the session is revoked")
+	ErrAccessTokensDisabled = errors.New("access tokens are not enabled")
+)
+
+// Session represents a logged-in device of a user, only the hash of its token is stored
+type Session struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"userId" db:"user_id"`
+	TokenHash  string     `json:"-" db:"token_hash" audit:"-"`
+	UserAgent  string     `json:"userAgent" db:"user_agent"`
+	IP         string     `json:"ip" db:"ip"`
+	LastSeenAt time.Time  `json:"lastSeenAt" db:"last_seen_at"`
+	ExpiredAt  time.Time  `json:"expiredAt" db:"expired_at"`
+	Current    bool       `json:"current" db:"-"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy  *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt  *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy  *string    `json:"updatedBy" db:"updated_by"`
+}
+
+//FindAllSessionsParams params for find all
+type FindAllSessionsParams struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"userId"`
+	TokenHash string `json:"-"`
+}
+
+// RefreshTokenHistory represents a refresh token of a session which was rotated already.
+// Presenting it again means it leaked, so the whole session is revoked.
+type RefreshTokenHistory struct {
+	ID        int        `json:"id" db:"id"`
+	SessionID int        `json:"sessionId" db:"session_id"`
+	TokenHash string     `json:"-" db:"token_hash" audit:"-"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// RefreshTokenParams represent the http request data for refreshing an access token
+// swagger:model
+type RefreshTokenParams struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// AccessTokenIssuer represents the issuer of the signed access tokens
+type AccessTokenIssuer interface {
+	Issue(userID int, role string, sessionID int) (string, time.Time, error)
+}
+
+// ClientInfo represents the device a user logs in from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionStorage represents the session storage interface
+type SessionStorage interface {
+	FindAllSessions(ctx context.Context, params *FindAllSessionsParams) ([]*Session, *types.Error)
+	FindSessionByTokenHash(ctx context.Context, tokenHash string) (*Session, *types.Error)
+	InsertSession(ctx context.Context, session *Session) (*Session, *types.Error)
+	UpdateSession(ctx context.Context, session *Session) (*Session, *types.Error)
+	RotateSessionToken(ctx context.Context, tokenHash string, newTokenHash string, rotatedAt time.Time) (*Session, *types.Error)
+	DeleteSession(ctx context.Context, sessionID int) *types.Error
+	DeleteExpiredSessions(ctx context.Context, before time.Time) (int, *types.Error)
+}
+
+// RefreshTokenHistoryStorage represents the rotated refresh token storage interface
+type RefreshTokenHistoryStorage interface {
+	FindRefreshTokenHistoryByHash(ctx context.Context, tokenHash string) (*RefreshTokenHistory, *types.Error)
+	InsertRefreshTokenHistory(ctx context.Context, history *RefreshTokenHistory) (*RefreshTokenHistory, *types.Error)
+}
+
+// WithAccessTokens switches the login to signed access tokens.
+// The session token becomes a refresh token, rotated on every refresh and valid for the refresh lifetime.
+func (s *Service) WithAccessTokens(issuer AccessTokenIssuer, refreshLifetime time.Duration) *Service {
+	s.accessTokens = issuer
+	s.refreshLifetime = refreshLifetime
+	return s
+}
+
+// loginResponse builds the response of a new or refreshed session
+func (s *Service) loginResponse(user *User, session *Session, token string) (*LoginResponse, *types.Error) {
+	if s.accessTokens == nil {
+		return &LoginResponse{
+			SessionID:        token,
+			SessionExpiredAt: &session.ExpiredAt,
+			User:             user,
+		}, nil
+	}
+
+	accessToken, expiredAt, errIssue := s.accessTokens.Issue(user.ID, user.Role, session.ID)
+	if errIssue != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->loginResponse()",
+			Message: errIssue.Error(),
+			Error:   errIssue,
+			Type:    "golang-error",
+		}
+	}
+
+	return &LoginResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiredAt: &expiredAt,
+		RefreshToken:         token,
+		User:                 user,
+	}, nil
+}
+
+// WithSessionExpiry sets the absolute lifetime of the sessions and their idle timeout.
+// With an idle timeout a session expires when it is not used for as long, and is renewed while used
+// until the absolute lifetime is reached. Sessions are not renewed when access tokens are enabled.
+func (s *Service) WithSessionExpiry(absolute time.Duration, idle time.Duration) *Service {
+	s.sessionAbsoluteLifetime = absolute
+	s.sessionIdleTimeout = idle
+	return s
+}
+
+// sessionExpiry returns when a session created at the time and used now expires
+func (s *Service) sessionExpiry(createdAt time.Time, now time.Time) time.Time {
+	if s.accessTokens != nil {
+		return createdAt.Add(s.refreshLifetime)
+	}
+
+	limit := createdAt.Add(s.sessionAbsoluteLifetime)
+	if s.sessionIdleTimeout > 0 && now.Add(s.sessionIdleTimeout).Before(limit) {
+		return now.Add(s.sessionIdleTimeout)
+	}
+	return limit
+}
+
+// createSession issues a new token for the user, the raw token is only returned once
+func (s *Service) createSession(ctx context.Context, userID int, client *ClientInfo) (string, *Session, *types.Error) {
+	token, errToken := generateToken()
+	if errToken != nil {
+		return "", nil, &types.Error{
+			Path:    ".UserService->createSession()",
+			Message: errToken.Error(),
+			Error:   errToken,
+			Type:    "golang-error",
+		}
+	}
+
+	if client == nil {
+		client = &ClientInfo{}
+	}
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	session, err := s.sessionStorage.InsertSession(ctx, &Session{
+		UserID:     userID,
+		TokenHash:  hashToken(token),
+		UserAgent:  userAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiredAt:  s.sessionExpiry(now, now),
+		CreatedAt:  now,
+		UpdatedAt:  &now,
+	})
+	if err != nil {
+		err.Path = ".UserService->createSession()" + err.Path
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+// GetSession get the session of the token, expired sessions are not returned
+func (s *Service) GetSession(ctx context.Context, token string) (*Session, *types.Error) {
+	session, err := s.sessionStorage.FindSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		err.Path = ".UserService->GetSession()" + err.Path
+		return nil, err
+	}
+	if session.ExpiredAt.Before(time.Now()) {
+		return nil, &types.Error{
+			Path:    ".UserService->GetSession()",
+			Message: ErrSessionExpired.Error(),
+			Error:   ErrSessionExpired,
+			Type:    "validation-error",
+		}
+	}
+
+	return session, nil
+}
+
+// TouchSession records the session as seen now and renews it when it is used in the second half of its idle timeout.
+// The write is skipped when the session was seen recently so not every request hits the database.
+func (s *Service) TouchSession(ctx context.Context, session *Session) *types.Error {
+	now := time.Now()
+	expiredAt := session.ExpiredAt
+	if s.accessTokens == nil && s.sessionIdleTimeout > 0 && session.ExpiredAt.Sub(now) < s.sessionIdleTimeout/2 {
+		expiredAt = s.sessionExpiry(session.CreatedAt, now)
+	}
+	renew := expiredAt.After(session.ExpiredAt)
+	if !renew && now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+
+	if renew {
+		session.ExpiredAt = expiredAt
+	}
+	session.LastSeenAt = now
+	session.UpdatedAt = &now
+	_, err := s.sessionStorage.UpdateSession(ctx, session)
+	if err != nil {
+		err.Path = ".UserService->TouchSession()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// ListSessions is listing the sessions of the user, the session of the current request is marked
+func (s *Service) ListSessions(ctx context.Context, userID int) ([]*Session, *types.Error) {
+	sessions, err := s.sessionStorage.FindAllSessions(ctx, &FindAllSessionsParams{
+		UserID: userID,
+	})
+	if err != nil {
+		err.Path = ".UserService->ListSessions()" + err.Path
+		return nil, err
+	}
+
+	currentID := appcontext.UserSessionID(ctx)
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs the user out of one of its sessions
+func (s *Service) RevokeSession(ctx context.Context, userID int, sessionID int) *types.Error {
+	sessions, err := s.sessionStorage.FindAllSessions(ctx, &FindAllSessionsParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		err.Path = ".UserService->RevokeSession()" + err.Path
+		return err
+	}
+	if len(sessions) < 1 {
+		return &types.Error{
+			Path:    ".UserService->RevokeSession()",
+			Message: data.ErrNotFound.Error(),
+			Error:   data.ErrNotFound,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.sessionStorage.DeleteSession(ctx, sessionID)
+	if err != nil {
+		err.Path = ".UserService->RevokeSession()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// LogoutAll logs the user out of all of its sessions
+func (s *Service) LogoutAll(ctx context.Context, userID int) *types.Error {
+	sessions, err := s.sessionStorage.FindAllSessions(ctx, &FindAllSessionsParams{
+		UserID: userID,
+	})
+	if err != nil {
+		err.Path = ".UserService->LogoutAll()" + err.Path
+		return err
+	}
+
+	for _, session := range sessions {
+		err = s.sessionStorage.DeleteSession(ctx, session.ID)
+		if err != nil {
+			err.Path = ".UserService->LogoutAll()" + err.Path
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RefreshToken rotates the refresh token of the session and issues a new access token.
+// A refresh token which was rotated already revokes its session, so does one rotated concurrently.
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, *types.Error) {
+	if s.accessTokens == nil {
+		return nil, &types.Error{
+			Path:    ".UserService->RefreshToken()",
+			Message: ErrAccessTokensDisabled.Error(),
+			Error:   ErrAccessTokensDisabled,
+			Type:    "validation-error",
+		}
+	}
+
+	tokenHash := hashToken(refreshToken)
+	session, err := s.sessionStorage.FindSessionByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if err.Error != data.ErrNotFound {
+			err.Path = ".UserService->RefreshToken()" + err.Path
+			return nil, err
+		}
+		return nil, s.refreshTokenNotFound(ctx, tokenHash)
+	}
+	if session.ExpiredAt.Before(time.Now()) {
+		return nil, &types.Error{
+			Path:    ".UserService->RefreshToken()",
+			Message: ErrSessionExpired.Error(),
+			Error:   ErrSessionExpired,
+			Type:    "validation-error",
+		}
+	}
+
+	user, err := s.GetUser(ctx, session.UserID)
+	if err != nil {
+		err.Path = ".UserService->RefreshToken()" + err.Path
+		return nil, err
+	}
+
+	token, errToken := generateToken()
+	if errToken != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->RefreshToken()",
+			Message: errToken.Error(),
+			Error:   errToken,
+			Type:    "golang-error",
+		}
+	}
+	// a concurrent refresh with the same token rotated it first, which is handled as a reuse
+	session, err = s.sessionStorage.RotateSessionToken(ctx, tokenHash, hashToken(token), time.Now())
+	if err != nil {
+		if err.Error != data.ErrNotFound {
+			err.Path = ".UserService->RefreshToken()" + err.Path
+			return nil, err
+		}
+		return nil, s.refreshTokenNotFound(ctx, tokenHash)
+	}
+
+	_, err = s.refreshHistoryStorage.InsertRefreshTokenHistory(ctx, &RefreshTokenHistory{
+		SessionID: session.ID,
+		TokenHash: tokenHash,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		err.Path = ".UserService->RefreshToken()" + err.Path
+		return nil, err
+	}
+
+	response, err := s.loginResponse(user, session, token)
+	if err != nil {
+		err.Path = ".UserService->RefreshToken()" + err.Path
+		return nil, err
+	}
+	return response, nil
+}
+
+// refreshTokenNotFound revokes the session when the refresh token was rotated already
+func (s *Service) refreshTokenNotFound(ctx context.Context, tokenHash string) *types.Error {
+	history, err := s.refreshHistoryStorage.FindRefreshTokenHistoryByHash(ctx, tokenHash)
+	if err != nil {
+		if err.Error != data.ErrNotFound {
+			err.Path = ".UserService->refreshTokenNotFound()" + err.Path
+			return err
+		}
+		return &types.Error{
+			Path:    ".UserService->refreshTokenNotFound()",
+			Message: ErrRefreshTokenInvalid.Error(),
+			Error:   ErrRefreshTokenInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.sessionStorage.DeleteSession(ctx, history.SessionID)
+	if err != nil {
+		err.Path = ".UserService->refreshTokenNotFound()" + err.Path
+		return err
+	}
+
+	return &types.Error{
+		Path:    ".UserService->refreshTokenNotFound()",
+		Message: ErrRefreshTokenReused.Error(),
+		Error:   ErrRefreshTokenReused,
+		Type:    "validation-error",
+	}
+}
@@ -0,0 +1,119 @@
+package user
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// stubIssuer issues unsigned access tokens naming the session
+type stubIssuer struct{}
+
+func (stubIssuer) Issue(userID int, role string, sessionID int) (string, time.Time, error) {
+	return "access-" + strconv.Itoa(sessionID), time.Now().Add(15 * time.Minute), nil
+}
+
+// staleSessionStorage returns the session as read before the last rotation,
+// as a concurrent refresh which read the session before the other one committed
+type staleSessionStorage struct {
+	*memSessionStorage
+	stale *Session
+}
+
+func (s *staleSessionStorage) FindSessionByTokenHash(ctx context.Context, tokenHash string) (*Session, *types.Error) {
+	if s.stale != nil && s.stale.TokenHash == tokenHash {
+		found := *s.stale
+		return &found, nil
+	}
+	return s.memSessionStorage.FindSessionByTokenHash(ctx, tokenHash)
+}
+
+func newRefreshTest(t *testing.T) (*Service, *memSessionStorage, string) {
+	jane := &User{ID: 7, Name: "Jane", Email: "jane@example.com", Role: RoleCustomer}
+	sessions := &memSessionStorage{}
+	service := NewService(&memUserStorage{users: []*User{jane}}, sessions, &memRefreshTokenHistoryStorage{}, nil, &memTOTPStorage{}, nil, nil, "").
+		WithAccessTokens(stubIssuer{}, time.Hour)
+
+	response, err := service.openSession(context.Background(), jane, nil)
+	if err != nil {
+		t.Fatalf("openSession() error = %v", err.Error)
+	}
+	return service, sessions, response.RefreshToken
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	service, sessions, refreshToken := newRefreshTest(t)
+	ctx := context.Background()
+
+	response, err := service.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken() error = %v", err.Error)
+	}
+	if response.RefreshToken == "" || response.RefreshToken == refreshToken || response.AccessToken != "access-1" {
+		t.Errorf("RefreshToken() = %+v, want a new refresh token of the session", response)
+	}
+
+	// the new token is rotated in turn
+	_, err = service.RefreshToken(ctx, response.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken() with the rotated token error = %v", err.Error)
+	}
+	if len(sessions.sessions) != 1 {
+		t.Errorf("got %d sessions, want 1", len(sessions.sessions))
+	}
+
+	_, err = service.RefreshToken(ctx, "unknown")
+	if err == nil || err.Error != ErrRefreshTokenInvalid {
+		t.Errorf("RefreshToken() with an unknown token error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	service, sessions, refreshToken := newRefreshTest(t)
+	ctx := context.Background()
+
+	response, err := service.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken() error = %v", err.Error)
+	}
+
+	_, err = service.RefreshToken(ctx, refreshToken)
+	if err == nil || err.Error != ErrRefreshTokenReused {
+		t.Fatalf("RefreshToken() with the rotated token error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if len(sessions.sessions) != 0 {
+		t.Errorf("got %d sessions, want the session revoked", len(sessions.sessions))
+	}
+
+	// the token of the legitimate client is revoked along with the session
+	_, err = service.RefreshToken(ctx, response.RefreshToken)
+	if err == nil || err.Error != ErrRefreshTokenInvalid {
+		t.Errorf("RefreshToken() after the revocation error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestRefreshTokenConcurrentReuseRevokesSession(t *testing.T) {
+	service, sessions, refreshToken := newRefreshTest(t)
+	ctx := context.Background()
+
+	stale := *sessions.sessions[0]
+	storage := &staleSessionStorage{memSessionStorage: sessions, stale: &stale}
+	service.sessionStorage = storage
+
+	_, err := service.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken() error = %v", err.Error)
+	}
+
+	// the second refresh still finds the session, the rotation does not
+	_, err = service.RefreshToken(ctx, refreshToken)
+	if err == nil || err.Error != ErrRefreshTokenReused {
+		t.Fatalf("concurrent RefreshToken() error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if len(sessions.sessions) != 0 {
+		t.Errorf("got %d sessions, want the session revoked", len(sessions.sessions))
+	}
+}
@@ -0,0 +1,226 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/mailer"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+func notFound(path string) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: data.ErrNotFound.Error(),
+		Error:   data.ErrNotFound,
+		Type:    "pq-error",
+	}
+}
+
+// the in-memory storages implement the methods the login with the provider uses,
+// the others are left to the nil interfaces
+type memUserStorage struct {
+	Storage
+	users []*User
+}
+
+func (s *memUserStorage) FindByID(ctx context.Context, userID int) (*User, *types.Error) {
+	for _, u := range s.users {
+		if u.ID == userID {
+			return u, nil
+		}
+	}
+	return nil, notFound(".memUserStorage->FindByID()")
+}
+
+func (s *memUserStorage) FindByEmail(ctx context.Context, email string) (*User, *types.Error) {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, notFound(".memUserStorage->FindByEmail()")
+}
+
+func (s *memUserStorage) Insert(ctx context.Context, user *User) (*User, *types.Error) {
+	user.ID = len(s.users) + 1
+	s.users = append(s.users, user)
+	return user, nil
+}
+
+func (s *memUserStorage) Update(ctx context.Context, user *User) (*User, *types.Error) {
+	return user, nil
+}
+
+type memSessionStorage struct {
+	SessionStorage
+	sessions []*Session
+}
+
+func (s *memSessionStorage) FindAllSessions(ctx context.Context, params *FindAllSessionsParams) ([]*Session, *types.Error) {
+	sessions := []*Session{}
+	for _, session := range s.sessions {
+		if session.UserID == params.UserID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *memSessionStorage) InsertSession(ctx context.Context, session *Session) (*Session, *types.Error) {
+	session.ID = len(s.sessions) + 1
+	s.sessions = append(s.sessions, session)
+	return session, nil
+}
+
+func (s *memSessionStorage) FindSessionByTokenHash(ctx context.Context, tokenHash string) (*Session, *types.Error) {
+	for _, session := range s.sessions {
+		if session.TokenHash == tokenHash {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, notFound(".memSessionStorage->FindSessionByTokenHash()")
+}
+
+func (s *memSessionStorage) RotateSessionToken(ctx context.Context, tokenHash string, newTokenHash string, rotatedAt time.Time) (*Session, *types.Error) {
+	for _, session := range s.sessions {
+		if session.TokenHash == tokenHash && session.ExpiredAt.After(rotatedAt) {
+			session.TokenHash = newTokenHash
+			session.LastSeenAt = rotatedAt
+			rotated := *session
+			return &rotated, nil
+		}
+	}
+	return nil, notFound(".memSessionStorage->RotateSessionToken()")
+}
+
+func (s *memSessionStorage) DeleteSession(ctx context.Context, sessionID int) *types.Error {
+	for i, session := range s.sessions {
+		if session.ID == sessionID {
+			s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return notFound(".memSessionStorage->DeleteSession()")
+}
+
+type memTOTPStorage struct {
+	TOTPStorage
+	totps []*UserTOTP
+}
+
+func (s *memTOTPStorage) FindTOTPByUserID(ctx context.Context, userID int) (*UserTOTP, *types.Error) {
+	for _, userTOTP := range s.totps {
+		if userTOTP.UserID == userID {
+			return userTOTP, nil
+		}
+	}
+	return nil, notFound(".memTOTPStorage->FindTOTPByUserID()")
+}
+
+func (s *memTOTPStorage) UpdateTOTP(ctx context.Context, userTOTP *UserTOTP) (*UserTOTP, *types.Error) {
+	return userTOTP, nil
+}
+
+type memRecoveryCodeStorage struct {
+	RecoveryCodeStorage
+	recoveryCodes []*RecoveryCode
+}
+
+func (s *memRecoveryCodeStorage) FindAllRecoveryCodes(ctx context.Context, userID int) ([]*RecoveryCode, *types.Error) {
+	recoveryCodes := []*RecoveryCode{}
+	for _, recoveryCode := range s.recoveryCodes {
+		if recoveryCode.UserID == userID {
+			recoveryCodes = append(recoveryCodes, recoveryCode)
+		}
+	}
+	return recoveryCodes, nil
+}
+
+func (s *memRecoveryCodeStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (*RecoveryCode, *types.Error) {
+	for _, recoveryCode := range s.recoveryCodes {
+		if recoveryCode.UserID == userID && recoveryCode.CodeHash == codeHash && recoveryCode.UsedAt == nil {
+			recoveryCode.UsedAt = &usedAt
+			return recoveryCode, nil
+		}
+	}
+	return nil, notFound(".memRecoveryCodeStorage->UseRecoveryCode()")
+}
+
+type memRefreshTokenHistoryStorage struct {
+	histories []*RefreshTokenHistory
+}
+
+func (s *memRefreshTokenHistoryStorage) FindRefreshTokenHistoryByHash(ctx context.Context, tokenHash string) (*RefreshTokenHistory, *types.Error) {
+	for _, history := range s.histories {
+		if history.TokenHash == tokenHash {
+			return history, nil
+		}
+	}
+	return nil, notFound(".memRefreshTokenHistoryStorage->FindRefreshTokenHistoryByHash()")
+}
+
+func (s *memRefreshTokenHistoryStorage) InsertRefreshTokenHistory(ctx context.Context, history *RefreshTokenHistory) (*RefreshTokenHistory, *types.Error) {
+	for _, existing := range s.histories {
+		if existing.TokenHash == history.TokenHash {
+			return nil, &types.Error{
+				Path:    ".memRefreshTokenHistoryStorage->InsertRefreshTokenHistory()",
+				Message: data.ErrAlreadyExist.Error(),
+				Error:   data.ErrAlreadyExist,
+				Type:    "pq-error",
+			}
+		}
+	}
+	history.ID = len(s.histories) + 1
+	s.histories = append(s.histories, history)
+	return history, nil
+}
+
+type memUserTokenStorage struct {
+	UserTokenStorage
+	userTokens []*UserToken
+}
+
+// FindAllUserTokens returns the tokens latest first
+func (s *memUserTokenStorage) FindAllUserTokens(ctx context.Context, params *FindAllUserTokensParams) ([]*UserToken, *types.Error) {
+	userTokens := []*UserToken{}
+	for i := len(s.userTokens) - 1; i >= 0; i-- {
+		userToken := s.userTokens[i]
+		if (params.UserID == 0 || userToken.UserID == params.UserID) &&
+			(params.Purpose == "" || userToken.Purpose == params.Purpose) &&
+			(params.TokenHash == "" || userToken.TokenHash == params.TokenHash) &&
+			(params.CreatedAfter == nil || userToken.CreatedAt.After(*params.CreatedAfter)) {
+			userTokens = append(userTokens, userToken)
+		}
+	}
+	return userTokens, nil
+}
+
+func (s *memUserTokenStorage) InsertUserToken(ctx context.Context, userToken *UserToken) (*UserToken, *types.Error) {
+	userToken.ID = len(s.userTokens) + 1
+	s.userTokens = append(s.userTokens, userToken)
+	return userToken, nil
+}
+
+func (s *memUserTokenStorage) UseUserToken(ctx context.Context, tokenHash string, purposes []string, usedAt time.Time) (*UserToken, *types.Error) {
+	for _, userToken := range s.userTokens {
+		if userToken.TokenHash == tokenHash && hasPurpose(userToken, purposes) &&
+			userToken.UsedAt == nil && userToken.ExpiredAt.After(usedAt) {
+			userToken.UsedAt = &usedAt
+			return userToken, nil
+		}
+	}
+	return nil, notFound(".memUserTokenStorage->UseUserToken()")
+}
+
+// memMailer keeps the messages instead of sending them
+type memMailer struct {
+	messages []*mailer.Message
+}
+
+func (m *memMailer) Send(ctx context.Context, message *mailer.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
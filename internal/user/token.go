@@ -0,0 +1,218 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// User token purposes
+const (
+	TokenVerifyEmail    = "verify-email"
+	TokenChangeEmail    = "change-email"
+	TokenResetPassword  = "reset-password"
+	TokenLoginChallenge = "login-challenge"
+)
+
+const (
+	// userTokenInterval is the minimum time between two tokens of the same purpose sent to a user
+	userTokenInterval = time.Minute
+
+	// maxUserTokensPerHour limits the tokens of the same purpose sent to a user per hour
+	maxUserTokensPerHour = 5
+)
+
+// ErrTokenInvalid is returned when the token is unknown, used already or expired
+var ErrTokenInvalid = errors.New("Token is invalid or expired")
+
+// UserToken represents a single-use token sent to the user by email, only the hash of the token is stored
+type UserToken struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"userId" db:"user_id"`
+	Purpose   string     `json:"purpose" db:"purpose"`
+	TokenHash string     `json:"-" db:"token_hash" audit:"-"`
+	Email     string     `json:"email" db:"email"`
+	ExpiredAt time.Time  `json:"expiredAt" db:"expired_at"`
+	UsedAt    *time.Time `json:"usedAt" db:"used_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+}
+
+//FindAllUserTokensParams params for find all
+type FindAllUserTokensParams struct {
+	UserID       int        `json:"userId"`
+	Purpose      string     `json:"purpose"`
+	TokenHash    string     `json:"-"`
+	CreatedAfter *time.Time `json:"createdAfter"`
+}
+
+// UserTokenStorage represents the user token storage interface
+type UserTokenStorage interface {
+	FindAllUserTokens(ctx context.Context, params *FindAllUserTokensParams) ([]*UserToken, *types.Error)
+	InsertUserToken(ctx context.Context, userToken *UserToken) (*UserToken, *types.Error)
+	UpdateUserToken(ctx context.Context, userToken *UserToken) (*UserToken, *types.Error)
+	UseUserToken(ctx context.Context, tokenHash string, purposes []string, usedAt time.Time) (*UserToken, *types.Error)
+	DeleteUserToken(ctx context.Context, userTokenID int) *types.Error
+	DeleteExpiredUserTokens(ctx context.Context, before time.Time) (int, *types.Error)
+}
+
+// issueUserToken creates a token of the purpose for the user, the raw token is only returned once
+func (s *Service) issueUserToken(ctx context.Context, user *User, purpose string, email string, lifetime time.Duration) (string, *types.Error) {
+	token, errToken := generateToken()
+	if errToken != nil {
+		return "", &types.Error{
+			Path:    ".UserService->issueUserToken()",
+			Message: errToken.Error(),
+			Error:   errToken,
+			Type:    "golang-error",
+		}
+	}
+
+	now := time.Now()
+	_, err := s.userTokenStorage.InsertUserToken(ctx, &UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     email,
+		ExpiredAt: now.Add(lifetime),
+		CreatedAt: now,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		err.Path = ".UserService->issueUserToken()" + err.Path
+		return "", err
+	}
+
+	return token, nil
+}
+
+// findUserToken returns the valid token of one of the purposes without using it,
+// unknown, used and expired tokens are refused alike
+func (s *Service) findUserToken(ctx context.Context, token string, purposes ...string) (*UserToken, *types.Error) {
+	userTokens, err := s.userTokenStorage.FindAllUserTokens(ctx, &FindAllUserTokensParams{
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		err.Path = ".UserService->findUserToken()" + err.Path
+		return nil, err
+	}
+
+	if len(userTokens) < 1 || !hasPurpose(userTokens[0], purposes) ||
+		userTokens[0].UsedAt != nil || userTokens[0].ExpiredAt.Before(time.Now()) {
+		return nil, &types.Error{
+			Path:    ".UserService->findUserToken()",
+			Message: ErrTokenInvalid.Error(),
+			Error:   ErrTokenInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	return userTokens[0], nil
+}
+
+// useUserToken marks the token of one of the purposes as used and returns it.
+// The check and the update are atomic so a token can not be used twice by concurrent requests.
+func (s *Service) useUserToken(ctx context.Context, token string, purposes ...string) (*UserToken, *types.Error) {
+	userToken, err := s.userTokenStorage.UseUserToken(ctx, hashToken(token), purposes, time.Now())
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, &types.Error{
+				Path:    ".UserService->useUserToken()",
+				Message: ErrTokenInvalid.Error(),
+				Error:   ErrTokenInvalid,
+				Type:    "validation-error",
+			}
+		}
+		err.Path = ".UserService->useUserToken()" + err.Path
+		return nil, err
+	}
+
+	return userToken, nil
+}
+
+// revokeUserTokens marks the pending tokens of the purpose of the user as used
+func (s *Service) revokeUserTokens(ctx context.Context, userID int, purpose string) *types.Error {
+	userTokens, err := s.userTokenStorage.FindAllUserTokens(ctx, &FindAllUserTokensParams{
+		UserID:  userID,
+		Purpose: purpose,
+	})
+	if err != nil {
+		err.Path = ".UserService->revokeUserTokens()" + err.Path
+		return err
+	}
+
+	now := time.Now()
+	for _, userToken := range userTokens {
+		if userToken.UsedAt != nil || userToken.ExpiredAt.Before(now) {
+			continue
+		}
+		userToken.UsedAt = &now
+		userToken.UpdatedAt = &now
+		_, err = s.userTokenStorage.UpdateUserToken(ctx, userToken)
+		if err != nil {
+			err.Path = ".UserService->revokeUserTokens()" + err.Path
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasPurpose(userToken *UserToken, purposes []string) bool {
+	for _, purpose := range purposes {
+		if userToken.Purpose == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// limitUserTokens returns ErrTooManyRequests when too many tokens of the purpose were sent to the user lately
+func (s *Service) limitUserTokens(ctx context.Context, userID int, purpose string) *types.Error {
+	now := time.Now()
+	hourAgo := now.Add(-time.Hour)
+	sent, err := s.userTokenStorage.FindAllUserTokens(ctx, &FindAllUserTokensParams{
+		UserID:       userID,
+		Purpose:      purpose,
+		CreatedAfter: &hourAgo,
+	})
+	if err != nil {
+		err.Path = ".UserService->limitUserTokens()" + err.Path
+		return err
+	}
+	// tokens are sorted latest first
+	if len(sent) >= maxUserTokensPerHour || (len(sent) > 0 && now.Sub(sent[0].CreatedAt) < userTokenInterval) {
+		return &types.Error{
+			Path:    ".UserService->limitUserTokens()",
+			Message: ErrTooManyRequests.Error(),
+			Error:   ErrTooManyRequests,
+			Type:    "validation-error",
+		}
+	}
+
+	return nil
+}
+
+// tokenUser returns the user of the token, a deleted user makes the token invalid
+func (s *Service) tokenUser(ctx context.Context, userToken *UserToken) (*User, *types.Error) {
+	user, err := s.GetUser(ctx, userToken.UserID)
+	if err != nil {
+		if err.Error != data.ErrNotFound {
+			err.Path = ".UserService->tokenUser()" + err.Path
+			return nil, err
+		}
+		return nil, &types.Error{
+			Path:    ".UserService->tokenUser()",
+			Message: ErrTokenInvalid.Error(),
+			Error:   ErrTokenInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	return user, nil
+}
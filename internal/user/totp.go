@@ -0,0 +1,726 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/totp"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// Second factors a login has to be completed with
+const (
+	SecondFactorTOTP       = "totp"
+	SecondFactorTOTPEnroll = "totp-enroll"
+)
+
+const (
+	// challengeLifetime is how long the second factor of a login can be entered
+	challengeLifetime = 5 * time.Minute
+
+	// recoveryCodeCount is the number of recovery codes generated at once
+	recoveryCodeCount = 10
+
+	// recoveryCodeAlphabet leaves out the characters which are easily mistaken for each other
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// Errors
+var (
+	ErrTOTPInvalid        = errors.New("Authentication code is wrong")
+	ErrTOTPNotEnabled     = errors.New("Two-factor authentication is not enabled")
+	ErrTOTPNotEnrolled    = errors.New("Two-factor authentication is not enrolled")
+	ErrTOTPAlreadyEnabled = errors.New("Two-factor authentication is already enabled")
+	ErrTOTPRequired       = errors.New("Two-factor authentication is required for the role")
+)
+
+// UserTOTP represents the TOTP secret of a user, it is enabled once a first code is verified
+type UserTOTP struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"userId" db:"user_id"`
+	Secret    string     `json:"-" db:"secret" audit:"-"`
+	EnabledAt *time.Time `json:"enabledAt" db:"enabled_at"`
+	LastStep  int64      `json:"-" db:"last_step"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// RecoveryCode represents a single-use code which replaces the TOTP code, only the hash of the code is stored
+type RecoveryCode struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"userId" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash" audit:"-"`
+	UsedAt    *time.Time `json:"usedAt" db:"used_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy *string    `json:"updatedBy" db:"updated_by"`
+}
+
+// TOTPStatus is the two-factor authentication state of a user
+// swagger:model
+type TOTPStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabledAt"`
+	Required          bool       `json:"required"`
+	RecoveryCodesLeft int        `json:"recoveryCodesLeft"`
+}
+
+// TOTPEnrollment is the secret to add to an authenticator app, the uri is meant to be shown as a QR code
+// swagger:model
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// ActivateTOTPParams is the first code of the enrolled secret
+// swagger:model
+type ActivateTOTPParams struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// SecondFactorParams is a TOTP code or a recovery code
+// swagger:model
+type SecondFactorParams struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// LoginChallengeParams is the challenge token returned by a login which requires a second factor
+// swagger:model
+type LoginChallengeParams struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+}
+
+// CompleteLoginParams completes a login challenged for the second factor
+// swagger:model
+type CompleteLoginParams struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+// TOTPStorage represents the TOTP storage interface
+type TOTPStorage interface {
+	FindTOTPByUserID(ctx context.Context, userID int) (*UserTOTP, *types.Error)
+	InsertTOTP(ctx context.Context, userTOTP *UserTOTP) (*UserTOTP, *types.Error)
+	UpdateTOTP(ctx context.Context, userTOTP *UserTOTP) (*UserTOTP, *types.Error)
+	DeleteTOTP(ctx context.Context, userTOTPID int) *types.Error
+}
+
+// RecoveryCodeStorage represents the recovery code storage interface
+type RecoveryCodeStorage interface {
+	FindAllRecoveryCodes(ctx context.Context, userID int) ([]*RecoveryCode, *types.Error)
+	InsertRecoveryCode(ctx context.Context, recoveryCode *RecoveryCode) (*RecoveryCode, *types.Error)
+	UpdateRecoveryCode(ctx context.Context, recoveryCode *RecoveryCode) (*RecoveryCode, *types.Error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (*RecoveryCode, *types.Error)
+	DeleteRecoveryCode(ctx context.Context, recoveryCodeID int) *types.Error
+}
+
+// WithTOTPPolicy sets the issuer shown in the authenticator apps and the roles which can not log in without TOTP
+func (s *Service) WithTOTPPolicy(issuer string, requiredRoles []string) *Service {
+	if issuer != "" {
+		s.totpIssuer = issuer
+	}
+	s.totpRequiredRoles = map[string]bool{}
+	for _, role := range requiredRoles {
+		s.totpRequiredRoles[role] = true
+	}
+	return s
+}
+
+// findTOTP returns the TOTP of the user, nil when the user never enrolled
+func (s *Service) findTOTP(ctx context.Context, userID int) (*UserTOTP, *types.Error) {
+	userTOTP, err := s.totpStorage.FindTOTPByUserID(ctx, userID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, nil
+		}
+		err.Path = ".UserService->findTOTP()" + err.Path
+		return nil, err
+	}
+	return userTOTP, nil
+}
+
+// secondFactor returns the second factor the login of the user has to be completed with, empty when none
+func (s *Service) secondFactor(ctx context.Context, user *User) (string, *types.Error) {
+	userTOTP, err := s.findTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->secondFactor()" + err.Path
+		return "", err
+	}
+
+	if userTOTP != nil && userTOTP.EnabledAt != nil {
+		return SecondFactorTOTP, nil
+	}
+	if s.totpRequiredRoles[user.Role] {
+		return SecondFactorTOTPEnroll, nil
+	}
+	return "", nil
+}
+
+// loginChallenge answers a correct password with a challenge token instead of a session
+func (s *Service) loginChallenge(ctx context.Context, user *User, secondFactor string) (*LoginResponse, *types.Error) {
+	token, err := s.issueUserToken(ctx, user, TokenLoginChallenge, user.Email, challengeLifetime)
+	if err != nil {
+		err.Path = ".UserService->loginChallenge()" + err.Path
+		return nil, err
+	}
+
+	expiredAt := time.Now().Add(challengeLifetime)
+	return &LoginResponse{
+		SecondFactor:       secondFactor,
+		ChallengeToken:     token,
+		ChallengeExpiredAt: &expiredAt,
+	}, nil
+}
+
+// challengeUser returns the user of the challenge token without using the token
+func (s *Service) challengeUser(ctx context.Context, challengeToken string) (*User, *types.Error) {
+	userToken, err := s.findUserToken(ctx, challengeToken, TokenLoginChallenge)
+	if err != nil {
+		err.Path = ".UserService->challengeUser()" + err.Path
+		return nil, err
+	}
+
+	user, err := s.tokenUser(ctx, userToken)
+	if err != nil {
+		err.Path = ".UserService->challengeUser()" + err.Path
+		return nil, err
+	}
+	return user, nil
+}
+
+// EnrollTOTPForLogin enrolls the TOTP of a user whose role requires it and who logs in without it
+func (s *Service) EnrollTOTPForLogin(ctx context.Context, challengeToken string) (*TOTPEnrollment, *types.Error) {
+	user, err := s.challengeUser(ctx, challengeToken)
+	if err != nil {
+		err.Path = ".UserService->EnrollTOTPForLogin()" + err.Path
+		return nil, err
+	}
+
+	enrollment, err := s.enrollTOTP(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->EnrollTOTPForLogin()" + err.Path
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// CompleteLogin completes a challenged login with the second factor and opens the session.
+// A wrong code counts as a failed login, the challenge can be retried until the account is locked out.
+func (s *Service) CompleteLogin(ctx context.Context, params *CompleteLoginParams, client *ClientInfo) (*LoginResponse, *types.Error) {
+	if client == nil {
+		client = &ClientInfo{}
+	}
+
+	user, err := s.challengeUser(ctx, params.ChallengeToken)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+
+	err = s.checkLoginGuard(ctx, user.Email, client)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+
+	userTOTP, err := s.findTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+	if userTOTP == nil {
+		return nil, &types.Error{
+			Path:    ".UserService->CompleteLogin()",
+			Message: ErrTOTPNotEnrolled.Error(),
+			Error:   ErrTOTPNotEnrolled,
+			Type:    "validation-error",
+		}
+	}
+
+	var recoveryCodes []string
+	if userTOTP.EnabledAt != nil {
+		err = s.verifySecondFactor(ctx, userTOTP, &SecondFactorParams{
+			Code:         params.Code,
+			RecoveryCode: params.RecoveryCode,
+		})
+	} else {
+		recoveryCodes, err = s.activateTOTP(ctx, userTOTP, params.Code)
+	}
+	if err != nil {
+		if err.Error == ErrTOTPInvalid {
+			err = s.loginFailed(ctx, user.Email, client, ErrTOTPInvalid)
+		}
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+
+	_, err = s.useUserToken(ctx, params.ChallengeToken, TokenLoginChallenge)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+
+	err = s.loginSucceeded(ctx, user.Email)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+
+	token, session, err := s.createSession(ctx, user.ID, client)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+
+	response, err := s.loginResponse(user, session, token)
+	if err != nil {
+		err.Path = ".UserService->CompleteLogin()" + err.Path
+		return nil, err
+	}
+	response.RecoveryCodes = recoveryCodes
+	return response, nil
+}
+
+// GetTOTPStatus returns the two-factor authentication state of the user
+func (s *Service) GetTOTPStatus(ctx context.Context, userID int) (*TOTPStatus, *types.Error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->GetTOTPStatus()" + err.Path
+		return nil, err
+	}
+
+	userTOTP, err := s.findTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->GetTOTPStatus()" + err.Path
+		return nil, err
+	}
+
+	status := &TOTPStatus{
+		Required: s.totpRequiredRoles[user.Role],
+	}
+	if userTOTP == nil || userTOTP.EnabledAt == nil {
+		return status, nil
+	}
+
+	recoveryCodes, err := s.recoveryCodeStorage.FindAllRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->GetTOTPStatus()" + err.Path
+		return nil, err
+	}
+
+	status.Enabled = true
+	status.EnabledAt = userTOTP.EnabledAt
+	for _, recoveryCode := range recoveryCodes {
+		if recoveryCode.UsedAt == nil {
+			status.RecoveryCodesLeft++
+		}
+	}
+	return status, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user, it is enabled by ActivateTOTP
+func (s *Service) EnrollTOTP(ctx context.Context, userID int) (*TOTPEnrollment, *types.Error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->EnrollTOTP()" + err.Path
+		return nil, err
+	}
+
+	enrollment, err := s.enrollTOTP(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->EnrollTOTP()" + err.Path
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// enrollTOTP replaces the pending secret of the user, an enabled secret is only replaced once disabled
+func (s *Service) enrollTOTP(ctx context.Context, user *User) (*TOTPEnrollment, *types.Error) {
+	userTOTP, err := s.findTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->enrollTOTP()" + err.Path
+		return nil, err
+	}
+	if userTOTP != nil && userTOTP.EnabledAt != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->enrollTOTP()",
+			Message: ErrTOTPAlreadyEnabled.Error(),
+			Error:   ErrTOTPAlreadyEnabled,
+			Type:    "validation-error",
+		}
+	}
+
+	secret, errSecret := totp.GenerateSecret()
+	if errSecret != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->enrollTOTP()",
+			Message: errSecret.Error(),
+			Error:   errSecret,
+			Type:    "golang-error",
+		}
+	}
+
+	now := time.Now()
+	if userTOTP == nil {
+		_, err = s.totpStorage.InsertTOTP(ctx, &UserTOTP{
+			UserID:    user.ID,
+			Secret:    secret,
+			CreatedAt: now,
+			UpdatedAt: &now,
+		})
+	} else {
+		userTOTP.Secret = secret
+		userTOTP.LastStep = 0
+		userTOTP.UpdatedAt = &now
+		_, err = s.totpStorage.UpdateTOTP(ctx, userTOTP)
+	}
+	if err != nil {
+		err.Path = ".UserService->enrollTOTP()" + err.Path
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ActivateTOTP enables the enrolled TOTP once the user entered a first code and returns the recovery codes,
+// they are shown only once
+func (s *Service) ActivateTOTP(ctx context.Context, userID int, code string) ([]string, *types.Error) {
+	userTOTP, err := s.findTOTP(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->ActivateTOTP()" + err.Path
+		return nil, err
+	}
+	if userTOTP == nil {
+		return nil, &types.Error{
+			Path:    ".UserService->ActivateTOTP()",
+			Message: ErrTOTPNotEnrolled.Error(),
+			Error:   ErrTOTPNotEnrolled,
+			Type:    "validation-error",
+		}
+	}
+	if userTOTP.EnabledAt != nil {
+		return nil, &types.Error{
+			Path:    ".UserService->ActivateTOTP()",
+			Message: ErrTOTPAlreadyEnabled.Error(),
+			Error:   ErrTOTPAlreadyEnabled,
+			Type:    "validation-error",
+		}
+	}
+
+	recoveryCodes, err := s.activateTOTP(ctx, userTOTP, code)
+	if err != nil {
+		err.Path = ".UserService->ActivateTOTP()" + err.Path
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+func (s *Service) activateTOTP(ctx context.Context, userTOTP *UserTOTP, code string) ([]string, *types.Error) {
+	err := s.verifyTOTPCode(ctx, userTOTP, code)
+	if err != nil {
+		err.Path = ".UserService->activateTOTP()" + err.Path
+		return nil, err
+	}
+
+	now := time.Now()
+	userTOTP.EnabledAt = &now
+	userTOTP.UpdatedAt = &now
+	_, err = s.totpStorage.UpdateTOTP(ctx, userTOTP)
+	if err != nil {
+		err.Path = ".UserService->activateTOTP()" + err.Path
+		return nil, err
+	}
+
+	recoveryCodes, err := s.generateRecoveryCodes(ctx, userTOTP.UserID)
+	if err != nil {
+		err.Path = ".UserService->activateTOTP()" + err.Path
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// DisableTOTP disables the TOTP of the user after checking a code, unless the role of the user requires it
+func (s *Service) DisableTOTP(ctx context.Context, userID int, params *SecondFactorParams) *types.Error {
+	user, userTOTP, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->DisableTOTP()" + err.Path
+		return err
+	}
+	if s.totpRequiredRoles[user.Role] {
+		return &types.Error{
+			Path:    ".UserService->DisableTOTP()",
+			Message: ErrTOTPRequired.Error(),
+			Error:   ErrTOTPRequired,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.checkSecondFactor(ctx, user, userTOTP, params)
+	if err != nil {
+		err.Path = ".UserService->DisableTOTP()" + err.Path
+		return err
+	}
+
+	err = s.removeTOTP(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->DisableTOTP()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after checking a code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int, params *SecondFactorParams) ([]string, *types.Error) {
+	user, userTOTP, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	err = s.checkSecondFactor(ctx, user, userTOTP, params)
+	if err != nil {
+		err.Path = ".UserService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	recoveryCodes, err := s.generateRecoveryCodes(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// ResetTOTP removes the TOTP of a user who lost the authenticator and the recovery codes,
+// the user enrolls again on the next login when the role requires it
+func (s *Service) ResetTOTP(ctx context.Context, userID int) *types.Error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->ResetTOTP()" + err.Path
+		return err
+	}
+
+	err = s.removeTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->ResetTOTP()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// enabledTOTP returns the user with its enabled TOTP
+func (s *Service) enabledTOTP(ctx context.Context, userID int) (*User, *UserTOTP, *types.Error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->enabledTOTP()" + err.Path
+		return nil, nil, err
+	}
+
+	userTOTP, err := s.findTOTP(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->enabledTOTP()" + err.Path
+		return nil, nil, err
+	}
+	if userTOTP == nil || userTOTP.EnabledAt == nil {
+		return nil, nil, &types.Error{
+			Path:    ".UserService->enabledTOTP()",
+			Message: ErrTOTPNotEnabled.Error(),
+			Error:   ErrTOTPNotEnabled,
+			Type:    "validation-error",
+		}
+	}
+	return user, userTOTP, nil
+}
+
+// checkSecondFactor verifies the second factor of a logged-in user,
+// wrong codes count against the account like failed logins so they can not be guessed with a stolen session
+func (s *Service) checkSecondFactor(ctx context.Context, user *User, userTOTP *UserTOTP, params *SecondFactorParams) *types.Error {
+	client := &ClientInfo{}
+	err := s.checkLoginGuard(ctx, user.Email, client)
+	if err != nil {
+		err.Path = ".UserService->checkSecondFactor()" + err.Path
+		return err
+	}
+
+	err = s.verifySecondFactor(ctx, userTOTP, params)
+	if err != nil {
+		if err.Error == ErrTOTPInvalid {
+			err = s.loginFailed(ctx, user.Email, client, ErrTOTPInvalid)
+		}
+		err.Path = ".UserService->checkSecondFactor()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// verifySecondFactor verifies the TOTP code, or the recovery code when no code is given
+func (s *Service) verifySecondFactor(ctx context.Context, userTOTP *UserTOTP, params *SecondFactorParams) *types.Error {
+	if params.Code == "" && params.RecoveryCode != "" {
+		err := s.useRecoveryCode(ctx, userTOTP.UserID, params.RecoveryCode)
+		if err != nil {
+			err.Path = ".UserService->verifySecondFactor()" + err.Path
+			return err
+		}
+		return nil
+	}
+
+	err := s.verifyTOTPCode(ctx, userTOTP, params.Code)
+	if err != nil {
+		err.Path = ".UserService->verifySecondFactor()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// verifyTOTPCode checks the code and remembers its period so the same code can not be used twice
+func (s *Service) verifyTOTPCode(ctx context.Context, userTOTP *UserTOTP, code string) *types.Error {
+	step, ok := totp.Validate(userTOTP.Secret, code, time.Now())
+	if !ok || step <= userTOTP.LastStep {
+		return &types.Error{
+			Path:    ".UserService->verifyTOTPCode()",
+			Message: ErrTOTPInvalid.Error(),
+			Error:   ErrTOTPInvalid,
+			Type:    "validation-error",
+		}
+	}
+
+	now := time.Now()
+	userTOTP.LastStep = step
+	userTOTP.UpdatedAt = &now
+	_, err := s.totpStorage.UpdateTOTP(ctx, userTOTP)
+	if err != nil {
+		err.Path = ".UserService->verifyTOTPCode()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// useRecoveryCode marks the unused recovery code of the user as used,
+// the storage checks and marks it at once so a code entered twice concurrently is accepted only once
+func (s *Service) useRecoveryCode(ctx context.Context, userID int, code string) *types.Error {
+	_, err := s.recoveryCodeStorage.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return &types.Error{
+				Path:    ".UserService->useRecoveryCode()",
+				Message: ErrTOTPInvalid.Error(),
+				Error:   ErrTOTPInvalid,
+				Type:    "validation-error",
+			}
+		}
+		err.Path = ".UserService->useRecoveryCode()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// generateRecoveryCodes replaces the recovery codes of the user, the codes are returned once
+func (s *Service) generateRecoveryCodes(ctx context.Context, userID int) ([]string, *types.Error) {
+	err := s.deleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->generateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	now := time.Now()
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, errCode := generateRecoveryCode()
+		if errCode != nil {
+			return nil, &types.Error{
+				Path:    ".UserService->generateRecoveryCodes()",
+				Message: errCode.Error(),
+				Error:   errCode,
+				Type:    "golang-error",
+			}
+		}
+
+		_, err = s.recoveryCodeStorage.InsertRecoveryCode(ctx, &RecoveryCode{
+			UserID:    userID,
+			CodeHash:  hashToken(normalizeRecoveryCode(code)),
+			CreatedAt: now,
+			UpdatedAt: &now,
+		})
+		if err != nil {
+			err.Path = ".UserService->generateRecoveryCodes()" + err.Path
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (s *Service) deleteRecoveryCodes(ctx context.Context, userID int) *types.Error {
+	recoveryCodes, err := s.recoveryCodeStorage.FindAllRecoveryCodes(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->deleteRecoveryCodes()" + err.Path
+		return err
+	}
+	for _, recoveryCode := range recoveryCodes {
+		err = s.recoveryCodeStorage.DeleteRecoveryCode(ctx, recoveryCode.ID)
+		if err != nil {
+			err.Path = ".UserService->deleteRecoveryCodes()" + err.Path
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTOTP deletes the TOTP and the recovery codes of the user
+func (s *Service) removeTOTP(ctx context.Context, userID int) *types.Error {
+	userTOTP, err := s.findTOTP(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->removeTOTP()" + err.Path
+		return err
+	}
+	if userTOTP != nil {
+		err = s.totpStorage.DeleteTOTP(ctx, userTOTP.ID)
+		if err != nil {
+			err.Path = ".UserService->removeTOTP()" + err.Path
+			return err
+		}
+	}
+
+	err = s.deleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->removeTOTP()" + err.Path
+		return err
+	}
+	return nil
+}
+
+// generateRecoveryCode returns a code like "k7m2p-x9q4r"
+func generateRecoveryCode() (string, error) {
+	buff := make([]byte, 10)
+	_, err := rand.Read(buff)
+	if err != nil {
+		return "", err
+	}
+
+	code := make([]byte, 0, len(buff)+1)
+	for i, b := range buff {
+		if i == len(buff)/2 {
+			code = append(code, '-')
+		}
+		code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// normalizeRecoveryCode ignores the case, the spaces and the dash of the entered code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
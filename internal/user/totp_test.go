@@ -0,0 +1,58 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/totp"
+)
+
+func TestVerifySecondFactorRefusesReplay(t *testing.T) {
+	secret, errSecret := totp.GenerateSecret()
+	if errSecret != nil {
+		t.Fatal(errSecret)
+	}
+	enabledAt := time.Now().Add(-time.Hour)
+	userTOTP := &UserTOTP{ID: 1, UserID: 7, Secret: secret, EnabledAt: &enabledAt}
+	recoveryCodes := &memRecoveryCodeStorage{recoveryCodes: []*RecoveryCode{
+		{ID: 1, UserID: 7, CodeHash: hashToken(normalizeRecoveryCode("k7m2p-x9q4r"))},
+	}}
+	service := NewService(&memUserStorage{}, &memSessionStorage{}, nil, nil,
+		&memTOTPStorage{totps: []*UserTOTP{userTOTP}}, recoveryCodes, nil, "https://api.example.com")
+
+	step := totp.Step(time.Now())
+	code, errCode := totp.Code(secret, step)
+	if errCode != nil {
+		t.Fatal(errCode)
+	}
+	previousCode, errCode := totp.Code(secret, step-1)
+	if errCode != nil {
+		t.Fatal(errCode)
+	}
+
+	tests := []struct {
+		name    string
+		params  *SecondFactorParams
+		wantErr error
+	}{
+		{"code", &SecondFactorParams{Code: code}, nil},
+		{"same code again", &SecondFactorParams{Code: code}, ErrTOTPInvalid},
+		{"code of the previous period", &SecondFactorParams{Code: previousCode}, ErrTOTPInvalid},
+		{"recovery code", &SecondFactorParams{RecoveryCode: "K7M2P X9Q4R"}, nil},
+		{"same recovery code again", &SecondFactorParams{RecoveryCode: "k7m2p-x9q4r"}, ErrTOTPInvalid},
+		{"unknown recovery code", &SecondFactorParams{RecoveryCode: "aaaaa-bbbbb"}, ErrTOTPInvalid},
+	}
+	for _, tt := range tests {
+		err := service.verifySecondFactor(context.Background(), userTOTP, tt.params)
+		if tt.wantErr == nil && err != nil {
+			t.Errorf("%s: verifySecondFactor() error = %v, want nil", tt.name, err.Error)
+		}
+		if tt.wantErr != nil && (err == nil || err.Error != tt.wantErr) {
+			t.Errorf("%s: verifySecondFactor() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if userTOTP.LastStep != step {
+		t.Errorf("LastStep = %d, want the period of the accepted code %d", userTOTP.LastStep, step)
+	}
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"context"
+
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+// ListDeletedUsers is listing soft deleted users
+func (s *Service) ListDeletedUsers(ctx context.Context, params *FindAllUsersParams) ([]*User, int, *types.Error) {
+	params.Deleted = true
+
+	users, err := s.userStorage.FindAll(ctx, params)
+	if err != nil {
+		err.Path = ".UserService->ListDeletedUsers()" + err.Path
+		return nil, 0, err
+	}
+	count, err := s.userStorage.Count(ctx, params)
+	if err != nil {
+		err.Path = ".UserService->ListDeletedUsers()" + err.Path
+		return nil, 0, err
+	}
+
+	return users, count, nil
+}
+
+// RestoreUser restores a soft deleted user.
+// It fails when a live user took the email in the meantime.
+func (s *Service) RestoreUser(ctx context.Context, userID int) (*User, *types.Error) {
+	user, err := s.userStorage.FindDeletedByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->RestoreUser()" + err.Path
+		return nil, err
+	}
+
+	users, _, err := s.ListUsers(ctx, &FindAllUsersParams{
+		Email: user.Email,
+	})
+	if err != nil {
+		err.Path = ".UserService->RestoreUser()" + err.Path
+		return nil, err
+	}
+	if len(users) > 0 {
+		return nil, &types.Error{
+			Path:    ".UserService->RestoreUser()",
+			Message: ErrEmailAlreadyExists.Error(),
+			Error:   ErrEmailAlreadyExists,
+			Type:    "validation-error",
+		}
+	}
+
+	err = s.userStorage.Restore(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->RestoreUser()" + err.Path
+		return nil, err
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// PurgeUser permanently deletes a soft deleted user.
+// Users who placed orders can not be purged.
+func (s *Service) PurgeUser(ctx context.Context, userID int) *types.Error {
+	_, err := s.userStorage.FindDeletedByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->PurgeUser()" + err.Path
+		return err
+	}
+
+	err = s.userStorage.DeleteHard(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->PurgeUser()" + err.Path
+		return err
+	}
+
+	return nil
+}
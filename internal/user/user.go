@@ -3,11 +3,16 @@ package user
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/loginguard"
+	"github.com/riskiramdan/evermos/internal/mailer"
+	"github.com/riskiramdan/evermos/internal/oidc"
 	"github.com/riskiramdan/evermos/internal/types"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -15,7 +20,6 @@ import (
 // Errors
 var (
 	ErrWrongPassword      = errors.New("wrong password")
-	ErrWrongEmail         = errors.New("wrong email")
 	ErrEmailAlreadyExists = errors.New("Email Already Exists")
 	ErrNotFound           = errors.New("not found")
 	ErrNoInput            = errors.New("no input")
@@ -23,87 +27,143 @@ var (
 	ErrNameAlreadyExist   = errors.New(("Name Already Exits"))
 )
 
-// User user
+// User user. The personal fields are redacted in the audit log, so anonymizing the row removes them.
 type User struct {
-	ID             int        `json:"id" db:"id"`
-	Name           string     `json:"name" db:"name"`
-	Email          string     `json:"email" db:"email"`
-	Password       string     `json:"password" db:"password"`
-	Token          *string    `json:"token" db:"token"`
-	TokenExpiredAt *time.Time `json:"tokenExpiredAt" db:"tokenExpiredAt"`
-	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt      *time.Time `json:"updatedAt" db:"updated_at"`
+	ID           int        `json:"id" db:"id"`
+	Name         string     `json:"name" db:"name" audit:"-"`
+	Email        string     `json:"email" db:"email" audit:"-"`
+	Role         string     `json:"role" db:"role"`
+	Password     string     `json:"-" db:"password" audit:"-"`
+	VerifiedAt   *time.Time `json:"verifiedAt" db:"verified_at"`
+	AnonymizedAt *time.Time `json:"anonymizedAt,omitempty" db:"anonymized_at"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	CreatedBy    *string    `json:"createdBy" db:"created_by"`
+	UpdatedAt    *time.Time `json:"updatedAt" db:"updated_at"`
+	UpdatedBy    *string    `json:"updatedBy" db:"updated_by"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	DeletedBy    *string    `json:"deletedBy,omitempty" db:"deleted_by"`
 }
 
 //FindAllUsersParams params for find all
 type FindAllUsersParams struct {
-	UserID int    `json:"userId"`
-	Page   int    `json:"page"`
-	Search string `json:"search"`
-	Limit  int    `json:"limit"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
-	Token  string `json:"token"`
+	UserID        int        `json:"userId"`
+	Page          int        `json:"page"`
+	Search        string     `json:"search"`
+	Limit         int        `json:"limit"`
+	Email         string     `json:"email"`
+	Name          string     `json:"name"`
+	Cursor        string     `json:"cursor"`
+	EstimateCount bool       `json:"estimateCount"`
+	Deleted       bool       `json:"deleted"`
+	DeletedBefore *time.Time `json:"deletedBefore"`
 }
 
-// CreateUserParams represent the http request data for create user
+// CreateUserParams represent the http request data for create user.
+// The role defaults to customer.
 // swagger:model
 type CreateUserParams struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name     string `json:"name" validate:"required,max=80"`
+	Email    string `json:"email" validate:"required,useremail"`
+	Password string `json:"password" validate:"required,password"`
+	Role     string `json:"role"`
 }
 
-// UpdateUserParams represent the http request data for update user
+// UpdateUserParams represent the http request data for update user.
+// The role is kept when empty.
 // swagger:model
 type UpdateUserParams struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name  string `json:"name" validate:"required,max=80"`
+	Email string `json:"email" validate:"required,useremail"`
+	Role  string `json:"role"`
 }
 
 // LoginParams represent the http request data for login user
 // swagger:model
 type LoginParams struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,max=80"`
+	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse represents the response of login function
+// LoginResponse represents the response of login function.
+// It holds a session id, or an access and a refresh token when access tokens are enabled.
 // swagger:model
 type LoginResponse struct {
-	SessionID string `json:"sessionId"`
-	User      *User  `json:"user"`
+	SessionID            string     `json:"sessionId,omitempty"`
+	SessionExpiredAt     *time.Time `json:"sessionExpiredAt,omitempty"`
+	AccessToken          string     `json:"accessToken,omitempty"`
+	AccessTokenExpiredAt *time.Time `json:"accessTokenExpiredAt,omitempty"`
+	RefreshToken         string     `json:"refreshToken,omitempty"`
+	User                 *User      `json:"user"`
+	// SecondFactor is set instead of the session when the login has to be completed with a second factor
+	SecondFactor       string     `json:"secondFactor,omitempty"`
+	ChallengeToken     string     `json:"challengeToken,omitempty"`
+	ChallengeExpiredAt *time.Time `json:"challengeExpiredAt,omitempty"`
+	// RecoveryCodes are returned once, when the second factor was enrolled during the login
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
 }
 
 // ChangePasswordParams represent the http request data for change password
 // swagger:model
 type ChangePasswordParams struct {
-	OldPassword string `json:"oldPassword"`
-	NewPassword string `json:"newPassword"`
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,password"`
 }
 
 // Storage represents the user storage interface
 type Storage interface {
 	FindAll(ctx context.Context, params *FindAllUsersParams) ([]*User, *types.Error)
+	FindAllByCursor(ctx context.Context, params *FindAllUsersParams) ([]*User, *data.CursorPage, *types.Error)
+	Count(ctx context.Context, params *FindAllUsersParams) (int, *types.Error)
 	FindByID(ctx context.Context, userID int) (*User, *types.Error)
 	FindByEmail(ctx context.Context, email string) (*User, *types.Error)
-	FindByToken(ctx context.Context, token string) (*User, *types.Error)
 	Insert(ctx context.Context, user *User) (*User, *types.Error)
 	Update(ctx context.Context, user *User) (*User, *types.Error)
 	Delete(ctx context.Context, userID int) *types.Error
+	FindDeletedByID(ctx context.Context, userID int) (*User, *types.Error)
+	Restore(ctx context.Context, userID int) *types.Error
+	DeleteHard(ctx context.Context, userID int) *types.Error
 }
 
 // ServiceInterface represents the user service interface
 type ServiceInterface interface {
 	ListUsers(ctx context.Context, params *FindAllUsersParams) ([]*User, int, *types.Error)
+	ListUsersByCursor(ctx context.Context, params *FindAllUsersParams) ([]*User, *data.CursorPage, *types.Error)
 	GetUser(ctx context.Context, userID int) (*User, *types.Error)
 	CreateUser(ctx context.Context, params *CreateUserParams) (*User, *types.Error)
 	UpdateUser(ctx context.Context, userID int, params *UpdateUserParams) (*User, *types.Error)
+	UpdateProfile(ctx context.Context, userID int, params *UpdateProfileParams) (*User, *types.Error)
 	DeleteUser(ctx context.Context, userID int) *types.Error
+	AnonymizeUser(ctx context.Context, userID int) *types.Error
+	ExportUser(ctx context.Context, userID int) (*Export, *types.Error)
+	ListDeletedUsers(ctx context.Context, params *FindAllUsersParams) ([]*User, int, *types.Error)
+	RestoreUser(ctx context.Context, userID int) (*User, *types.Error)
+	PurgeUser(ctx context.Context, userID int) *types.Error
 	ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) *types.Error
-	Login(ctx context.Context, email string, password string) (*LoginResponse, *types.Error)
-	Logout(ctx context.Context, token string) *types.Error
-	GetByToken(ctx context.Context, token string) (*User, *types.Error)
+	Register(ctx context.Context, params *RegisterParams) (*User, *types.Error)
+	VerifyEmail(ctx context.Context, token string) (*User, *types.Error)
+	ResendVerification(ctx context.Context, email string) *types.Error
+	RequestResetPassword(ctx context.Context, email string) *types.Error
+	ResetPassword(ctx context.Context, params *ResetPasswordParams) *types.Error
+	Login(ctx context.Context, email string, password string, client *ClientInfo) (*LoginResponse, *types.Error)
+	Logout(ctx context.Context, sessionID int) *types.Error
+	RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, *types.Error)
+	LogoutAll(ctx context.Context, userID int) *types.Error
+	GetSession(ctx context.Context, token string) (*Session, *types.Error)
+	TouchSession(ctx context.Context, session *Session) *types.Error
+	ListSessions(ctx context.Context, userID int) ([]*Session, *types.Error)
+	RevokeSession(ctx context.Context, userID int, sessionID int) *types.Error
+	UnlockUser(ctx context.Context, userID int) *types.Error
+	GetTOTPStatus(ctx context.Context, userID int) (*TOTPStatus, *types.Error)
+	EnrollTOTP(ctx context.Context, userID int) (*TOTPEnrollment, *types.Error)
+	ActivateTOTP(ctx context.Context, userID int, code string) ([]string, *types.Error)
+	DisableTOTP(ctx context.Context, userID int, params *SecondFactorParams) *types.Error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, params *SecondFactorParams) ([]string, *types.Error)
+	ResetTOTP(ctx context.Context, userID int) *types.Error
+	EnrollTOTPForLogin(ctx context.Context, challengeToken string) (*TOTPEnrollment, *types.Error)
+	CompleteLogin(ctx context.Context, params *CompleteLoginParams, client *ClientInfo) (*LoginResponse, *types.Error)
+	StartOIDCLogin(ctx context.Context) (*OIDCLogin, *types.Error)
+	CompleteOIDCLogin(ctx context.Context, params *OIDCCallbackParams, client *ClientInfo) (*LoginResponse, *types.Error)
+	DeleteExpiredTokens(ctx context.Context, now time.Time) (int, *types.Error)
 }
 
 func generateToken() (string, error) {
@@ -116,9 +176,33 @@ func generateToken() (string, error) {
 	return token, nil
 }
 
+// hashToken returns the SHA-256 hash of the token, only the hash is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // Service is the domain logic implementation of user Service interface
 type Service struct {
-	userStorage Storage
+	userStorage             Storage
+	sessionStorage          SessionStorage
+	refreshHistoryStorage   RefreshTokenHistoryStorage
+	userTokenStorage        UserTokenStorage
+	totpStorage             TOTPStorage
+	recoveryCodeStorage     RecoveryCodeStorage
+	mailer                  mailer.Mailer
+	appURL                  string
+	resetPasswordURL        string
+	accessTokens            AccessTokenIssuer
+	refreshLifetime         time.Duration
+	sessionAbsoluteLifetime time.Duration
+	sessionIdleTimeout      time.Duration
+	loginGuard              *loginguard.Guard
+	totpIssuer              string
+	totpRequiredRoles       map[string]bool
+	oidcProvider            *oidc.Provider
+	identityStorage         IdentityStorage
+	oidcStateStorage        OIDCStateStorage
 }
 
 // ListUsers is listing users
@@ -128,15 +212,24 @@ func (s *Service) ListUsers(ctx context.Context, params *FindAllUsersParams) ([]
 		err.Path = ".UserService->ListUsers()" + err.Path
 		return nil, 0, err
 	}
-	params.Page = 0
-	params.Limit = 0
-	allUsers, err := s.userStorage.FindAll(ctx, params)
+	count, err := s.userStorage.Count(ctx, params)
 	if err != nil {
 		err.Path = ".UserService->ListUsers()" + err.Path
 		return nil, 0, err
 	}
 
-	return users, len(allUsers), nil
+	return users, count, nil
+}
+
+// ListUsersByCursor is listing users paginated by cursor
+func (s *Service) ListUsersByCursor(ctx context.Context, params *FindAllUsersParams) ([]*User, *data.CursorPage, *types.Error) {
+	users, cursors, err := s.userStorage.FindAllByCursor(ctx, params)
+	if err != nil {
+		err.Path = ".UserService->ListUsersByCursor()" + err.Path
+		return nil, nil, err
+	}
+
+	return users, cursors, nil
 }
 
 // GetUser is get user
@@ -150,28 +243,47 @@ func (s *Service) GetUser(ctx context.Context, userID int) (*User, *types.Error)
 	return user, nil
 }
 
-// CreateUser create user
+// CreateUser create user, users created by another user are verified already
 func (s *Service) CreateUser(ctx context.Context, params *CreateUserParams) (*User, *types.Error) {
+	user, err := s.createUser(ctx, params, true)
+	if err != nil {
+		err.Path = ".UserService->CreateUser()" + err.Path
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) createUser(ctx context.Context, params *CreateUserParams, verified bool) (*User, *types.Error) {
 	users, _, errType := s.ListUsers(ctx, &FindAllUsersParams{
 		Email: params.Email,
 	})
 	if errType != nil {
-		errType.Path = ".UserService->CreateUser()" + errType.Path
+		errType.Path = ".UserService->createUser()" + errType.Path
 		return nil, errType
 	}
 	if len(users) > 0 {
 		return nil, &types.Error{
-			Path:    ".UserService->CreateUser()",
+			Path:    ".UserService->createUser()",
 			Message: ErrEmailAlreadyExists.Error(),
 			Error:   ErrEmailAlreadyExists,
 			Type:    "validation-error",
 		}
 	}
 
+	role := params.Role
+	if role == "" {
+		role = RoleCustomer
+	}
+	errType = validateRole(".UserService->createUser()", role)
+	if errType != nil {
+		return nil, errType
+	}
+
 	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, &types.Error{
-			Path:    ".UserService->CreateUser()",
+			Path:    ".UserService->createUser()",
 			Message: err.Error(),
 			Error:   err,
 			Type:    "golang-error",
@@ -181,18 +293,20 @@ func (s *Service) CreateUser(ctx context.Context, params *CreateUserParams) (*Us
 	now := time.Now()
 
 	user := &User{
-		Name:           params.Name,
-		Email:          params.Email,
-		Password:       string(bcryptHash),
-		Token:          nil,
-		TokenExpiredAt: nil,
-		CreatedAt:      now,
-		UpdatedAt:      &now,
+		Name:      params.Name,
+		Email:     params.Email,
+		Role:      role,
+		Password:  string(bcryptHash),
+		CreatedAt: now,
+		UpdatedAt: &now,
+	}
+	if verified {
+		user.VerifiedAt = &now
 	}
 
 	user, errType = s.userStorage.Insert(ctx, user)
 	if errType != nil {
-		errType.Path = ".UserService->CreateUser()" + errType.Path
+		errType.Path = ".UserService->createUser()" + errType.Path
 		return nil, errType
 	}
 
@@ -207,22 +321,28 @@ func (s *Service) UpdateUser(ctx context.Context, userID int, params *UpdateUser
 		return nil, err
 	}
 
-	users, _, err := s.ListUsers(ctx, &FindAllUsersParams{
-		Email: params.Email,
-	})
+	err = s.checkEmailAvailable(ctx, params.Email, user.ID)
 	if err != nil {
 		err.Path = ".UserService->UpdateUser()" + err.Path
 		return nil, err
 	}
-	if len(users) > 0 {
-		return nil, &types.Error{
-			Path:    ".UserService->CreateUser()",
-			Message: data.ErrAlreadyExist.Error(),
-			Error:   data.ErrAlreadyExist,
-			Type:    "validation-error",
+	if params.Email != user.Email {
+		// a pending email change would undo the new email
+		err = s.revokeUserTokens(ctx, user.ID, TokenChangeEmail)
+		if err != nil {
+			err.Path = ".UserService->UpdateUser()" + err.Path
+			return nil, err
 		}
 	}
 
+	if params.Role != "" {
+		err = validateRole(".UserService->UpdateUser()", params.Role)
+		if err != nil {
+			return nil, err
+		}
+		user.Role = params.Role
+	}
+
 	user.Name = params.Name
 	user.Email = params.Email
 
@@ -243,6 +363,12 @@ func (s *Service) DeleteUser(ctx context.Context, userID int) *types.Error {
 		return err
 	}
 
+	err = s.LogoutAll(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->DeleteUser()" + err.Path
+		return err
+	}
+
 	return nil
 }
 
@@ -284,99 +410,122 @@ func (s *Service) ChangePassword(ctx context.Context, userID int, oldPassword, n
 	return nil
 }
 
-// Login login
-func (s *Service) Login(ctx context.Context, email string, password string) (*LoginResponse, *types.Error) {
-	users, err := s.userStorage.FindAll(ctx, &FindAllUsersParams{
-		Email: email,
-	})
+// Login login, every login opens a new session so the user can be logged in on several devices.
+// A wrong email and a wrong password fail alike.
+func (s *Service) Login(ctx context.Context, email string, password string, client *ClientInfo) (*LoginResponse, *types.Error) {
+	if client == nil {
+		client = &ClientInfo{}
+	}
+
+	err := s.checkLoginGuard(ctx, email, client)
 	if err != nil {
 		err.Path = ".UserService->Login()" + err.Path
 		return nil, err
 	}
-	if len(users) < 1 {
-		return nil, &types.Error{
-			Path:    ".UserService->Login()",
-			Message: ErrWrongEmail.Error(),
-			Error:   ErrWrongEmail,
-			Type:    "validation-error",
+
+	user, err := s.userStorage.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error != data.ErrNotFound {
+			err.Path = ".UserService->Login()" + err.Path
+			return nil, err
 		}
+		user = nil
 	}
 
-	user := users[0]
-	errBcrypt := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if errBcrypt != nil {
-		return nil, &types.Error{
-			Path:    ".UserService->ChangePassword()",
-			Message: ErrWrongPassword.Error(),
-			Error:   ErrWrongPassword,
-			Type:    "golang-error",
-		}
+	if comparePassword(user, password) != nil {
+		err = s.loginFailed(ctx, email, client, ErrInvalidCredentials)
+		err.Path = ".UserService->Login()" + err.Path
+		return nil, err
 	}
 
-	token, errToken := generateToken()
-	if errToken != nil {
+	if user.VerifiedAt == nil {
 		return nil, &types.Error{
-			Path:    ".UserService->CreateUser()",
-			Message: errToken.Error(),
-			Error:   errToken,
-			Type:    "golang-error",
+			Path:    ".UserService->Login()",
+			Message: ErrEmailNotVerified.Error(),
+			Error:   ErrEmailNotVerified,
+			Type:    "validation-error",
 		}
 	}
 
-	now := time.Now()
-	tokenExpiredAt := now.Add(72 * time.Hour)
-
-	user.Token = &token
-	user.TokenExpiredAt = &tokenExpiredAt
-	user.UpdatedAt = &now
-
-	user, err = s.userStorage.Update(ctx, user)
+	response, err := s.openSession(ctx, user, client)
 	if err != nil {
-		err.Path = ".UserService->CreateUser()" + err.Path
+		err.Path = ".UserService->Login()" + err.Path
 		return nil, err
 	}
-
-	return &LoginResponse{
-		SessionID: token,
-		User:      user,
-	}, nil
+	return response, nil
 }
 
-// Logout logout
-func (s *Service) Logout(ctx context.Context, token string) *types.Error {
-	user, err := s.userStorage.FindByToken(ctx, token)
+// openSession opens the session of an authenticated user, or challenges the login for the second factor
+func (s *Service) openSession(ctx context.Context, user *User, client *ClientInfo) (*LoginResponse, *types.Error) {
+	// the failed logins are kept until the second factor is passed as well
+	kind, err := s.secondFactor(ctx, user)
 	if err != nil {
-		err.Path = ".UserService->Logout()" + err.Path
-		return err
+		err.Path = ".UserService->openSession()" + err.Path
+		return nil, err
+	}
+	if kind != "" {
+		response, err := s.loginChallenge(ctx, user, kind)
+		if err != nil {
+			err.Path = ".UserService->openSession()" + err.Path
+			return nil, err
+		}
+		return response, nil
 	}
 
-	user.Token = nil
-	user.TokenExpiredAt = nil
-	user, err = s.userStorage.Update(ctx, user)
+	err = s.loginSucceeded(ctx, user.Email)
 	if err != nil {
-		err.Path = ".UserService->Logout()" + err.Path
-		return err
+		err.Path = ".UserService->openSession()" + err.Path
+		return nil, err
 	}
 
-	return nil
-}
+	token, session, err := s.createSession(ctx, user.ID, client)
+	if err != nil {
+		err.Path = ".UserService->openSession()" + err.Path
+		return nil, err
+	}
 
-// GetByToken get user by its token
-func (s *Service) GetByToken(ctx context.Context, token string) (*User, *types.Error) {
-	user, err := s.userStorage.FindByToken(ctx, token)
+	response, err := s.loginResponse(user, session, token)
 	if err != nil {
-		err.Path = ".UserService->GetByToken()" + err.Path
+		err.Path = ".UserService->openSession()" + err.Path
 		return nil, err
 	}
+	return response, nil
+}
 
-	return user, nil
+// Logout logout of the session
+func (s *Service) Logout(ctx context.Context, sessionID int) *types.Error {
+	err := s.sessionStorage.DeleteSession(ctx, sessionID)
+	if err != nil {
+		err.Path = ".UserService->Logout()" + err.Path
+		return err
+	}
+
+	return nil
 }
 
 // NewService creates a new user AppService
 func NewService(
 	userStorage Storage,
+	sessionStorage SessionStorage,
+	refreshHistoryStorage RefreshTokenHistoryStorage,
+	userTokenStorage UserTokenStorage,
+	totpStorage TOTPStorage,
+	recoveryCodeStorage RecoveryCodeStorage,
+	mailer mailer.Mailer,
+	appURL string,
 ) *Service {
 	return &Service{
-		userStorage: userStorage,
+		userStorage:             userStorage,
+		sessionStorage:          sessionStorage,
+		refreshHistoryStorage:   refreshHistoryStorage,
+		userTokenStorage:        userTokenStorage,
+		totpStorage:             totpStorage,
+		recoveryCodeStorage:     recoveryCodeStorage,
+		mailer:                  mailer,
+		appURL:                  appURL,
+		resetPasswordURL:        appURL + "/v1/resetPassword",
+		totpIssuer:              "evermos",
+		totpRequiredRoles:       map[string]bool{},
+		sessionAbsoluteLifetime: defaultSessionLifetime,
 	}
 }
@@ -0,0 +1,174 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/mailer"
+	"github.com/riskiramdan/evermos/internal/types"
+)
+
+const (
+	// verificationLifetime is how long a verification link stays valid
+	verificationLifetime = 24 * time.Hour
+)
+
+// Errors
+var (
+	ErrEmailNotVerified = errors.New("Email is not verified")
+	ErrTooManyRequests  = errors.New("Too many requests, try again later")
+)
+
+// RegisterParams represent the http request data for self-service registration
+// swagger:model
+type RegisterParams struct {
+	Name     string `json:"name" validate:"required,max=80"`
+	Email    string `json:"email" validate:"required,useremail"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+// ResendVerificationParams represent the http request data for resending the verification email
+// swagger:model
+type ResendVerificationParams struct {
+	Email string `json:"email" validate:"required,max=80"`
+}
+
+// Register creates an unverified customer and emails the verification link
+func (s *Service) Register(ctx context.Context, params *RegisterParams) (*User, *types.Error) {
+	if params.Name == "" || params.Email == "" || params.Password == "" {
+		return nil, &types.Error{
+			Path:    ".UserService->Register()",
+			Message: ErrNoInput.Error(),
+			Error:   ErrNoInput,
+			Type:    "validation-error",
+		}
+	}
+
+	user, err := s.createUser(ctx, &CreateUserParams{
+		Name:     params.Name,
+		Email:    params.Email,
+		Password: params.Password,
+		Role:     RoleCustomer,
+	}, false)
+	if err != nil {
+		err.Path = ".UserService->Register()" + err.Path
+		return nil, err
+	}
+
+	err = s.sendVerification(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->Register()" + err.Path
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// sendVerification emails a new verification link to the user
+func (s *Service) sendVerification(ctx context.Context, user *User) *types.Error {
+	token, err := s.issueUserToken(ctx, user, TokenVerifyEmail, user.Email, verificationLifetime)
+	if err != nil {
+		err.Path = ".UserService->sendVerification()" + err.Path
+		return err
+	}
+
+	errSend := s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email, it is valid for %d hours.\n\n%s/v1/verifyEmail?token=%s\n",
+			user.Name, int(verificationLifetime.Hours()), s.appURL, url.QueryEscape(token)),
+	})
+	if errSend != nil {
+		return &types.Error{
+			Path:    ".UserService->sendVerification()",
+			Message: errSend.Error(),
+			Error:   errSend,
+			Type:    "golang-error",
+		}
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email of the user of the token as verified.
+// The token only verifies the email it was sent to, a token of an email change switches the user to it.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*User, *types.Error) {
+	userToken, err := s.useUserToken(ctx, token, TokenVerifyEmail, TokenChangeEmail)
+	if err != nil {
+		err.Path = ".UserService->VerifyEmail()" + err.Path
+		return nil, err
+	}
+
+	user, err := s.tokenUser(ctx, userToken)
+	if err != nil {
+		err.Path = ".UserService->VerifyEmail()" + err.Path
+		return nil, err
+	}
+	if userToken.Purpose == TokenChangeEmail {
+		user, err = s.changeEmail(ctx, user, userToken.Email)
+		if err != nil {
+			err.Path = ".UserService->VerifyEmail()" + err.Path
+			return nil, err
+		}
+		return user, nil
+	}
+	if user.Email != userToken.Email {
+		return nil, &types.Error{
+			Path:    ".UserService->VerifyEmail()",
+			Message: ErrTokenInvalid.Error(),
+			Error:   ErrTokenInvalid,
+			Type:    "validation-error",
+		}
+	}
+	if user.VerifiedAt != nil {
+		return user, nil
+	}
+
+	now := time.Now()
+	user.VerifiedAt = &now
+	user.UpdatedAt = &now
+	user, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->VerifyEmail()" + err.Path
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResendVerification emails a new verification link.
+// Unknown and verified emails and requests over the limit are ignored so the response does not tell which emails are registered.
+func (s *Service) ResendVerification(ctx context.Context, email string) *types.Error {
+	user, err := s.userStorage.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".UserService->ResendVerification()" + err.Path
+		return err
+	}
+	if user.VerifiedAt != nil {
+		return nil
+	}
+
+	err = s.limitUserTokens(ctx, user.ID, TokenVerifyEmail)
+	if err != nil {
+		if err.Error == ErrTooManyRequests {
+			return nil
+		}
+		err.Path = ".UserService->ResendVerification()" + err.Path
+		return err
+	}
+
+	err = s.sendVerification(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->ResendVerification()" + err.Path
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResendVerificationDoesNotTellRegisteredEmails(t *testing.T) {
+	verifiedAt := time.Now()
+	mails := &memMailer{}
+	users := &memUserStorage{users: []*User{
+		{ID: 7, Name: "Jane", Email: "jane@example.com", Role: RoleCustomer},
+		{ID: 8, Name: "John", Email: "john@example.com", Role: RoleCustomer, VerifiedAt: &verifiedAt},
+	}}
+	service := NewService(users, &memSessionStorage{}, nil, &memUserTokenStorage{}, &memTOTPStorage{}, nil, mails, "https://api.example.com")
+
+	tests := []struct {
+		name      string
+		email     string
+		wantMails int
+	}{
+		{"unverified", "jane@example.com", 1},
+		{"unverified again within the interval", "jane@example.com", 1},
+		{"verified", "john@example.com", 1},
+		{"unknown", "nobody@example.com", 1},
+	}
+	for _, tt := range tests {
+		err := service.ResendVerification(context.Background(), tt.email)
+		if err != nil {
+			t.Errorf("%s: ResendVerification() error = %v, want nil", tt.name, err.Error)
+		}
+		if len(mails.messages) != tt.wantMails {
+			t.Errorf("%s: sent %d emails, want %d", tt.name, len(mails.messages), tt.wantMails)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strings"
+	"unicode"
+
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	// minPasswordLength is the minimum length of a password
+	minPasswordLength = 8
+
+	// maxPasswordLength is the length bcrypt stops reading a password at
+	maxPasswordLength = 72
+
+	// maxEmailLength matches the size of the email columns
+	maxEmailLength = 80
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// report the json names of the fields, as the client sent them
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidation("password", password)
+	v.RegisterValidation("useremail", userEmail)
+
+	return v
+}
+
+// password requires a password long enough which mixes letters and digits
+func password(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if len(value) < minPasswordLength || len(value) > maxPasswordLength {
+		return false
+	}
+
+	var letter, digit bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return letter && digit
+}
+
+// userEmail requires a bare email address, without a display name, whose domain has a dot
+func userEmail(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if len(value) > maxEmailLength {
+		return false
+	}
+
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		return false
+	}
+
+	at := strings.LastIndex(value, "@")
+	return strings.Contains(value[at+1:], ".")
+}
+
+// Validate validates the struct against its validate tags, the failing fields are returned
+// as validator.ValidationErrors
+func Validate(params interface{}) error {
+	return validate.Struct(params)
+}
+
+// Message returns the message of the failing field for the client
+func Message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "useremail":
+		return fmt.Sprintf("must be a valid email address of at most %d characters", maxEmailLength)
+	case "password":
+		return fmt.Sprintf("must be %d to %d characters long and contain a letter and a digit", minPasswordLength, maxPasswordLength)
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "required_without":
+		// the param is the go name of the other field, the clients know it by its json name
+		other := fe.Param()
+		return fmt.Sprintf("is required when %s is empty", strings.ToLower(other[:1])+other[1:])
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fe.Param())
+	}
+	return fmt.Sprintf("failed on %s", fe.Tag())
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/riskiramdan/evermos/config"
+	"github.com/riskiramdan/evermos/internal/data"
+	"github.com/riskiramdan/evermos/internal/user"
 )
 
 // SeedUp seeding the database
@@ -21,19 +23,27 @@ func SeedUp() error {
 	defer db.Close()
 
 	_, err = db.Exec(`
-	insert into "user" ("name", "email", "password", "created_at", "updated_at") values
-	('Admin evermos', 'admin', '$2a$10$CCWkaZ1UedUQeGtABKwEqepsNTBR1Rp.b4UlFCuvtkbGmfX3rk4SC', now(), now()),
-	('author', 'author@evermos.com', '$2a$10$CCWkaZ1UedUQeGtABKwEqepsNTBR1Rp.b4UlFCuvtkbGmfX3rk4SC', now(), now());
-	`)
+	insert into "user" ("name", "email", "role", "password", "verified_at", "created_at", "created_by", "updated_at", "updated_by") values
+	('Admin evermos', 'admin', $2, '$2a$10$CCWkaZ1UedUQeGtABKwEqepsNTBR1Rp.b4UlFCuvtkbGmfX3rk4SC', now(), now(), $1, now(), $1),
+	('author', 'author@evermos.com', $3, '$2a$10$CCWkaZ1UedUQeGtABKwEqepsNTBR1Rp.b4UlFCuvtkbGmfX3rk4SC', now(), now(), $1, now(), $1);
+	`, data.SystemActor, user.RoleAdmin, user.RoleCustomer)
 	if err != nil {
 		return err
 	}
 
 	_, err = db.Exec(`
-	insert into "product" ("name", "qty", "price", "created_at", "updated_at") values
-	('Laptop', '5', '2000000', now(), now()),
-	('Handphone', '2', '1000000', now(), now());
-	`)
+	insert into "product" ("name", "slug", "qty", "price", "created_at", "created_by", "updated_at", "updated_by") values
+	('Laptop', 'laptop', '5', '2000000', now(), $1, now(), $1),
+	('Handphone', 'handphone', '2', '1000000', now(), $1, now(), $1);
+	`, data.SystemActor)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	insert into "product_price" ("product_id", "price", "effective_from", "applied_at", "created_at", "created_by", "updated_at", "updated_by")
+	select "id", "price", "created_at", "created_at", now(), $1, now(), $1 from "product";
+	`, data.SystemActor)
 	if err != nil {
 		return err
 	}